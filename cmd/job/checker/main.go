@@ -3,79 +3,64 @@ package main
 import (
 	"context"
 	"fmt"
-	"log/slog"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/adapter/gateway"
-	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/usecase"
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure"
-	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/checker"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func main() {
-	infra := infrastructure.GetInfrastructure()
+	ctx := context.Background()
+	infra, err := infrastructure.New(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize infrastructure: %s\n", err.Error())
+		os.Exit(1)
+	}
 	l := infra.Logger
 	jobConfig := infra.JobConfig
-	k8sAPI := infra.K8sAPI
 	videoUsecase := usecase.NewVideoUsecase(gateway.NewVideoGateway(infra.SNS))
-	ctx := context.Background()
 
 	mdcLogger := l.With(
 		"jobName", jobConfig.JobName,
 		"namespace", jobConfig.Namespace,
+		"attempt", jobConfig.Attempt,
 		"component", "job-checker",
 		"version", "1.0.0",
 	)
 
-	updateVideoStatus(ctx, mdcLogger, videoUsecase, jobConfig, dto.VideoStatusUploaded)
+	shutdownTracing, err := tracing.Setup(ctx, "job-checker", infra.Config.OTLPEndpoint)
+	if err != nil {
+		mdcLogger.Error("Failed to set up tracing", "error", err.Error())
+		os.Exit(1)
+	}
+
+	// Continue the trace of the upload, given by the starter in TRACEPARENT
+	ctx, span := tracing.Start(tracing.ExtractEnv(ctx), "checker.Run",
+		trace.WithAttributes(semconv.K8SJobName(jobConfig.JobName), semconv.K8SNamespaceName(jobConfig.Namespace)),
+	)
 
-	var backoffLimit = 0
-	var jobPending = false
+	c := checker.NewChecker(jobConfig, mdcLogger, infra.K8sAPI, videoUsecase, 1*time.Second)
+	err = c.Run(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		mdcLogger.ErrorContext(ctx, "Checker failed", "error", err.Error(), "code", domain.CodeOf(err))
+	}
+	span.End()
 
-	for {
-		time.Sleep(1 * time.Second)
-		jobStatus, err := k8sAPI.GetLastJobStatus(context.Background(), jobConfig.JobName, jobConfig.Namespace)
-		mdcLogger.Info("Job status", "jobStatus", jobStatus)
-		if err != nil {
-			mdcLogger.Error("Error getting job status",
-				"error", err,
-				"backoffLimit", backoffLimit,
-			)
-			backoffLimit++
-			if backoffLimit > 3 {
-				mdcLogger.Error("Job failed", "backoffLimit", backoffLimit)
-				os.Exit(1)
-			}
-		}
-		mdcLogger.Info(fmt.Sprintf("Job %s", strings.ToLower(jobStatus)))
-		switch jobStatus {
-		case "Complete":
-			// This status will be updated by the Video Processor Job
-			os.Exit(0)
-		case "Failed":
-			updateVideoStatus(ctx, mdcLogger, videoUsecase, jobConfig, dto.VideoStatusFailed)
-			os.Exit(1)
-		case "Pending":
-		case "Running":
-			if !jobPending {
-				updateVideoStatus(ctx, mdcLogger, videoUsecase, jobConfig, dto.VideoStatusProcessing)
-				jobPending = true
-			}
-		}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		mdcLogger.Error("Failed to flush spans", "error", flushErr.Error())
 	}
-}
+	cancel()
 
-func updateVideoStatus(ctx context.Context, mdcLogger *slog.Logger, videoUsecase *usecase.VideoUsecase, jobConfig *config.JobConfig, status dto.VideoProcessingStatus) {
-	err := videoUsecase.UpdateVideoStatus(ctx, dto.UpdateVideoStatusInput{
-		VideoId: jobConfig.VideoId,
-		UserId:  jobConfig.UserId,
-		Status:  status,
-	})
 	if err != nil {
-		mdcLogger.Error("Error updating video status", "error", err)
 		os.Exit(1)
 	}
 }
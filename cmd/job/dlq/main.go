@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/adapter/gateway"
+	awsclient "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/s3"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sqs"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/dlq"
+)
+
+const usage = `Usage: job-dlq <command> [flags]
+
+Inspects the dead-letter queue of the starter.
+
+Commands:
+  list      list the messages with their failure
+  redrive   send the messages back to the queue they came from, or to -target
+  discard   delete the messages
+
+Flags:
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON configuration file of the starter, overridden by environment variables")
+	queueURL := flags.String("queue", "", "dead-letter queue URL (default AWS_SQS_DLQ_URL)")
+	target := flags.String("target", "", "queue URL to redrive to (default the source-queue of each message)")
+	videoId := flags.Int64("video", 0, "only the messages of this video")
+	userId := flags.Int64("user", 0, "only the messages of this user")
+	code := flags.String("code", "", "only the messages that failed with this code, such as JOB_START_TIMEOUT")
+	maxMessages := flags.Int("max", 100, "maximum number of messages to read from the queue")
+	dryRun := flags.Bool("dry-run", false, "print the messages that would be redriven or discarded without changing the queues")
+	printJSON := flags.Bool("json", false, "print one JSON object per message")
+	_ = flags.Parse(os.Args[2:])
+
+	if command != "list" && command != "redrive" && command != "discard" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// The tool reads the configuration of the starter, but only needs its AWS settings
+	cfg, err := config.LoadLambdaConfigFrom(*configFile)
+	var validationErr *config.ValidationError
+	if err != nil && !errors.As(err, &validationErr) {
+		fail(err)
+	}
+	if *queueURL == "" {
+		*queueURL = cfg.DeadLetter.QueueURL
+	}
+	if *queueURL == "" {
+		fail(errors.New("no dead-letter queue, set AWS_SQS_DLQ_URL or -queue"))
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	factory, err := awsclient.NewClientFactory(ctx, cfg.AWS.Region)
+	if err != nil {
+		fail(err)
+	}
+	sqsClient, err := sqs.NewSqsClient(factory)
+	if err != nil {
+		fail(err)
+	}
+	inspector := dlq.NewInspector(sqsClient, *queueURL, gateway.NewObjectGateway(s3.NewS3(cfg)))
+	filter := dlq.Filter{VideoId: *videoId, UserId: *userId, Code: *code}
+
+	var messages []dlq.Message
+	switch command {
+	case "list":
+		messages, err = inspector.List(ctx, filter, *maxMessages)
+	case "redrive":
+		messages, err = inspector.Redrive(ctx, filter, *maxMessages, *target, *dryRun)
+	case "discard":
+		messages, err = inspector.Discard(ctx, filter, *maxMessages, *dryRun)
+	}
+
+	if printErr := printMessages(os.Stdout, messages, *printJSON); printErr != nil {
+		fail(printErr)
+	}
+	if command != "list" && !*printJSON {
+		verb := map[string]string{"redrive": "redriven", "discard": "discarded"}[command]
+		if *dryRun {
+			fmt.Printf("\n%d messages would be %s (dry run)\n", len(messages), verb)
+		} else {
+			fmt.Printf("\n%d messages %s\n", len(messages), verb)
+		}
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+// printMessages writes the messages as a table, or as JSON lines for scripts
+func printMessages(out io.Writer, messages []dlq.Message, printJSON bool) error {
+	if printJSON {
+		encoder := json.NewEncoder(out)
+		for _, message := range messages {
+			if err := encoder.Encode(message); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MESSAGE ID\tCODE\tRECEIVES\tVIDEO\tUSER\tMESSAGE\tJOB\tREASON")
+	for _, message := range messages {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			message.MessageId,
+			orDash(message.FailureCode),
+			orDash(formatInt(int64(message.ReceiveCount))),
+			orDash(formatInt(message.VideoId)),
+			orDash(formatInt(message.UserId)),
+			orDash(describe(message)),
+			orDash(message.JobName),
+			orDash(message.FailureReason),
+		)
+	}
+	return w.Flush()
+}
+
+// describe summarizes the body of a message, such as "ObjectCreated:Put uploads/movie.mp4"
+func describe(message dlq.Message) string {
+	switch {
+	case message.Action != "":
+		return message.Action
+	case message.Key != "":
+		return message.EventName + " " + message.Bucket + "/" + message.Key
+	default:
+		return ""
+	}
+}
+
+func formatInt(value int64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(1)
+}
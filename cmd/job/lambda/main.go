@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	awslambda "github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/adapter/gateway"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/usecase"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/lambda"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sqs"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/starter"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/tracing"
+)
+
+func main() {
+	cfg, err := config.LoadLambdaConfigFrom(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+
+	// Build infrastructure
+	infra, err := infrastructure.New(ctx, infrastructure.WithConfig(cfg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize infrastructure: %s\n", err.Error())
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, "job-starter-lambda", infra.Config.OTLPEndpoint)
+	if err != nil {
+		infra.Logger.Error("Failed to set up tracing", "error", err.Error())
+		os.Exit(1)
+	}
+
+	sqsClient, err := sqs.NewSqsClient(infra.AWSClientFactory)
+	if err != nil {
+		infra.Logger.Error("Failed to create SQS client", "error", err.Error())
+		os.Exit(1)
+	}
+
+	s := starter.NewStarter(
+		infra.Config,
+		infra.Logger,
+		infra.K8sAPI,
+		gateway.NewObjectGateway(infra.S3),
+		usecase.NewVideoUsecase(gateway.NewVideoGateway(infra.SNS)),
+		gateway.NewUserTierGateway(infra.Config.Priority.UserTiers),
+	)
+
+	// The event source mapping receives and deletes the messages, the queue handler only
+	// forwards failures to the dead-letter queue and delays throttled messages
+	queueHandler := func(queueURL string) *sqs.SqsHandler {
+		return sqs.NewSqsHandler(sqsClient, queueURL, 0, 0, infra.Logger, nil).
+			WithDeadLetterQueue(infra.Config.DeadLetter.QueueURL, infra.Config.DeadLetter.MaxReceiveCount)
+	}
+	handler := lambda.NewHandler(s.HandleMessage, lambda.NewQueueURLResolver(sqsClient).QueueURL, queueHandler, infra.Logger).
+		WithFlush(tracing.Flush)
+
+	infra.Logger.Info("Starting Lambda handler")
+	awslambda.StartWithOptions(handler.Invoke,
+		awslambda.WithEnableSIGTERM(func() {
+			flushCtx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			if err := shutdownTracing(flushCtx); err != nil {
+				infra.Logger.Error("Failed to flush spans", "error", err.Error())
+			}
+		}),
+	)
+}
@@ -2,46 +2,58 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
-	"strconv"
-	"strings"
 	"syscall"
+	"time"
 
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/adapter/gateway"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/usecase"
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure"
-	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sqs"
-	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/health"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/metrics"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/starter"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/tracing"
 )
 
 func init() {
 	// Pre SQS consumer initialization
 }
 
-type S3Event struct {
-	Records []S3EventRecord `json:"Records"`
-}
-
-type S3EventRecord struct {
-	S3 S3Record `json:"s3"`
-}
-
-type S3Record struct {
-	Bucket S3Bucket `json:"bucket"`
-	Object S3Object `json:"object"`
-}
-
-type S3Bucket struct {
-	Name string `json:"name"`
-}
+func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML or JSON configuration file, overridden by environment variables")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	cfg, err := config.LoadLambdaConfigFrom(*configFile)
+	if *printConfig {
+		if printErr := config.Print(os.Stdout, cfg); printErr != nil {
+			fmt.Fprintln(os.Stderr, printErr.Error())
+			os.Exit(1)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	if *printConfig {
+		return
+	}
 
-type S3Object struct {
-	Key string `json:"key"`
-}
+	// Take the loaded file as the reference for the changes to reload
+	var watcher *config.FileWatcher
+	if *configFile != "" && cfg.ReloadInterval > 0 {
+		watcher, err = config.NewFileWatcher(*configFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
 
-func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -54,139 +66,111 @@ func main() {
 		cancel()
 	}()
 
-	// Get infrastructure
-	infra := infrastructure.GetInfrastructure()
-
-	sqsClient, err := sqs.NewSqsClient(infra.AWSClientFactory)
+	// Build infrastructure
+	infra, err := infrastructure.New(ctx, infrastructure.WithConfig(cfg))
 	if err != nil {
-		infra.Logger.Error("Failed to create SQS client", "error", err.Error())
+		fmt.Fprintf(os.Stderr, "Failed to initialize infrastructure: %s\n", err.Error())
 		os.Exit(1)
 	}
 
-	sqsHandler := sqs.NewSqsHandler(
-		sqsClient,
-		infra.Config.AWS.SQS.QueueURL,
-		infra.Config.AWS.SQS.MaxMessagesBatch,
-		infra.Config.AWS.SQS.WaitTimeSeconds,
-		infra.Logger,
-	)
-
-	infra.Logger.Info("Starting SQS consumer", "queueURL", infra.Config.AWS.SQS.QueueURL)
-
-	// Receive messages from SQS
-	for {
-		err = sqsHandler.ReceiveMessages(ctx, func(message types.Message) (bool, error) {
-			infra.Logger.Info("Processing message", "message", message)
-
-			var s3Event S3Event
-			if err := json.Unmarshal([]byte(*message.Body), &s3Event); err != nil {
-				return true, fmt.Errorf("failed to unmarshal S3 event: %s", err.Error())
-			}
-
-			for _, record := range s3Event.Records {
-				err := processS3Record(ctx, infra, record)
-				if err != nil {
-					infra.Logger.Error("Failed to process message", "error", err.Error(), "messageID", *message.MessageId)
-					return true, err
-				}
-			}
-
-			return false, nil
-		})
-		if err != nil {
-			infra.Logger.Error("Failed to receive messages", "error", err.Error())
-		}
+	shutdownTracing, err := tracing.Setup(ctx, "job-starter", infra.Config.OTLPEndpoint)
+	if err != nil {
+		infra.Logger.Error("Failed to set up tracing", "error", err.Error())
+		os.Exit(1)
 	}
-}
-
-func processS3Record(ctx context.Context, infra *infrastructure.Infrastructure, record S3EventRecord) error {
-	infra.Logger.InfoContext(ctx, "Processing S3 record", "key", record.S3.Object.Key, "bucket", record.S3.Bucket.Name)
+	defer func() {
+		// ctx is canceled on shutdown, the spans left are flushed with a fresh deadline
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			infra.Logger.Error("Failed to flush spans", "error", err.Error())
+		}
+	}()
 
-	// Get object metadata
-	metadata, err := infra.S3.GetObjectMetadata(ctx, record.S3.Bucket.Name, record.S3.Object.Key)
+	sqsClient, err := sqs.NewSqsClient(infra.AWSClientFactory)
 	if err != nil {
-		return fmt.Errorf("error getting object metadata: %s", err.Error())
+		infra.Logger.Error("Failed to create SQS client", "error", err.Error())
+		os.Exit(1)
 	}
 
-	infra.Logger.InfoContext(ctx, "Object metadata", "metadata", metadata)
+	registry := metrics.NewRegistry()
+	m := metrics.NewMetrics(registry)
 
-	// Parse video ID
-	videoId, err := strconv.ParseInt(metadata["video-id"], 10, 64)
-	if err != nil {
-		return fmt.Errorf("error parsing video id: %s", err.Error())
+	// Poll the priority queues by weight, or only the main queue when none is configured
+	queues := infra.Config.Priority.Queues
+	if len(queues) == 0 {
+		queues = []config.WeightedQueue{{URL: infra.Config.AWS.SQS.QueueURL, Weight: 1}}
 	}
-
-	// Parse user ID
-	userId, err := strconv.ParseInt(metadata["user-id"], 10, 64)
-	if err != nil {
-		return fmt.Errorf("error parsing user id: %s", err.Error())
+	handlers := make([]sqs.WeightedHandler, 0, len(queues))
+	for _, queue := range queues {
+		handlers = append(handlers, sqs.WeightedHandler{
+			Handler: sqs.NewSqsHandler(
+				sqsClient,
+				queue.URL,
+				infra.Config.AWS.SQS.MaxMessagesBatch,
+				infra.Config.AWS.SQS.WaitTimeSeconds,
+				infra.Logger,
+				m,
+			).WithDeadLetterQueue(infra.Config.DeadLetter.QueueURL, infra.Config.DeadLetter.MaxReceiveCount),
+			Weight: queue.Weight,
+		})
+		infra.Logger.Info("Starting SQS consumer", "queueURL", queue.URL, "weight", queue.Weight)
 	}
-
-	// Generate job names
-	splittedKey := strings.Split(record.S3.Object.Key, "/")
-	fileName := splittedKey[len(splittedKey)-1]
-	fileNameWithoutExtension := strings.Split(fileName, ".")[0]
-	jobName := fmt.Sprintf("%s-%s", infra.Config.K8S.Job.Prefix, fileNameWithoutExtension)
-	jobCheckerName := fmt.Sprintf("%s-%s-checker", infra.Config.K8S.Job.Prefix, fileNameWithoutExtension)
-
-	// Create job checker
-	infra.Logger.InfoContext(ctx, "Creating job checker", "jobName", jobCheckerName)
-	err = infra.K8sAPI.CreateJob(ctx, &api.JobInput{
-		Namespace:          infra.Config.K8S.Namespace,
-		JobName:            jobCheckerName,
-		Image:              infra.Config.K8S.Job.ImageChecker,
-		Cmd:                infra.Config.K8S.Job.Command,
-		ServiceAccountName: infra.Config.K8S.ServiceAccountName,
-		Envs: map[string]string{
-			"JOB_NAME":                           jobName,
-			"JOB_NAMESPACE":                      infra.Config.K8S.Namespace,
-			"JOB_VIDEO_ID":                       strconv.FormatInt(videoId, 10),
-			"JOB_USER_ID":                        strconv.FormatInt(userId, 10),
-			"AWS_ACCESS_KEY_ID":                  infra.Config.AWS.AccessKey,
-			"AWS_SECRET_ACCESS_KEY":              infra.Config.AWS.SecretAccessKey,
-			"AWS_SESSION_TOKEN":                  infra.Config.AWS.SessionToken,
-			"AWS_REGION":                         infra.Config.AWS.Region,
-			"AWS_SNS_TOPIC_ARN":                  infra.Config.AWS.SNS.TopicArn,
-			"AWS_SQS_QUEUE_URL":                  infra.Config.AWS.SQS.QueueURL,
-			"K8S_NAMESPACE":                      infra.Config.K8S.Namespace,
-			"K8S_JOB_NAME":                       jobName,
-			"K8S_JOB_IMAGE":                      infra.Config.K8S.Job.Image,
-			"K8S_JOB_COMMAND":                    infra.Config.K8S.Job.Command,
-			"K8S_JOB_PREFIX":                     infra.Config.K8S.Job.Prefix,
-			"K8S_JOB_BACK_OFF_LIMIT":             strconv.FormatInt(int64(infra.Config.K8S.Job.BackOffLimit), 10),
-			"K8S_JOB_IMAGE_CHECKER":              infra.Config.K8S.Job.ImageChecker,
-			"K8S_JOB_TTL_SECONDS_AFTER_FINISHED": strconv.FormatInt(int64(infra.Config.K8S.Job.TtlSecondsAfterFinished.Seconds()), 10),
-		},
-		TtlSecondsAfterFinished: infra.Config.K8S.Job.TtlSecondsAfterFinished,
-	})
-	if err != nil {
-		return fmt.Errorf("error creating job checker: %s", err.Error())
+	sqsPoller := sqs.NewWeightedPoller(handlers)
+
+	// Serve the probes and metrics, ready once every queue and the cluster are reachable
+	if infra.Config.HTTPAddr != "" {
+		checks := map[string]health.Check{
+			"kubernetes": infra.K8sAPI.Ping,
+			"sqs": func(ctx context.Context) error {
+				for _, queue := range queues {
+					if err := sqsClient.Ping(ctx, queue.URL); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+		server := health.NewServer(infra.Config.HTTPAddr, registry, checks, infra.Logger)
+		go func() {
+			if err := server.Run(ctx); err != nil {
+				infra.Logger.Error("Failed to serve health endpoints", "error", err.Error())
+			}
+		}()
+		infra.Logger.Info("Serving health endpoints", "addr", infra.Config.HTTPAddr)
 	}
 
-	// Create main job
-	infra.Logger.InfoContext(ctx, "Creating job", "jobName", jobName)
-	err = infra.K8sAPI.CreateJob(ctx, &api.JobInput{
-		Namespace: infra.Config.K8S.Namespace,
-		JobName:   jobName,
-		Image:     infra.Config.K8S.Job.Image,
-		Cmd:       infra.Config.K8S.Job.Command,
-		Envs: map[string]string{
-			"VIDEO_KEY":             record.S3.Object.Key,
-			"VIDEO_BUCKET":          record.S3.Bucket.Name,
-			"PROCESSED_BUCKET":      record.S3.Bucket.Name,
-			"VIDEO_ID":              strconv.FormatInt(videoId, 10),
-			"VIDEO_USER_ID":         strconv.FormatInt(userId, 10),
-			"SNS_TOPIC_ARN":         infra.Config.AWS.SNS.TopicArn,
-			"AWS_ACCESS_KEY_ID":     infra.Config.AWS.AccessKey,
-			"AWS_SECRET_ACCESS_KEY": infra.Config.AWS.SecretAccessKey,
-			"AWS_SESSION_TOKEN":     infra.Config.AWS.SessionToken,
-			"AWS_REGION":            infra.Config.AWS.Region,
-		},
-		TtlSecondsAfterFinished: infra.Config.K8S.Job.TtlSecondsAfterFinished,
-	})
-	if err != nil {
-		return fmt.Errorf("error creating job: %s", err.Error())
+	s := starter.NewStarter(
+		infra.Config,
+		infra.Logger,
+		metrics.InstrumentK8sAPI(infra.K8sAPI, m),
+		gateway.NewObjectGateway(infra.S3),
+		usecase.NewVideoUsecase(gateway.NewVideoGateway(infra.SNS)),
+		gateway.NewUserTierGateway(infra.Config.Priority.UserTiers),
+	)
+
+	// Apply changes of the mounted configuration file to new messages
+	if watcher != nil {
+		go watcher.Watch(ctx, infra.Config.ReloadInterval,
+			func(cfg *config.Config) {
+				for _, change := range s.Reload(cfg, gateway.NewUserTierGateway(cfg.Priority.UserTiers)) {
+					if config.RequiresRestart(change) {
+						infra.Logger.Warn("Configuration change requires a restart", "change", change)
+					}
+				}
+			},
+			func(err error) {
+				infra.Logger.Error("Failed to reload configuration, keeping the current one", "error", err.Error())
+			},
+		)
+		infra.Logger.Info("Watching configuration file", "path", *configFile, "interval", infra.Config.ReloadInterval)
 	}
 
-	return nil
+	// Receive messages from SQS until a shutdown signal
+	for ctx.Err() == nil {
+		err = sqsPoller.ReceiveMessages(ctx, s.HandleMessage)
+		if err != nil && ctx.Err() == nil {
+			infra.Logger.Error("Failed to receive messages", "error", err.Error())
+		}
+	}
 }
@@ -0,0 +1,30 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/s3"
+)
+
+// ObjectGateway reads the metadata of the uploaded objects from S3
+type ObjectGateway struct {
+	s3 s3.S3Interface
+}
+
+func NewObjectGateway(s3 s3.S3Interface) port.ObjectGateway {
+	return &ObjectGateway{s3: s3}
+}
+
+func (g *ObjectGateway) GetObjectInfo(ctx context.Context, bucket string, key string, versionId string) (*dto.ObjectInfo, error) {
+	info, err := g.s3.GetObjectInfo(ctx, bucket, key, versionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.ObjectInfo{
+		Metadata: info.Metadata,
+		Size:     info.Size,
+	}, nil
+}
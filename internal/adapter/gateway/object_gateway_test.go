@@ -0,0 +1,57 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/s3"
+	mocks "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/s3/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestObjectGateway_GetObjectInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockS3 := mocks.NewMockS3Interface(ctrl)
+	gateway := NewObjectGateway(mockS3)
+
+	t.Run("should return the metadata and size of the object", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		metadata := map[string]string{"video-id": "123", "user-id": "456"}
+
+		mockS3.EXPECT().
+			GetObjectInfo(ctx, "bucket", "movie.mp4", "").
+			Return(&s3.ObjectInfo{Metadata: metadata, Size: 1024}, nil).
+			Times(1)
+
+		// Act
+		info, err := gateway.GetObjectInfo(ctx, "bucket", "movie.mp4", "")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, &dto.ObjectInfo{Metadata: metadata, Size: 1024}, info)
+	})
+
+	t.Run("should return error when S3 fails", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		expectedError := errors.New("S3 error")
+
+		mockS3.EXPECT().
+			GetObjectInfo(ctx, "bucket", "movie.mp4", "").
+			Return(nil, expectedError).
+			Times(1)
+
+		// Act
+		info, err := gateway.GetObjectInfo(ctx, "bucket", "movie.mp4", "")
+
+		// Assert
+		assert.Nil(t, info)
+		assert.Equal(t, expectedError, err)
+	})
+}
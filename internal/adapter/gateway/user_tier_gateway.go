@@ -0,0 +1,20 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port"
+)
+
+// UserTierGateway resolves user tiers from a static map, usually loaded from configuration
+type UserTierGateway struct {
+	userTiers map[int64]string
+}
+
+func NewUserTierGateway(userTiers map[int64]string) port.UserTierGateway {
+	return &UserTierGateway{userTiers: userTiers}
+}
+
+func (g *UserTierGateway) GetUserTier(ctx context.Context, userId int64) (string, error) {
+	return g.userTiers[userId], nil
+}
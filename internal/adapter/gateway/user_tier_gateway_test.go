@@ -0,0 +1,30 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserTierGateway_GetUserTier(t *testing.T) {
+	gateway := NewUserTierGateway(map[int64]string{42: "premium"})
+
+	t.Run("should return the tier of a known user", func(t *testing.T) {
+		// Act
+		tier, err := gateway.GetUserTier(context.Background(), 42)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "premium", tier)
+	})
+
+	t.Run("should return an empty tier for an unknown user", func(t *testing.T) {
+		// Act
+		tier, err := gateway.GetUserTier(context.Background(), 7)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, tier)
+	})
+}
@@ -3,8 +3,10 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port"
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sns"
@@ -24,11 +26,15 @@ func (g *VideoGateway) UpdateVideoStatus(ctx context.Context, input dto.UpdateVi
 		VideoId:    input.VideoId,
 		UserId:     input.UserId,
 		Status:     string(input.Status),
+		Progress:   input.Progress,
 		OccurredAt: time.Now(),
 	})
 	if err != nil {
 		return err
 	}
 
-	return g.sns.Publish(ctx, string(json))
+	if err := g.sns.Publish(ctx, string(json)); err != nil {
+		return domain.NewError(domain.CodeStatusPublishFailed, fmt.Sprintf("failed to publish status %s of video %d", input.Status, input.VideoId), err)
+	}
+	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
 	mocks "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sns/mocks"
 	"github.com/stretchr/testify/assert"
@@ -59,8 +60,8 @@ func TestVideoGateway_UpdateVideoStatus(t *testing.T) {
 		err := gateway.UpdateVideoStatus(ctx, input)
 
 		// Assert
-		assert.Error(t, err)
-		assert.Equal(t, expectedError, err)
+		assert.ErrorIs(t, err, expectedError)
+		assert.ErrorIs(t, err, domain.ErrStatusPublishFailed)
 	})
 
 	t.Run("should handle different video statuses", func(t *testing.T) {
@@ -154,6 +155,41 @@ func TestVideoGateway_UpdateVideoStatus(t *testing.T) {
 		assert.Equal(t, input.UserId, payload.UserId)
 		assert.Equal(t, string(input.Status), payload.Status)
 		assert.NotZero(t, payload.OccurredAt)
+		assert.Nil(t, payload.Progress)
+	})
+
+	t.Run("should include chunk progress in JSON payload", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		input := dto.UpdateVideoStatusInput{
+			VideoId: 789,
+			UserId:  101112,
+			Status:  dto.VideoStatusProcessing,
+			Progress: &dto.VideoProgress{
+				CompletedChunks: 2,
+				TotalChunks:     5,
+			},
+		}
+
+		var capturedMessage string
+		mockSNS.EXPECT().
+			Publish(ctx, gomock.Any()).
+			Do(func(ctx context.Context, message string) {
+				capturedMessage = message
+			}).
+			Return(nil).
+			Times(1)
+
+		// Act
+		err := gateway.UpdateVideoStatus(ctx, input)
+
+		// Assert
+		assert.NoError(t, err)
+
+		var payload dto.VideoStatusPayload
+		err = json.Unmarshal([]byte(capturedMessage), &payload)
+		assert.NoError(t, err)
+		assert.Equal(t, input.Progress, payload.Progress)
 	})
 }
 
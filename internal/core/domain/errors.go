@@ -1,38 +1,96 @@
 package domain
 
+import (
+	"errors"
+	"time"
+)
+
+// Code identifies the kind of a pipeline error in logs, metrics and dead-letter messages
+type Code string
+
+const (
+	CodeInvalidS3Event          Code = "INVALID_S3_EVENT"
+	CodeInvalidControlMessage   Code = "INVALID_CONTROL_MESSAGE"
+	CodeMissingMetadata         Code = "MISSING_METADATA"
+	CodeJobAlreadyExists        Code = "JOB_ALREADY_EXISTS"
+	CodeJobStartTimeout         Code = "JOB_START_TIMEOUT"
+	CodeJobFailed               Code = "JOB_FAILED"
+	CodeStatusPublishFailed     Code = "STATUS_PUBLISH_FAILED"
+	CodeInvalidStatusTransition Code = "INVALID_STATUS_TRANSITION"
+	CodeThrottled               Code = "THROTTLED"
+	CodeInternal                Code = "INTERNAL"
+)
+
+// Permanent tells if the errors of the code can never succeed when retried, such as a
+// malformed message
+func (c Code) Permanent() bool {
+	switch c {
+	case CodeInvalidS3Event, CodeInvalidControlMessage, CodeMissingMetadata, CodeJobAlreadyExists, CodeInvalidStatusTransition:
+		return true
+	default:
+		return false
+	}
+}
+
+// Errors of the pipeline, to be matched with errors.Is. Errors created with NewError match
+// the one of their code.
 var (
-	ErrConflict           = "data conflicts with existing data"
-	ErrNotFound           = "data not found"
-	ErrInvalidParam       = "invalid parameter"
-	ErrInvalidQueryParams = "invalid query parameters"
-	ErrInvalidBody        = "invalid body"
-
-	ErrTokenDuration = "invalid token duration format"
-	ErrTokenCreation = "error creating token"
-	ErrExpiredToken  = "access token has expired"
-	ErrInvalidToken  = "access token is invalid"
-
-	ErrOrderInvalidStatusTransition = "invalid status transition"
-	ErrOrderWithoutProducts         = "order without products"
-	ErrProductIsMandatory           = "product is mandatory"
-	ErrStaffIdIsMandatory           = "staff is mandatory"
-	ErrOrderIsMandatory             = "order is mandatory"
-	ErrOrderIsNotOpen               = "order is not on status open"
-	ErrRoleInvalid                  = "invalid role"
-
-	ErrPageMustBeGreaterThanZero = "page must be greater than zero"
-	ErrLimitMustBeBetween1And100 = "limit must be between 1 and 100"
-
-	ErrInternalError      = "internal server error"
-	ErrUnknownError       = "unknown error"
-	ErrValidationError    = "validation error"
-	ErrInvalidInput       = "invalid input"
-	ErrPreconditionFailed = "precondition failed"
-
-	ErrFailedToCreatePaymentExternal = "failed to create payment external"
-	ErrFetchingCustomer              = "failed to fetch customer"
+	ErrInvalidS3Event          = &Error{Code: CodeInvalidS3Event, Message: "invalid S3 event"}
+	ErrInvalidControlMessage   = &Error{Code: CodeInvalidControlMessage, Message: "invalid control message"}
+	ErrMissingMetadata         = &Error{Code: CodeMissingMetadata, Message: "missing object metadata"}
+	ErrJobAlreadyExists        = &Error{Code: CodeJobAlreadyExists, Message: "job already exists"}
+	ErrJobStartTimeout         = &Error{Code: CodeJobStartTimeout, Message: "job did not start in time"}
+	ErrJobFailed               = &Error{Code: CodeJobFailed, Message: "job failed"}
+	ErrStatusPublishFailed     = &Error{Code: CodeStatusPublishFailed, Message: "failed to publish video status"}
+	ErrInvalidStatusTransition = &Error{Code: CodeInvalidStatusTransition, Message: "invalid status transition"}
+	ErrTooManyActiveJobs       = &Error{Code: CodeThrottled, Message: "too many active jobs"}
+)
+
+const (
+	msgValidationError = "validation error"
+	msgInternalError   = "internal server error"
 )
 
+// Error is an error of the pipeline, with the code of its kind and the error that caused it
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the errors of the same code, so that errors.Is(err, ErrJobAlreadyExists) holds
+// whatever the message of err
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// JobError tells the job an error happened on, to be reported with the failure. It reads as
+// the error it wraps.
+type JobError struct {
+	JobName string
+	Err     error
+}
+
+func (e *JobError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *JobError) Unwrap() error {
+	return e.Err
+}
+
 type ValidationError struct {
 	Message string
 	Err     error
@@ -45,6 +103,10 @@ func (e *ValidationError) Error() string {
 	return e.Message
 }
 
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
 type NotFoundError struct {
 	Message string
 }
@@ -65,6 +127,10 @@ func (e *InternalError) Error() string {
 	return e.Message
 }
 
+func (e *InternalError) Unwrap() error {
+	return e.Err
+}
+
 type InvalidInputError struct {
 	Message string
 }
@@ -73,9 +139,34 @@ func (e *InvalidInputError) Error() string {
 	return e.Message
 }
 
+// ThrottledError means the request can't be handled right now and should be retried after
+// RetryAfter. Err is the reason it matches with errors.Is, such as ErrTooManyActiveJobs.
+type ThrottledError struct {
+	Message    string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ThrottledError) Error() string {
+	return e.Message
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
+
+// NewError creates an error of the code, which matches the Err variable of the code
+func NewError(code Code, message string, err error) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+		Err:     err,
+	}
+}
+
 func NewValidationError(err error) *ValidationError {
 	return &ValidationError{
-		Message: ErrValidationError,
+		Message: msgValidationError,
 		Err:     err,
 	}
 }
@@ -88,7 +179,7 @@ func NewNotFoundError(message string) *NotFoundError {
 
 func NewInternalError(err error) *InternalError {
 	return &InternalError{
-		Message: ErrInternalError,
+		Message: msgInternalError,
 		Err:     err,
 	}
 }
@@ -98,3 +189,58 @@ func NewInvalidInputError(message string) *InvalidInputError {
 		Message: message,
 	}
 }
+
+func NewThrottledError(message string, retryAfter time.Duration, err error) *ThrottledError {
+	return &ThrottledError{
+		Message:    message,
+		RetryAfter: retryAfter,
+		Err:        err,
+	}
+}
+
+// WithJob wraps err in a JobError naming the job, or returns nil when err is nil
+func WithJob(jobName string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &JobError{JobName: jobName, Err: err}
+}
+
+// JobNameOf returns the name of the last job err happened on, or an empty string
+func JobNameOf(err error) string {
+	var jobErr *JobError
+	if errors.As(err, &jobErr) {
+		return jobErr.JobName
+	}
+	return ""
+}
+
+// IsPermanent tells if err, or an error it wraps, is an Error with a permanent code
+func IsPermanent(err error) bool {
+	var pipelineErr *Error
+	return errors.As(err, &pipelineErr) && pipelineErr.Code.Permanent()
+}
+
+// CodeOf returns the code of err, CodeThrottled for a ThrottledError and CodeInternal for
+// the errors without a code
+func CodeOf(err error) Code {
+	var pipelineErr *Error
+	if errors.As(err, &pipelineErr) {
+		return pipelineErr.Code
+	}
+	var throttledErr *ThrottledError
+	if errors.As(err, &throttledErr) {
+		return CodeThrottled
+	}
+	return CodeInternal
+}
+
+// RetryAfter returns how long to wait before retrying when err, or an error it wraps,
+// is a ThrottledError
+func RetryAfter(err error) (time.Duration, bool) {
+	var throttledErr *ThrottledError
+	if errors.As(err, &throttledErr) {
+		return throttledErr.RetryAfter, true
+	}
+	return 0, false
+}
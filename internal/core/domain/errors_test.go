@@ -2,7 +2,9 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -49,7 +51,7 @@ func TestValidationError(t *testing.T) {
 		validationErr := NewValidationError(expectedErr)
 
 		// Assert
-		assert.Equal(t, ErrValidationError, validationErr.Message)
+		assert.Equal(t, msgValidationError, validationErr.Message)
 		assert.Equal(t, expectedErr, validationErr.Err)
 		assert.Equal(t, expectedErr.Error(), validationErr.Error())
 	})
@@ -125,7 +127,7 @@ func TestInternalError(t *testing.T) {
 		internalErr := NewInternalError(expectedErr)
 
 		// Assert
-		assert.Equal(t, ErrInternalError, internalErr.Message)
+		assert.Equal(t, msgInternalError, internalErr.Message)
 		assert.Equal(t, expectedErr, internalErr.Err)
 		assert.Equal(t, expectedErr.Error(), internalErr.Error())
 	})
@@ -159,32 +161,161 @@ func TestInvalidInputError(t *testing.T) {
 	})
 }
 
-func TestErrorConstants(t *testing.T) {
-	t.Run("should have correct error constants", func(t *testing.T) {
-		assert.Equal(t, "data conflicts with existing data", ErrConflict)
-		assert.Equal(t, "data not found", ErrNotFound)
-		assert.Equal(t, "invalid parameter", ErrInvalidParam)
-		assert.Equal(t, "invalid query parameters", ErrInvalidQueryParams)
-		assert.Equal(t, "invalid body", ErrInvalidBody)
-		assert.Equal(t, "invalid token duration format", ErrTokenDuration)
-		assert.Equal(t, "error creating token", ErrTokenCreation)
-		assert.Equal(t, "access token has expired", ErrExpiredToken)
-		assert.Equal(t, "access token is invalid", ErrInvalidToken)
-		assert.Equal(t, "invalid status transition", ErrOrderInvalidStatusTransition)
-		assert.Equal(t, "order without products", ErrOrderWithoutProducts)
-		assert.Equal(t, "product is mandatory", ErrProductIsMandatory)
-		assert.Equal(t, "staff is mandatory", ErrStaffIdIsMandatory)
-		assert.Equal(t, "order is mandatory", ErrOrderIsMandatory)
-		assert.Equal(t, "order is not on status open", ErrOrderIsNotOpen)
-		assert.Equal(t, "invalid role", ErrRoleInvalid)
-		assert.Equal(t, "page must be greater than zero", ErrPageMustBeGreaterThanZero)
-		assert.Equal(t, "limit must be between 1 and 100", ErrLimitMustBeBetween1And100)
-		assert.Equal(t, "internal server error", ErrInternalError)
-		assert.Equal(t, "unknown error", ErrUnknownError)
-		assert.Equal(t, "validation error", ErrValidationError)
-		assert.Equal(t, "invalid input", ErrInvalidInput)
-		assert.Equal(t, "precondition failed", ErrPreconditionFailed)
-		assert.Equal(t, "failed to create payment external", ErrFailedToCreatePaymentExternal)
-		assert.Equal(t, "failed to fetch customer", ErrFetchingCustomer)
+func TestThrottledError(t *testing.T) {
+	t.Run("should create ThrottledError using NewThrottledError", func(t *testing.T) {
+		// Arrange
+		expectedMessage := "too many active jobs"
+		expectedRetryAfter := 30 * time.Second
+
+		// Act
+		throttledErr := NewThrottledError(expectedMessage, expectedRetryAfter, ErrTooManyActiveJobs)
+
+		// Assert
+		assert.Equal(t, expectedMessage, throttledErr.Message)
+		assert.Equal(t, expectedRetryAfter, throttledErr.RetryAfter)
+		assert.Equal(t, expectedMessage, throttledErr.Error())
+		assert.ErrorIs(t, throttledErr, ErrTooManyActiveJobs)
+		assert.ErrorIs(t, fmt.Errorf("admission: %w", throttledErr), ErrTooManyActiveJobs)
+	})
+
+	t.Run("should be found in a wrapped error chain", func(t *testing.T) {
+		// Arrange
+		wrapped := fmt.Errorf("admission: %w", NewThrottledError(ErrTooManyActiveJobs.Message, time.Minute, ErrTooManyActiveJobs))
+
+		// Act
+		var throttledErr *ThrottledError
+		found := errors.As(wrapped, &throttledErr)
+
+		// Assert
+		assert.True(t, found)
+		assert.Equal(t, time.Minute, throttledErr.RetryAfter)
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Run("should return the delay of a throttled error", func(t *testing.T) {
+		// Arrange
+		wrapped := fmt.Errorf("admission: %w", NewThrottledError(ErrTooManyActiveJobs.Message, time.Minute, ErrTooManyActiveJobs))
+
+		// Act
+		retryAfter, throttled := RetryAfter(wrapped)
+
+		// Assert
+		assert.True(t, throttled)
+		assert.Equal(t, time.Minute, retryAfter)
+	})
+
+	t.Run("should not delay other errors", func(t *testing.T) {
+		// Act
+		_, throttled := RetryAfter(NewError(CodeInternal, "error creating job", nil))
+
+		// Assert
+		assert.False(t, throttled)
+	})
+}
+
+func TestError(t *testing.T) {
+	t.Run("should describe the cause and unwrap it", func(t *testing.T) {
+		// Arrange
+		cause := errors.New("unexpected end of JSON input")
+
+		// Act
+		err := NewError(CodeInvalidS3Event, "failed to unmarshal S3 event", cause)
+
+		// Assert
+		assert.Equal(t, "failed to unmarshal S3 event: unexpected end of JSON input", err.Error())
+		assert.Equal(t, "job already exists", ErrJobAlreadyExists.Error())
+		assert.ErrorIs(t, err, cause)
+	})
+
+	t.Run("should match the error of its code in a wrapped error chain", func(t *testing.T) {
+		// Arrange
+		wrapped := fmt.Errorf("starter: %w", NewError(CodeJobAlreadyExists, "job video-processor-42 already exists", nil))
+
+		// Act
+		matches := errors.Is(wrapped, ErrJobAlreadyExists)
+
+		// Assert
+		assert.True(t, matches)
+		assert.False(t, errors.Is(wrapped, ErrJobStartTimeout))
+	})
+
+	t.Run("should tell the permanent codes", func(t *testing.T) {
+		for _, code := range []Code{CodeInvalidS3Event, CodeInvalidControlMessage, CodeMissingMetadata, CodeJobAlreadyExists, CodeInvalidStatusTransition} {
+			assert.True(t, code.Permanent(), code)
+			assert.True(t, IsPermanent(NewError(code, "failed", nil)), code)
+		}
+		for _, code := range []Code{CodeJobStartTimeout, CodeJobFailed, CodeStatusPublishFailed, CodeThrottled, CodeInternal} {
+			assert.False(t, code.Permanent(), code)
+			assert.False(t, IsPermanent(NewError(code, "failed", nil)), code)
+		}
+	})
+
+	t.Run("should tell a permanent error in a wrapped error chain", func(t *testing.T) {
+		// Arrange
+		wrapped := fmt.Errorf("starter: %w", NewError(CodeInvalidControlMessage, "unknown control action", nil))
+
+		// Act
+		permanent := IsPermanent(wrapped)
+
+		// Assert
+		assert.True(t, permanent)
+		assert.False(t, IsPermanent(errors.New("unknown")))
+	})
+}
+
+func TestCodeOf(t *testing.T) {
+	t.Run("should return the code of a wrapped error", func(t *testing.T) {
+		// Arrange
+		wrapped := fmt.Errorf("checker: %w", NewError(CodeStatusPublishFailed, "failed to publish FAILED", errors.New("timeout")))
+
+		// Act
+		code := CodeOf(wrapped)
+
+		// Assert
+		assert.Equal(t, CodeStatusPublishFailed, code)
+	})
+
+	t.Run("should return throttled for a throttled error", func(t *testing.T) {
+		assert.Equal(t, CodeThrottled, CodeOf(NewThrottledError("too many active jobs", time.Minute, ErrTooManyActiveJobs)))
+	})
+
+	t.Run("should return internal for an error without a code", func(t *testing.T) {
+		assert.Equal(t, CodeInternal, CodeOf(errors.New("unknown")))
+	})
+}
+
+func TestJobError(t *testing.T) {
+	t.Run("should name the job of a wrapped error", func(t *testing.T) {
+		// Arrange
+		cause := NewError(CodeJobStartTimeout, "job video-processor-42 did not start in time", nil)
+
+		// Act
+		err := fmt.Errorf("error creating job: %w", WithJob("video-processor-42", cause))
+
+		// Assert
+		assert.Equal(t, "video-processor-42", JobNameOf(err))
+		assert.Equal(t, "error creating job: job video-processor-42 did not start in time", err.Error())
+		assert.ErrorIs(t, err, ErrJobStartTimeout)
+	})
+
+	t.Run("should not wrap a nil error", func(t *testing.T) {
+		assert.NoError(t, WithJob("video-processor-42", nil))
+		assert.Empty(t, JobNameOf(errors.New("unknown")))
+	})
+}
+
+func TestUnwrap(t *testing.T) {
+	t.Run("should find the cause of validation and internal errors", func(t *testing.T) {
+		// Arrange
+		cause := errors.New("database connection failed")
+
+		// Act
+		validationErr := fmt.Errorf("usecase: %w", NewValidationError(cause))
+		internalErr := fmt.Errorf("usecase: %w", NewInternalError(cause))
+
+		// Assert
+		assert.ErrorIs(t, validationErr, cause)
+		assert.ErrorIs(t, internalErr, cause)
 	})
 }
@@ -5,3 +5,55 @@ type StartJobInput struct {
 
 	Variables map[string]string `json:"variables"`
 }
+
+// ObjectInfo holds the user metadata and the size of an uploaded object
+type ObjectInfo struct {
+	Metadata map[string]string
+	Size     int64
+}
+
+// StartVideoJobInput describes the uploaded video to start the jobs of. The video and the
+// user are read from the "video-id" and "user-id" metadata of the object when zero.
+type StartVideoJobInput struct {
+	Bucket string
+	Key    string
+	// VersionId selects the version of the object in a versioned bucket, the latest when empty
+	VersionId string
+	// Sequencer and ETag identify the S3 event of the upload, kept on the jobs
+	Sequencer string
+	ETag      string
+	VideoId   int64
+	UserId    int64
+	// Attempt numbers the jobs of a reprocessed video, from 1 for the upload
+	Attempt int
+	// Revision numbers the uploads of the object, from 1, as an overwrite starts the video over
+	Revision int
+	// Template selects one of the job tiers instead of the tier of the user
+	Template string
+}
+
+// StartVideoJobOutput identifies the jobs started for a video
+type StartVideoJobOutput struct {
+	VideoId        int64
+	UserId         int64
+	JobName        string
+	CheckerJobName string
+}
+
+// ScheduleVideoJobsInput describes the processor and checker jobs of a video
+type ScheduleVideoJobsInput struct {
+	JobName        string
+	CheckerJobName string
+	Bucket         string
+	Key            string
+	VersionId      string
+	Sequencer      string
+	ETag           string
+	VideoId        int64
+	UserId         int64
+	Metadata       map[string]string
+	Size           int64
+	Attempt        int
+	Revision       int
+	Template       string
+}
@@ -1,26 +1,56 @@
 package dto
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 type VideoProcessingStatus string
 
 const (
 	VideoStatusUploaded     VideoProcessingStatus = "UPLOADED"
+	VideoStatusQueued       VideoProcessingStatus = "QUEUED"
 	VideoStatusProcessing   VideoProcessingStatus = "PROCESSING"
 	VideoStatusReprocessing VideoProcessingStatus = "REPROCESSING"
 	VideoStatusFinished     VideoProcessingStatus = "FINISHED"
 	VideoStatusFailed       VideoProcessingStatus = "FAILED"
+	VideoStatusCanceled     VideoProcessingStatus = "CANCELED"
 )
 
+// nextStatuses lists the statuses a video may move to from each status. PROCESSING repeats with
+// the progress of the chunks, and a finished video only moves again when reprocessed.
+var nextStatuses = map[VideoProcessingStatus][]VideoProcessingStatus{
+	VideoStatusUploaded:     {VideoStatusQueued, VideoStatusProcessing, VideoStatusFinished, VideoStatusFailed, VideoStatusCanceled},
+	VideoStatusReprocessing: {VideoStatusQueued, VideoStatusProcessing, VideoStatusFinished, VideoStatusFailed, VideoStatusCanceled},
+	VideoStatusQueued:       {VideoStatusProcessing, VideoStatusFinished, VideoStatusFailed, VideoStatusCanceled},
+	VideoStatusProcessing:   {VideoStatusProcessing, VideoStatusFinished, VideoStatusFailed, VideoStatusCanceled},
+	VideoStatusFinished:     {VideoStatusReprocessing},
+	VideoStatusFailed:       {VideoStatusReprocessing},
+	VideoStatusCanceled:     {VideoStatusReprocessing},
+}
+
+// CanTransitionTo tells if a video in status s may move to next
+func (s VideoProcessingStatus) CanTransitionTo(next VideoProcessingStatus) bool {
+	return slices.Contains(nextStatuses[s], next)
+}
+
+// VideoProgress reports how many chunks of a video were processed by an Indexed Job
+type VideoProgress struct {
+	CompletedChunks int32 `json:"completed_chunks"`
+	TotalChunks     int32 `json:"total_chunks"`
+}
+
 type UpdateVideoStatusInput struct {
-	VideoId int64                 `json:"video_id"`
-	UserId  int64                 `json:"user_id"`
-	Status  VideoProcessingStatus `json:"status"`
+	VideoId  int64                 `json:"video_id"`
+	UserId   int64                 `json:"user_id"`
+	Status   VideoProcessingStatus `json:"status"`
+	Progress *VideoProgress        `json:"progress,omitempty"`
 }
 
 type VideoStatusPayload struct {
-	VideoId    int64     `json:"video_id"`
-	UserId     int64     `json:"user_id"`
-	Status     string    `json:"status"`
-	OccurredAt time.Time `json:"occurred_at"`
+	VideoId    int64          `json:"video_id"`
+	UserId     int64          `json:"user_id"`
+	Status     string         `json:"status"`
+	Progress   *VideoProgress `json:"progress,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
 }
@@ -10,21 +10,51 @@ import (
 func TestVideoProcessingStatus(t *testing.T) {
 	t.Run("should have correct status constants", func(t *testing.T) {
 		assert.Equal(t, VideoProcessingStatus("UPLOADED"), VideoStatusUploaded)
+		assert.Equal(t, VideoProcessingStatus("QUEUED"), VideoStatusQueued)
 		assert.Equal(t, VideoProcessingStatus("PROCESSING"), VideoStatusProcessing)
 		assert.Equal(t, VideoProcessingStatus("REPROCESSING"), VideoStatusReprocessing)
 		assert.Equal(t, VideoProcessingStatus("FINISHED"), VideoStatusFinished)
 		assert.Equal(t, VideoProcessingStatus("FAILED"), VideoStatusFailed)
+		assert.Equal(t, VideoProcessingStatus("CANCELED"), VideoStatusCanceled)
 	})
 
 	t.Run("should convert status to string correctly", func(t *testing.T) {
 		assert.Equal(t, "UPLOADED", string(VideoStatusUploaded))
+		assert.Equal(t, "QUEUED", string(VideoStatusQueued))
 		assert.Equal(t, "PROCESSING", string(VideoStatusProcessing))
 		assert.Equal(t, "REPROCESSING", string(VideoStatusReprocessing))
 		assert.Equal(t, "FINISHED", string(VideoStatusFinished))
 		assert.Equal(t, "FAILED", string(VideoStatusFailed))
+		assert.Equal(t, "CANCELED", string(VideoStatusCanceled))
 	})
 }
 
+func TestVideoProcessingStatus_CanTransitionTo(t *testing.T) {
+	testCases := []struct {
+		name     string
+		from     VideoProcessingStatus
+		to       VideoProcessingStatus
+		expected bool
+	}{
+		{"uploaded to queued", VideoStatusUploaded, VideoStatusQueued, true},
+		{"queued to processing", VideoStatusQueued, VideoStatusProcessing, true},
+		{"processing with progress", VideoStatusProcessing, VideoStatusProcessing, true},
+		{"processing to failed", VideoStatusProcessing, VideoStatusFailed, true},
+		{"reprocessing to processing", VideoStatusReprocessing, VideoStatusProcessing, true},
+		{"failed to reprocessing", VideoStatusFailed, VideoStatusReprocessing, true},
+		{"processing back to uploaded", VideoStatusProcessing, VideoStatusUploaded, false},
+		{"processing back to queued", VideoStatusProcessing, VideoStatusQueued, false},
+		{"finished to processing", VideoStatusFinished, VideoStatusProcessing, false},
+		{"canceled to failed", VideoStatusCanceled, VideoStatusFailed, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.from.CanTransitionTo(tc.to))
+		})
+	}
+}
+
 func TestUpdateVideoStatusInput(t *testing.T) {
 	t.Run("should create UpdateVideoStatusInput with valid data", func(t *testing.T) {
 		// Arrange
@@ -1,3 +1,5 @@
 //go:generate go run go.uber.org/mock/mockgen -source=video_port.go -destination=mocks/video_port_mock.go
+//go:generate go run go.uber.org/mock/mockgen -source=user_tier_port.go -destination=mocks/user_tier_port_mock.go
+//go:generate go run go.uber.org/mock/mockgen -source=video_job_port.go -destination=mocks/video_job_port_mock.go
 
 package port
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: user_tier_port.go
+//
+// Generated by this command:
+//
+//	mockgen -source=user_tier_port.go -destination=mocks/user_tier_port_mock.go
+//
+
+// Package mock_port is a generated GoMock package.
+package mock_port
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserTierGateway is a mock of UserTierGateway interface.
+type MockUserTierGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserTierGatewayMockRecorder
+	isgomock struct{}
+}
+
+// MockUserTierGatewayMockRecorder is the mock recorder for MockUserTierGateway.
+type MockUserTierGatewayMockRecorder struct {
+	mock *MockUserTierGateway
+}
+
+// NewMockUserTierGateway creates a new mock instance.
+func NewMockUserTierGateway(ctrl *gomock.Controller) *MockUserTierGateway {
+	mock := &MockUserTierGateway{ctrl: ctrl}
+	mock.recorder = &MockUserTierGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserTierGateway) EXPECT() *MockUserTierGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetUserTier mocks base method.
+func (m *MockUserTierGateway) GetUserTier(ctx context.Context, userId int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTier", ctx, userId)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTier indicates an expected call of GetUserTier.
+func (mr *MockUserTierGatewayMockRecorder) GetUserTier(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTier", reflect.TypeOf((*MockUserTierGateway)(nil).GetUserTier), ctx, userId)
+}
@@ -0,0 +1,134 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: video_job_port.go
+//
+// Generated by this command:
+//
+//	mockgen -source=video_job_port.go -destination=mocks/video_job_port_mock.go
+//
+
+// Package mock_port is a generated GoMock package.
+package mock_port
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dto "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStartVideoJobUsecase is a mock of StartVideoJobUsecase interface.
+type MockStartVideoJobUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockStartVideoJobUsecaseMockRecorder
+	isgomock struct{}
+}
+
+// MockStartVideoJobUsecaseMockRecorder is the mock recorder for MockStartVideoJobUsecase.
+type MockStartVideoJobUsecaseMockRecorder struct {
+	mock *MockStartVideoJobUsecase
+}
+
+// NewMockStartVideoJobUsecase creates a new mock instance.
+func NewMockStartVideoJobUsecase(ctrl *gomock.Controller) *MockStartVideoJobUsecase {
+	mock := &MockStartVideoJobUsecase{ctrl: ctrl}
+	mock.recorder = &MockStartVideoJobUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStartVideoJobUsecase) EXPECT() *MockStartVideoJobUsecaseMockRecorder {
+	return m.recorder
+}
+
+// StartVideoJob mocks base method.
+func (m *MockStartVideoJobUsecase) StartVideoJob(ctx context.Context, input dto.StartVideoJobInput) (*dto.StartVideoJobOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartVideoJob", ctx, input)
+	ret0, _ := ret[0].(*dto.StartVideoJobOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartVideoJob indicates an expected call of StartVideoJob.
+func (mr *MockStartVideoJobUsecaseMockRecorder) StartVideoJob(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartVideoJob", reflect.TypeOf((*MockStartVideoJobUsecase)(nil).StartVideoJob), ctx, input)
+}
+
+// MockObjectGateway is a mock of ObjectGateway interface.
+type MockObjectGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MockObjectGatewayMockRecorder
+	isgomock struct{}
+}
+
+// MockObjectGatewayMockRecorder is the mock recorder for MockObjectGateway.
+type MockObjectGatewayMockRecorder struct {
+	mock *MockObjectGateway
+}
+
+// NewMockObjectGateway creates a new mock instance.
+func NewMockObjectGateway(ctrl *gomock.Controller) *MockObjectGateway {
+	mock := &MockObjectGateway{ctrl: ctrl}
+	mock.recorder = &MockObjectGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockObjectGateway) EXPECT() *MockObjectGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetObjectInfo mocks base method.
+func (m *MockObjectGateway) GetObjectInfo(ctx context.Context, bucket, key, versionId string) (*dto.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetObjectInfo", ctx, bucket, key, versionId)
+	ret0, _ := ret[0].(*dto.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetObjectInfo indicates an expected call of GetObjectInfo.
+func (mr *MockObjectGatewayMockRecorder) GetObjectInfo(ctx, bucket, key, versionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectInfo", reflect.TypeOf((*MockObjectGateway)(nil).GetObjectInfo), ctx, bucket, key, versionId)
+}
+
+// MockJobScheduler is a mock of JobScheduler interface.
+type MockJobScheduler struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobSchedulerMockRecorder
+	isgomock struct{}
+}
+
+// MockJobSchedulerMockRecorder is the mock recorder for MockJobScheduler.
+type MockJobSchedulerMockRecorder struct {
+	mock *MockJobScheduler
+}
+
+// NewMockJobScheduler creates a new mock instance.
+func NewMockJobScheduler(ctrl *gomock.Controller) *MockJobScheduler {
+	mock := &MockJobScheduler{ctrl: ctrl}
+	mock.recorder = &MockJobSchedulerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobScheduler) EXPECT() *MockJobSchedulerMockRecorder {
+	return m.recorder
+}
+
+// ScheduleVideoJobs mocks base method.
+func (m *MockJobScheduler) ScheduleVideoJobs(ctx context.Context, input dto.ScheduleVideoJobsInput) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleVideoJobs", ctx, input)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScheduleVideoJobs indicates an expected call of ScheduleVideoJobs.
+func (mr *MockJobSchedulerMockRecorder) ScheduleVideoJobs(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleVideoJobs", reflect.TypeOf((*MockJobScheduler)(nil).ScheduleVideoJobs), ctx, input)
+}
@@ -0,0 +1,8 @@
+package port
+
+import "context"
+
+// UserTierGateway looks up the subscription tier of a user. An empty tier means the user has no tier.
+type UserTierGateway interface {
+	GetUserTier(ctx context.Context, userId int64) (string, error)
+}
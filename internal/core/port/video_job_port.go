@@ -0,0 +1,22 @@
+package port
+
+import (
+	"context"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+)
+
+// StartVideoJobUsecase starts the processing of an uploaded video
+type StartVideoJobUsecase interface {
+	StartVideoJob(ctx context.Context, input dto.StartVideoJobInput) (*dto.StartVideoJobOutput, error)
+}
+
+// ObjectGateway reads the metadata of uploaded objects. An empty versionId reads the latest version.
+type ObjectGateway interface {
+	GetObjectInfo(ctx context.Context, bucket string, key string, versionId string) (*dto.ObjectInfo, error)
+}
+
+// JobScheduler creates the processor job of a video and the checker job following it
+type JobScheduler interface {
+	ScheduleVideoJobs(ctx context.Context, input dto.ScheduleVideoJobsInput) error
+}
@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port"
+)
+
+// StartVideoJobUsecase starts the jobs of an uploaded video: it reads the video and the user
+// from the metadata of the object, names the jobs after the object and the attempt, schedules
+// them and announces reprocessing attempts
+type StartVideoJobUsecase struct {
+	objects      port.ObjectGateway
+	jobs         port.JobScheduler
+	videoGateway port.VideoGateway
+	jobPrefix    string
+}
+
+func NewStartVideoJobUsecase(objects port.ObjectGateway, jobs port.JobScheduler, videoGateway port.VideoGateway, jobPrefix string) *StartVideoJobUsecase {
+	return &StartVideoJobUsecase{
+		objects:      objects,
+		jobs:         jobs,
+		videoGateway: videoGateway,
+		jobPrefix:    jobPrefix,
+	}
+}
+
+// StartVideoJob schedules the jobs of a video. It fails with CodeMissingMetadata when the
+// object doesn't identify its video or user.
+func (u *StartVideoJobUsecase) StartVideoJob(ctx context.Context, input dto.StartVideoJobInput) (*dto.StartVideoJobOutput, error) {
+	objectInfo, err := u.objects.GetObjectInfo(ctx, input.Bucket, input.Key, input.VersionId)
+	if err != nil {
+		return nil, fmt.Errorf("error getting object metadata: %w", err)
+	}
+
+	videoId := input.VideoId
+	if videoId == 0 {
+		videoId, err = strconv.ParseInt(objectInfo.Metadata["video-id"], 10, 64)
+		if err != nil {
+			return nil, domain.NewError(domain.CodeMissingMetadata, "error parsing video id", err)
+		}
+	}
+
+	userId := input.UserId
+	if userId == 0 {
+		userId, err = strconv.ParseInt(objectInfo.Metadata["user-id"], 10, 64)
+		if err != nil {
+			return nil, domain.NewError(domain.CodeMissingMetadata, "error parsing user id", err)
+		}
+	}
+
+	attempt := max(input.Attempt, 1)
+	revision := max(input.Revision, 1)
+	jobName, checkerJobName := jobNames(u.jobPrefix, input.Key, revision, attempt)
+
+	err = u.jobs.ScheduleVideoJobs(ctx, dto.ScheduleVideoJobsInput{
+		JobName:        jobName,
+		CheckerJobName: checkerJobName,
+		Bucket:         input.Bucket,
+		Key:            input.Key,
+		VersionId:      input.VersionId,
+		Sequencer:      input.Sequencer,
+		ETag:           input.ETag,
+		VideoId:        videoId,
+		UserId:         userId,
+		Metadata:       objectInfo.Metadata,
+		Size:           objectInfo.Size,
+		Attempt:        attempt,
+		Revision:       revision,
+		Template:       input.Template,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The checker announces the first attempt once it starts following the job
+	if attempt > 1 {
+		err = u.videoGateway.UpdateVideoStatus(ctx, dto.UpdateVideoStatusInput{
+			VideoId: videoId,
+			UserId:  userId,
+			Status:  dto.VideoStatusReprocessing,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &dto.StartVideoJobOutput{
+		VideoId:        videoId,
+		UserId:         userId,
+		JobName:        jobName,
+		CheckerJobName: checkerJobName,
+	}, nil
+}
+
+// invalidJobNameChars matches the runs of characters Kubernetes doesn't accept in a job name
+var invalidJobNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// maxJobNameLength is the longest job name, as Kubernetes labels the pods of a job with its name
+const maxJobNameLength = 63
+
+// jobNames names the jobs of a video after the file name of its object without extension,
+// such as "video-processor-movie" and "video-processor-movie-checker" for "uploads/movie.mp4",
+// suffixed by the revision when overwritten, "movie-r2", then by the attempt when reprocessed. The file name is lowercased and other characters
+// than letters, digits and dashes are replaced by dashes, "My Movie.mp4" names "my-movie".
+// A file name left without any of them, such as "日本.mp4", is replaced by a hash of the key so
+// that different keys don't share the same jobs. A file name too long for the checker name to
+// fit in maxJobNameLength is cut and suffixed by the hash of the key, for the same reason.
+func jobNames(prefix string, key string, revision int, attempt int) (jobName string, checkerJobName string) {
+	const checkerSuffix = "-checker"
+
+	splittedKey := strings.Split(key, "/")
+	fileName := splittedKey[len(splittedKey)-1]
+	fileNameWithoutExtension := strings.Split(fileName, ".")[0]
+	fileNameWithoutExtension = strings.Trim(invalidJobNameChars.ReplaceAllString(strings.ToLower(fileNameWithoutExtension), "-"), "-")
+
+	suffix := ""
+	if revision > 1 {
+		suffix = fmt.Sprintf("-r%d", revision)
+	}
+	if attempt > 1 {
+		suffix = fmt.Sprintf("%s-%d", suffix, attempt)
+	}
+	maxLength := maxJobNameLength - len(prefix) - len("-") - len(suffix) - len(checkerSuffix)
+	if fileNameWithoutExtension == "" {
+		fileNameWithoutExtension = keyHash(key)
+	} else if len(fileNameWithoutExtension) > maxLength {
+		hash := keyHash(key)
+		cut := strings.Trim(fileNameWithoutExtension[:max(maxLength-len(hash)-1, 0)], "-")
+		fileNameWithoutExtension = strings.TrimPrefix(cut+"-"+hash, "-")
+	}
+
+	jobName = fmt.Sprintf("%s-%s%s", prefix, fileNameWithoutExtension, suffix)
+	return jobName, jobName + checkerSuffix
+}
+
+// keyHash is a short hash of an object key that is valid in a job name
+func keyHash(key string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return fmt.Sprintf("%08x", hash.Sum32())
+}
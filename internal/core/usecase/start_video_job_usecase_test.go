@@ -0,0 +1,290 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	mocks "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStartVideoJobUsecase_StartVideoJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockObjects := mocks.NewMockObjectGateway(ctrl)
+	mockJobs := mocks.NewMockJobScheduler(ctrl)
+	mockVideoGateway := mocks.NewMockVideoGateway(ctrl)
+	usecase := NewStartVideoJobUsecase(mockObjects, mockJobs, mockVideoGateway, "video-processor")
+
+	objectInfo := &dto.ObjectInfo{
+		Metadata: map[string]string{"video-id": "123", "user-id": "456"},
+		Size:     1024,
+	}
+
+	t.Run("should schedule the jobs of an uploaded video", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		input := dto.StartVideoJobInput{Bucket: "bucket", Key: "uploads/movie.mp4", Attempt: 1}
+
+		mockObjects.EXPECT().
+			GetObjectInfo(ctx, "bucket", "uploads/movie.mp4", "").
+			Return(objectInfo, nil).
+			Times(1)
+		mockJobs.EXPECT().
+			ScheduleVideoJobs(ctx, dto.ScheduleVideoJobsInput{
+				JobName:        "video-processor-movie",
+				CheckerJobName: "video-processor-movie-checker",
+				Bucket:         "bucket",
+				Key:            "uploads/movie.mp4",
+				VideoId:        123,
+				UserId:         456,
+				Metadata:       objectInfo.Metadata,
+				Size:           1024,
+				Attempt:        1,
+				Revision:       1,
+			}).
+			Return(nil).
+			Times(1)
+
+		// Act
+		output, err := usecase.StartVideoJob(ctx, input)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, &dto.StartVideoJobOutput{
+			VideoId:        123,
+			UserId:         456,
+			JobName:        "video-processor-movie",
+			CheckerJobName: "video-processor-movie-checker",
+		}, output)
+	})
+
+	t.Run("should read the metadata of the version of the object", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		input := dto.StartVideoJobInput{Bucket: "bucket", Key: "movie.mp4", VersionId: "v2", Sequencer: "0055AED6DCD90281E5", ETag: "etag", Attempt: 1}
+
+		mockObjects.EXPECT().
+			GetObjectInfo(ctx, "bucket", "movie.mp4", "v2").
+			Return(objectInfo, nil).
+			Times(1)
+		mockJobs.EXPECT().
+			ScheduleVideoJobs(ctx, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input dto.ScheduleVideoJobsInput) error {
+				assert.Equal(t, "v2", input.VersionId)
+				assert.Equal(t, "0055AED6DCD90281E5", input.Sequencer)
+				assert.Equal(t, "etag", input.ETag)
+				return nil
+			}).
+			Times(1)
+
+		// Act
+		_, err := usecase.StartVideoJob(ctx, input)
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reschedule a video and publish the reprocessing status", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		input := dto.StartVideoJobInput{Bucket: "bucket", Key: "movie.mp4", VideoId: 123, UserId: 789, Attempt: 2, Template: "premium"}
+
+		mockObjects.EXPECT().
+			GetObjectInfo(ctx, "bucket", "movie.mp4", "").
+			Return(objectInfo, nil).
+			Times(1)
+		mockJobs.EXPECT().
+			ScheduleVideoJobs(ctx, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input dto.ScheduleVideoJobsInput) error {
+				assert.Equal(t, "video-processor-movie-2", input.JobName)
+				assert.Equal(t, "video-processor-movie-2-checker", input.CheckerJobName)
+				assert.Equal(t, int64(789), input.UserId)
+				assert.Equal(t, "premium", input.Template)
+				return nil
+			}).
+			Times(1)
+		mockVideoGateway.EXPECT().
+			UpdateVideoStatus(ctx, dto.UpdateVideoStatusInput{VideoId: 123, UserId: 789, Status: dto.VideoStatusReprocessing}).
+			Return(nil).
+			Times(1)
+
+		// Act
+		output, err := usecase.StartVideoJob(ctx, input)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "video-processor-movie-2", output.JobName)
+	})
+
+	t.Run("should name the jobs of an overwritten object after its revision without announcing it", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		input := dto.StartVideoJobInput{Bucket: "bucket", Key: "movie.mp4", Attempt: 1, Revision: 2}
+
+		mockObjects.EXPECT().
+			GetObjectInfo(ctx, "bucket", "movie.mp4", "").
+			Return(objectInfo, nil).
+			Times(1)
+		mockJobs.EXPECT().
+			ScheduleVideoJobs(ctx, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input dto.ScheduleVideoJobsInput) error {
+				assert.Equal(t, "video-processor-movie-r2", input.JobName)
+				assert.Equal(t, "video-processor-movie-r2-checker", input.CheckerJobName)
+				assert.Equal(t, 1, input.Attempt)
+				assert.Equal(t, 2, input.Revision)
+				return nil
+			}).
+			Times(1)
+
+		// Act
+		output, err := usecase.StartVideoJob(ctx, input)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "video-processor-movie-r2", output.JobName)
+	})
+
+	t.Run("should fail with missing metadata when the object has no video id", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		input := dto.StartVideoJobInput{Bucket: "bucket", Key: "movie.mp4"}
+
+		mockObjects.EXPECT().
+			GetObjectInfo(ctx, "bucket", "movie.mp4", "").
+			Return(&dto.ObjectInfo{Metadata: map[string]string{"user-id": "456"}}, nil).
+			Times(1)
+
+		// Act
+		output, err := usecase.StartVideoJob(ctx, input)
+
+		// Assert
+		assert.Nil(t, output)
+		assert.Equal(t, domain.CodeMissingMetadata, domain.CodeOf(err))
+		assert.True(t, domain.IsPermanent(err))
+	})
+
+	t.Run("should return error when the object metadata can't be read", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		input := dto.StartVideoJobInput{Bucket: "bucket", Key: "movie.mp4"}
+		expectedError := errors.New("S3 error")
+
+		mockObjects.EXPECT().
+			GetObjectInfo(ctx, "bucket", "movie.mp4", "").
+			Return(nil, expectedError).
+			Times(1)
+
+		// Act
+		output, err := usecase.StartVideoJob(ctx, input)
+
+		// Assert
+		assert.Nil(t, output)
+		assert.ErrorIs(t, err, expectedError)
+		assert.Contains(t, err.Error(), "error getting object metadata")
+	})
+
+	t.Run("should return error when the jobs can't be scheduled", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		input := dto.StartVideoJobInput{Bucket: "bucket", Key: "movie.mp4", Attempt: 2}
+		expectedError := errors.New("k8s error")
+
+		mockObjects.EXPECT().
+			GetObjectInfo(ctx, "bucket", "movie.mp4", "").
+			Return(objectInfo, nil).
+			Times(1)
+		mockJobs.EXPECT().
+			ScheduleVideoJobs(ctx, gomock.Any()).
+			Return(expectedError).
+			Times(1)
+
+		// Act
+		output, err := usecase.StartVideoJob(ctx, input)
+
+		// Assert
+		assert.Nil(t, output)
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestJobNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		key         string
+		revision    int
+		attempt     int
+		wantJob     string
+		wantChecker string
+	}{
+		{name: "should name the jobs after the file name", key: "uploads/2024/movie.mp4", revision: 1, attempt: 1, wantJob: "video-processor-movie", wantChecker: "video-processor-movie-checker"},
+		{name: "should keep a key without extension", key: "movie", revision: 1, attempt: 1, wantJob: "video-processor-movie", wantChecker: "video-processor-movie-checker"},
+		{name: "should replace the characters not allowed in a job name", key: "uploads/My Movie (1).mp4", revision: 1, attempt: 1, wantJob: "video-processor-my-movie-1", wantChecker: "video-processor-my-movie-1-checker"},
+		{name: "should suffix the attempt", key: "movie.mp4", revision: 1, attempt: 3, wantJob: "video-processor-movie-3", wantChecker: "video-processor-movie-3-checker"},
+		{name: "should suffix the revision", key: "movie.mp4", revision: 2, attempt: 1, wantJob: "video-processor-movie-r2", wantChecker: "video-processor-movie-r2-checker"},
+		{name: "should suffix the revision then the attempt", key: "movie.mp4", revision: 3, attempt: 2, wantJob: "video-processor-movie-r3-2", wantChecker: "video-processor-movie-r3-2-checker"},
+		{name: "should hash a key without a character allowed in a job name", key: "uploads/日本.mp4", revision: 1, attempt: 2, wantJob: "video-processor-72164dff-2", wantChecker: "video-processor-72164dff-2-checker"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			jobName, checkerJobName := jobNames("video-processor", tt.key, tt.revision, tt.attempt)
+
+			// Assert
+			assert.Equal(t, tt.wantJob, jobName)
+			assert.Equal(t, tt.wantChecker, checkerJobName)
+		})
+	}
+
+	t.Run("should not share the jobs of keys without a character allowed in a job name", func(t *testing.T) {
+		// Act
+		first, _ := jobNames("video-processor", "uploads/日本.mp4", 1, 1)
+		second, _ := jobNames("video-processor", "uploads/中国.mp4", 1, 1)
+		hidden, _ := jobNames("video-processor", "uploads/.mp4", 1, 1)
+
+		// Assert
+		assert.Regexp(t, `^video-processor-[0-9a-f]{8}$`, first)
+		assert.NotEqual(t, first, second)
+		assert.NotEqual(t, "video-processor-", hidden)
+	})
+
+	t.Run("should cut a long file name to fit the checker name in 63 characters", func(t *testing.T) {
+		// Arrange
+		longName := strings.Repeat("a", 199)
+		validName := regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+		// Act
+		jobName, checkerJobName := jobNames("video-processor", "uploads/"+longName+"1.mp4", 1, 1)
+		otherJobName, _ := jobNames("video-processor", "uploads/"+longName+"2.mp4", 1, 1)
+		retryJobName, retryCheckerJobName := jobNames("video-processor", "uploads/"+longName+"1.mp4", 1, 12)
+
+		// Assert
+		assert.Len(t, checkerJobName, 63)
+		assert.Regexp(t, validName, checkerJobName)
+		assert.Regexp(t, `^video-processor-a+-[0-9a-f]{8}$`, jobName)
+		assert.NotEqual(t, jobName, otherJobName)
+		assert.Len(t, retryCheckerJobName, 63)
+		assert.Regexp(t, validName, retryCheckerJobName)
+		assert.True(t, strings.HasSuffix(retryJobName, "-12"))
+	})
+
+	t.Run("should keep a file name that fits", func(t *testing.T) {
+		// Arrange
+		fileName := strings.Repeat("a", 63-len("video-processor-")-len("-checker"))
+
+		// Act
+		_, checkerJobName := jobNames("video-processor", "uploads/"+fileName+".mp4", 1, 1)
+
+		// Assert
+		assert.Equal(t, "video-processor-"+fileName+"-checker", checkerJobName)
+	})
+}
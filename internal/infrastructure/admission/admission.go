@@ -0,0 +1,78 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+)
+
+// Admission decides if the jobs of a new video can be created now, based on how many
+// processor jobs are already active in the namespace and for the same user
+type Admission struct {
+	k8sAPI               api.K8sAPIInterface
+	namespace            string
+	maxActiveJobs        int
+	maxActiveJobsPerUser int
+	retryDelay           time.Duration
+	maxRetryDelay        time.Duration
+}
+
+func NewAdmission(k8sAPI api.K8sAPIInterface, cfg *config.Config) *Admission {
+	return &Admission{
+		k8sAPI:               k8sAPI,
+		namespace:            cfg.K8S.Namespace,
+		maxActiveJobs:        cfg.Admission.MaxActiveJobs,
+		maxActiveJobsPerUser: cfg.Admission.MaxActiveJobsPerUser,
+		retryDelay:           cfg.Admission.RetryDelay,
+		maxRetryDelay:        cfg.Admission.MaxRetryDelay,
+	}
+}
+
+// Admit returns a domain.ThrottledError matching domain.ErrTooManyActiveJobs when a new video
+// would exceed the global or the per user limit. The retry delay grows with how far over the
+// limit the namespace is.
+func (a *Admission) Admit(ctx context.Context, userId int64) error {
+	selector := fmt.Sprintf("%s=%s,%s=%s", api.LabelManagedBy, api.ManagedByJobStarter, api.LabelComponent, api.ComponentProcessor)
+
+	if a.maxActiveJobs > 0 {
+		active, err := a.k8sAPI.CountActiveJobs(ctx, a.namespace, selector)
+		if err != nil {
+			return fmt.Errorf("error counting active jobs: %w", err)
+		}
+		if active >= a.maxActiveJobs {
+			return domain.NewThrottledError(
+				fmt.Sprintf("%s: %d of %d in namespace %s", domain.ErrTooManyActiveJobs, active, a.maxActiveJobs, a.namespace),
+				a.retryAfter(active-a.maxActiveJobs),
+				domain.ErrTooManyActiveJobs,
+			)
+		}
+	}
+
+	if a.maxActiveJobsPerUser > 0 {
+		active, err := a.k8sAPI.CountActiveJobs(ctx, a.namespace, fmt.Sprintf("%s,%s=%d", selector, api.LabelUserID, userId))
+		if err != nil {
+			return fmt.Errorf("error counting active jobs for user %d: %w", userId, err)
+		}
+		if active >= a.maxActiveJobsPerUser {
+			return domain.NewThrottledError(
+				fmt.Sprintf("%s: %d of %d for user %d", domain.ErrTooManyActiveJobs, active, a.maxActiveJobsPerUser, userId),
+				a.retryAfter(active-a.maxActiveJobsPerUser),
+				domain.ErrTooManyActiveJobs,
+			)
+		}
+	}
+
+	return nil
+}
+
+func (a *Admission) retryAfter(excess int) time.Duration {
+	delay := a.retryDelay * time.Duration(excess+1)
+	if a.maxRetryDelay > 0 && delay > a.maxRetryDelay {
+		delay = a.maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,129 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	mocks "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api/mocks"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const processorSelector = "app.kubernetes.io/managed-by=job-starter,app.kubernetes.io/component=processor"
+
+func newConfig(maxActiveJobs, maxActiveJobsPerUser int) *config.Config {
+	cfg := &config.Config{}
+	cfg.K8S.Namespace = "video"
+	cfg.Admission.MaxActiveJobs = maxActiveJobs
+	cfg.Admission.MaxActiveJobsPerUser = maxActiveJobsPerUser
+	cfg.Admission.RetryDelay = 30 * time.Second
+	cfg.Admission.MaxRetryDelay = 2 * time.Minute
+	return cfg
+}
+
+func TestAdmission_Admit(t *testing.T) {
+	t.Run("should admit without counting jobs when limits are disabled", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockK8sAPI := mocks.NewMockK8sAPIInterface(ctrl)
+		admission := NewAdmission(mockK8sAPI, newConfig(0, 0))
+
+		// Act
+		err := admission.Admit(context.Background(), 1)
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should admit when under both limits", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockK8sAPI := mocks.NewMockK8sAPIInterface(ctrl)
+		admission := NewAdmission(mockK8sAPI, newConfig(10, 2))
+
+		mockK8sAPI.EXPECT().CountActiveJobs(gomock.Any(), "video", processorSelector).Return(5, nil)
+		mockK8sAPI.EXPECT().CountActiveJobs(gomock.Any(), "video", processorSelector+",user-id=1").Return(1, nil)
+
+		// Act
+		err := admission.Admit(context.Background(), 1)
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should throttle when the global limit is reached", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockK8sAPI := mocks.NewMockK8sAPIInterface(ctrl)
+		admission := NewAdmission(mockK8sAPI, newConfig(10, 2))
+
+		mockK8sAPI.EXPECT().CountActiveJobs(gomock.Any(), "video", processorSelector).Return(11, nil)
+
+		// Act
+		err := admission.Admit(context.Background(), 1)
+
+		// Assert
+		var throttledErr *domain.ThrottledError
+		assert.True(t, errors.As(err, &throttledErr))
+		assert.Equal(t, time.Minute, throttledErr.RetryAfter)
+		assert.ErrorIs(t, err, domain.ErrTooManyActiveJobs)
+		assert.Equal(t, domain.CodeThrottled, domain.CodeOf(err))
+	})
+
+	t.Run("should throttle when the user limit is reached", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockK8sAPI := mocks.NewMockK8sAPIInterface(ctrl)
+		admission := NewAdmission(mockK8sAPI, newConfig(0, 2))
+
+		mockK8sAPI.EXPECT().CountActiveJobs(gomock.Any(), "video", processorSelector+",user-id=7").Return(2, nil)
+
+		// Act
+		err := admission.Admit(context.Background(), 7)
+
+		// Assert
+		var throttledErr *domain.ThrottledError
+		assert.True(t, errors.As(err, &throttledErr))
+		assert.Equal(t, 30*time.Second, throttledErr.RetryAfter)
+		assert.Contains(t, err.Error(), "user 7")
+		assert.ErrorIs(t, err, domain.ErrTooManyActiveJobs)
+	})
+
+	t.Run("should cap the retry delay", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockK8sAPI := mocks.NewMockK8sAPIInterface(ctrl)
+		admission := NewAdmission(mockK8sAPI, newConfig(1, 0))
+
+		mockK8sAPI.EXPECT().CountActiveJobs(gomock.Any(), "video", processorSelector).Return(50, nil)
+
+		// Act
+		err := admission.Admit(context.Background(), 1)
+
+		// Assert
+		var throttledErr *domain.ThrottledError
+		assert.True(t, errors.As(err, &throttledErr))
+		assert.Equal(t, 2*time.Minute, throttledErr.RetryAfter)
+	})
+
+	t.Run("should return error when counting jobs fails", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockK8sAPI := mocks.NewMockK8sAPIInterface(ctrl)
+		admission := NewAdmission(mockK8sAPI, newConfig(10, 0))
+
+		mockK8sAPI.EXPECT().CountActiveJobs(gomock.Any(), "video", processorSelector).Return(0, errors.New("api unavailable"))
+
+		// Act
+		err := admission.Admit(context.Background(), 1)
+
+		// Assert
+		var throttledErr *domain.ThrottledError
+		assert.Error(t, err)
+		assert.False(t, errors.As(err, &throttledErr))
+	})
+}
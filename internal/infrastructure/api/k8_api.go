@@ -2,15 +2,62 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/tracing"
+)
+
+// Labels set on the jobs created by the starter, used to find them later by video or user
+const (
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	LabelComponent = "app.kubernetes.io/component"
+	LabelVideoID   = "video-id"
+	LabelUserID    = "user-id"
+	LabelTier      = "tier"
+	LabelAttempt   = "attempt"
+	LabelRevision  = "revision"
+	// LabelVideoObject holds ObjectLabel of the S3 object, as the bucket and key can't be label values
+	LabelVideoObject = "video-object"
+
+	AnnotationVideoBucket = "video-bucket"
+	AnnotationVideoKey    = "video-key"
+	// AnnotationVideoSequencer and AnnotationVideoETag identify the S3 event the jobs were
+	// created for, to tell the stale and duplicate events of the object
+	AnnotationVideoSequencer = "video-sequencer"
+	AnnotationVideoETag      = "video-etag"
+
+	ManagedByJobStarter = "job-starter"
+	ComponentProcessor  = "processor"
+	ComponentChecker    = "checker"
+)
+
+// Job statuses reported by GetLastJobStatus and GetJobProgress. Other job condition types
+// are reported as they are.
+const (
+	JobStatusPending  = "Pending"
+	JobStatusQueued   = "Queued"
+	JobStatusRunning  = "Running"
+	JobStatusComplete = "Complete"
+	JobStatusFailed   = "Failed"
 )
 
 type JobInput struct {
@@ -23,122 +70,292 @@ type JobInput struct {
 	BackOffLimit            int32
 	ImageChecker            string
 	ServiceAccountName      string
+	// Completions greater than one creates an Indexed Job, so each pod can
+	// pick its chunk of the video from JOB_COMPLETION_INDEX.
+	Completions int32
+	// Parallelism limits how many indexes run at the same time. Zero runs all of them.
+	Parallelism       int32
+	Labels            map[string]string
+	Annotations       map[string]string
+	PriorityClassName string
+	// Suspend creates the job with spec.suspend, leaving it to a batch scheduler such as Kueue
+	// to start it when there is capacity. QueueLabel and QueueName select the scheduler queue.
+	Suspend    bool
+	QueueLabel string
+	QueueName  string
+	// StartTimeout bounds the wait for the job to start. Zero waits until ctx is done.
+	StartTimeout time.Duration
+}
+
+// VideoJob identifies a job created by the starter for a video
+type VideoJob struct {
+	Name      string
+	Component string
+	Status    string
+	VideoId   int64
+	UserId    int64
+	Bucket    string
+	Key       string
+	Sequencer string
+	ETag      string
+	Attempt   int
+	Revision  int
+}
+
+// JobProgress is a snapshot of a job status, including per-index progress for Indexed Jobs
+type JobProgress struct {
+	Status           string
+	Completions      int32
+	Active           int32
+	Succeeded        int32
+	Failed           int32
+	CompletedIndexes int32
 }
 
 type K8sAPI struct {
-	Client *kubernetes.Clientset
+	Client kubernetes.Interface
+	logger *logger.Logger
 }
 
-func NewK8sAPI(client *kubernetes.Clientset) *K8sAPI {
-	return &K8sAPI{Client: client}
+func NewK8sAPI(client kubernetes.Interface, logger *logger.Logger) *K8sAPI {
+	return &K8sAPI{Client: client, logger: logger}
 }
 
+// CreateJob creates the job and waits for it to start, unless it is suspended. The trace
+// context of ctx is given to the job as the TRACEPARENT variable and annotation.
 func (k *K8sAPI) CreateJob(ctx context.Context, jobInput *JobInput) error {
-	err := validateParams(jobInput.Namespace, jobInput.JobName, jobInput.Image, jobInput.Cmd)
+	ctx, span := tracing.Start(ctx, "k8s.CreateJob",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.K8SJobName(jobInput.JobName), semconv.K8SNamespaceName(jobInput.Namespace)),
+	)
+	defer span.End()
+
+	ctx = logger.WithFields(ctx, "job", jobInput.JobName, "namespace", jobInput.Namespace)
+	if err := k.createJob(ctx, jobInput); err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	return nil
+}
+
+func (k *K8sAPI) createJob(ctx context.Context, jobInput *JobInput) error {
+	err := validateParams(jobInput.Namespace, jobInput.JobName, jobInput.Image)
 	if err != nil {
 		return err
 	}
 
-	finalJobName := jobInput.JobName
 	jobs := k.Client.BatchV1().Jobs(jobInput.Namespace)
-	var backOffLimit = jobInput.BackOffLimit
+	jobSpec := buildJobSpec(jobInput)
+	withTraceContext(ctx, jobSpec)
+
+	// Only the names of the variables are logged, the values may be secrets
+	k.logger.DebugContext(ctx, "Creating job", "image", jobInput.Image, "envs", slices.Sorted(maps.Keys(jobInput.Envs)))
+
+	_, err = jobs.Create(ctx, jobSpec, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		k.logger.WarnContext(ctx, "Job already exists")
+		return domain.NewError(domain.CodeJobAlreadyExists, fmt.Sprintf("job %s already exists", jobInput.JobName), err)
+	}
+	if err != nil {
+		k.logger.ErrorContext(ctx, "Error creating job", "error", err.Error())
+		return err
+	}
+
+	// A suspended job only starts when the scheduler admits it, so there is nothing to wait for
+	if jobInput.Suspend {
+		k.logger.InfoContext(ctx, "Job created suspended, waiting for capacity")
+		return nil
+	}
+
+	return k.waitForJobStart(ctx, jobInput)
+}
+
+// waitForJobStart watches the job until a pod is running or has failed, for at most the
+// start timeout of the job
+func (k *K8sAPI) waitForJobStart(ctx context.Context, jobInput *JobInput) error {
+	ctx, span := tracing.Start(ctx, "k8s.WatchJob",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.K8SJobName(jobInput.JobName), semconv.K8SNamespaceName(jobInput.Namespace)),
+	)
+	defer span.End()
+
+	if jobInput.StartTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, jobInput.StartTimeout)
+		defer cancel()
+	}
 
-	envVars := make([]v1.EnvVar, 0)
-	if jobInput.Envs != nil {
-		log.Info().Msg(fmt.Sprintf("Job %s envs:", jobInput.JobName))
-		for key, value := range jobInput.Envs {
-			log.Info().Msg(fmt.Sprintf("%s: %s", key, value))
-			envVars = append(envVars, v1.EnvVar{Name: key, Value: value})
+	finalJobName := jobInput.JobName
+	watcher, err := k.Client.BatchV1().
+		Jobs(jobInput.Namespace).
+		Watch(ctx, metav1.ListOptions{
+			FieldSelector: "metadata.name=" + finalJobName,
+		})
+	if err != nil {
+		k.logger.ErrorContext(ctx, "Error watching job", "error", err.Error())
+		return err
+	}
+	// Stop the watch when ctx is done, so the wait ends at the start timeout
+	defer watcher.Stop()
+	stopWatch := context.AfterFunc(ctx, watcher.Stop)
+	defer stopWatch()
+	for event := range watcher.ResultChan() {
+		if event.Type == watch.Error {
+			err := apierrors.FromObject(event.Object)
+			k.logger.ErrorContext(ctx, "Error watching job", "error", err.Error())
+			return fmt.Errorf("error watching job %s: %w", jobInput.JobName, err)
 		}
-	} else {
-		log.Info().Msg(fmt.Sprintf("No environment variables was set for job %s ", jobInput.JobName))
+		job, ok := event.Object.(*batchv1.Job)
+		if !ok {
+			continue
+		}
+		if job.Status.Active > 0 {
+			k.logger.InfoContext(ctx, "Job started successfully")
+			return nil
+		}
+		if job.Status.Failed > 0 {
+			k.logger.InfoContext(ctx, "Job failed", "failedPods", job.Status.Failed)
+
+			pods, _ := k.Client.CoreV1().Pods(jobInput.Namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: "job-name=" + finalJobName,
+			})
+			for _, pod := range pods.Items {
+				for _, cs := range pod.Status.ContainerStatuses {
+					if cs.State.Terminated != nil {
+						k.logger.ErrorContext(ctx, "Job failed with container error",
+							"pod", pod.Name,
+							"exitCode", cs.State.Terminated.ExitCode,
+							"reason", cs.State.Terminated.Reason,
+							"message", cs.State.Terminated.Message,
+						)
+					}
+				}
+			}
+			return domain.NewError(domain.CodeJobFailed, fmt.Sprintf("job %s failed to start", jobInput.JobName), nil)
+		}
+	}
+
+	// The watch stops without an event when ctx is done, before the job could start
+	if ctx.Err() != nil {
+		k.logger.ErrorContext(ctx, "Job did not start in time", "error", ctx.Err().Error())
+		return domain.NewError(domain.CodeJobStartTimeout, fmt.Sprintf("job %s did not start in time", jobInput.JobName), ctx.Err())
+	}
+
+	// The API server can close the watch at any time, which doesn't tell whether the job started
+	k.logger.ErrorContext(ctx, "Job watch closed before the job started")
+	return fmt.Errorf("watch of job %s closed before it started", jobInput.JobName)
+}
+
+func buildJobSpec(jobInput *JobInput) *batchv1.Job {
+	var backOffLimit = jobInput.BackOffLimit
+
+	envVars := make([]v1.EnvVar, 0, len(jobInput.Envs))
+	for key, value := range jobInput.Envs {
+		envVars = append(envVars, v1.EnvVar{Name: key, Value: value})
 	}
 
 	imagePullSecrets := make([]v1.LocalObjectReference, 0)
 	var ttlSecondsAfterFinished = int32(jobInput.TtlSecondsAfterFinished.Seconds())
 	jobSpec := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      finalJobName,
-			Namespace: jobInput.Namespace,
+			Name:        jobInput.JobName,
+			Namespace:   jobInput.Namespace,
+			Labels:      jobInput.Labels,
+			Annotations: jobInput.Annotations,
 		},
 		Spec: batchv1.JobSpec{
 			TTLSecondsAfterFinished: &ttlSecondsAfterFinished,
 			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: jobInput.Labels,
+				},
 				Spec: v1.PodSpec{
 					Containers: []v1.Container{
 						{
 							Name:            jobInput.JobName,
 							Image:           jobInput.Image,
 							ImagePullPolicy: v1.PullAlways,
+							Command:         containerCommand(jobInput.Cmd),
 							Env:             envVars,
 						},
 					},
 					RestartPolicy:      v1.RestartPolicyNever,
 					ImagePullSecrets:   imagePullSecrets,
 					ServiceAccountName: jobInput.ServiceAccountName,
+					PriorityClassName:  jobInput.PriorityClassName,
 				},
 			},
 			BackoffLimit: &backOffLimit,
 		},
 	}
 
-	_, err = jobs.Create(context.TODO(), jobSpec, metav1.CreateOptions{})
-	if err != nil {
-		log.Error().Err(err).Any("job", finalJobName).Any("namespace", jobInput.Namespace).Msg("Error creating job")
-		return err
+	if jobInput.Suspend {
+		suspend := true
+		jobSpec.Spec.Suspend = &suspend
 	}
 
-	watch, err := k.Client.BatchV1().
-		Jobs(jobInput.Namespace).
-		Watch(ctx, metav1.ListOptions{
-			FieldSelector: "metadata.name=" + finalJobName,
-		})
-	if err != nil {
-		log.Error().Err(err).Any("job", finalJobName).Any("namespace", jobInput.Namespace).Msg("Error watching job")
-		return err
+	if jobInput.QueueName != "" && jobInput.QueueLabel != "" {
+		labels := make(map[string]string, len(jobInput.Labels)+1)
+		for key, value := range jobInput.Labels {
+			labels[key] = value
+		}
+		labels[jobInput.QueueLabel] = jobInput.QueueName
+		jobSpec.Labels = labels
 	}
-	for event := range watch.ResultChan() {
-		job := event.Object.(*batchv1.Job)
-		if job.Status.Active > 0 {
-			log.Info().Any("job", finalJobName).Any("namespace", jobInput.Namespace).Msg("Job started successfully")
-			break
+
+	if jobInput.Completions > 1 {
+		completions := jobInput.Completions
+		parallelism := jobInput.Parallelism
+		if parallelism <= 0 || parallelism > completions {
+			parallelism = completions
 		}
-		if job.Status.Failed > 0 {
-			log.Info().
-				Any("job", finalJobName).
-				Any("namespace", jobInput.Namespace).
-				Err(fmt.Errorf("job failed")).
-				Int32("failedPods", job.Status.Failed).
-				Msg("Job failed")
+		completionMode := batchv1.IndexedCompletion
+		jobSpec.Spec.Completions = &completions
+		jobSpec.Spec.Parallelism = &parallelism
+		jobSpec.Spec.CompletionMode = &completionMode
+	}
 
-			pods, _ := k.Client.CoreV1().Pods(jobInput.Namespace).List(ctx, metav1.ListOptions{
-				LabelSelector: "job-name=" + finalJobName,
-			})
-			for _, pod := range pods.Items {
-				for _, cs := range pod.Status.ContainerStatuses {
-					if cs.State.Terminated != nil {
-						log.Error().
-							Str("job", finalJobName).
-							Str("namespace", jobInput.Namespace).
-							Str("pod", pod.Name).
-							Int32("exitCode", cs.State.Terminated.ExitCode).
-							Str("reason", cs.State.Terminated.Reason).
-							Str("message", cs.State.Terminated.Message).
-							Msg("Job failed with container error")
-					}
-				}
-			}
-			return fmt.Errorf("job failed")
+	return jobSpec
+}
+
+// withTraceContext passes the trace context to the containers of the job as environment
+// variables, and records it in the annotations of the job
+func withTraceContext(ctx context.Context, job *batchv1.Job) {
+	fields := tracing.Inject(ctx)
+	if len(fields) == 0 {
+		return
+	}
+
+	annotations := make(map[string]string, len(job.Annotations)+len(fields))
+	for key, value := range job.Annotations {
+		annotations[key] = value
+	}
+	for key, value := range fields {
+		annotations[key] = value
+	}
+	job.Annotations = annotations
+
+	envs := tracing.InjectEnv(ctx)
+	containers := job.Spec.Template.Spec.Containers
+	for i := range containers {
+		for key, value := range envs {
+			containers[i].Env = append(containers[i].Env, v1.EnvVar{Name: key, Value: value})
 		}
 	}
+}
 
-	log.Info().Any("job", finalJobName).Any("namespace", jobInput.Namespace).Msg("Job created successfully")
-	return nil
+// containerCommand runs cmd with a shell, so it can hold arguments and quotes like
+// K8S_JOB_COMMAND does. An empty cmd keeps the entrypoint of the image.
+func containerCommand(cmd string) []string {
+	if cmd == "" {
+		return nil
+	}
+	return []string{"/bin/sh", "-c", cmd}
 }
 
-func validateParams(namespace, jobName, image, cmd string) error {
-	if namespace == "" || jobName == "" || image == "" || cmd == "" {
-		return errors.New("the following envs are mandatory: K8S_NAMESPACE, K8S_JOB_NAME, K8S_JOB_IMAGE, K8S_JOB_COMMAND")
+func validateParams(namespace, jobName, image string) error {
+	if namespace == "" || jobName == "" || image == "" {
+		return errors.New("the following envs are mandatory: K8S_NAMESPACE, K8S_JOB_NAME, K8S_JOB_IMAGE")
 	}
 	return nil
 }
@@ -150,21 +367,224 @@ func (k *K8sAPI) GetLastJobStatus(ctx context.Context, jobName, namespace string
 		return "", err
 	}
 
+	return jobStatus(job), nil
+}
+
+// GetJobProgress returns the job status along with its pod counters and, for Indexed Jobs,
+// how many indexes have already completed
+func (k *K8sAPI) GetJobProgress(ctx context.Context, jobName, namespace string) (*JobProgress, error) {
+	jobs := k.Client.BatchV1().Jobs(namespace)
+	job, err := jobs.Get(ctx, jobName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	completions := int32(1)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+
+	completedIndexes := job.Status.Succeeded
+	if job.Spec.CompletionMode != nil && *job.Spec.CompletionMode == batchv1.IndexedCompletion {
+		completedIndexes = countIndexes(job.Status.CompletedIndexes)
+	}
+
+	return &JobProgress{
+		Status:           jobStatus(job),
+		Completions:      completions,
+		Active:           job.Status.Active,
+		Succeeded:        job.Status.Succeeded,
+		Failed:           job.Status.Failed,
+		CompletedIndexes: completedIndexes,
+	}, nil
+}
+
+// CountActiveJobs counts the jobs matching the label selector that have not completed or failed yet
+func (k *K8sAPI) CountActiveJobs(ctx context.Context, namespace, labelSelector string) (int, error) {
+	jobs, err := k.Client.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	active := 0
+	for i := range jobs.Items {
+		switch jobStatus(&jobs.Items[i]) {
+		case JobStatusComplete, JobStatusFailed:
+		default:
+			active++
+		}
+	}
+	return active, nil
+}
+
+// ListVideoJobs returns the processor and checker jobs of a video
+func (k *K8sAPI) ListVideoJobs(ctx context.Context, namespace string, videoId int64) ([]VideoJob, error) {
+	jobs, err := k.Client.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s=%d", LabelManagedBy, ManagedByJobStarter, LabelVideoID, videoId),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	videoJobs := make([]VideoJob, 0, len(jobs.Items))
+	for i := range jobs.Items {
+		videoJobs = append(videoJobs, newVideoJob(&jobs.Items[i]))
+	}
+	return videoJobs, nil
+}
+
+// DeleteJob deletes a job, leaving its pods to be removed in background. A job that
+// doesn't exist anymore is not an error.
+func (k *K8sAPI) DeleteJob(ctx context.Context, namespace, jobName string) error {
+	propagationPolicy := metav1.DeletePropagationBackground
+	err := k.Client.BatchV1().Jobs(namespace).Delete(ctx, jobName, metav1.DeleteOptions{
+		PropagationPolicy: &propagationPolicy,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting job %s: %w", jobName, err)
+	}
+	return nil
+}
+
+// CancelVideoJobs deletes the processor and checker jobs of a video, and their pods in background.
+// It returns the jobs that were deleted.
+func (k *K8sAPI) CancelVideoJobs(ctx context.Context, namespace string, videoId int64) ([]VideoJob, error) {
+	jobs, err := k.ListVideoJobs(ctx, namespace, videoId)
+	if err != nil {
+		return nil, err
+	}
+
+	canceled := make([]VideoJob, 0, len(jobs))
+	for _, job := range jobs {
+		if err := k.DeleteJob(ctx, namespace, job.Name); err != nil {
+			return canceled, err
+		}
+		k.logger.InfoContext(ctx, "Job canceled", "job", job.Name, "namespace", namespace, "videoId", videoId)
+		canceled = append(canceled, job)
+	}
+	return canceled, nil
+}
+
+// FindVideoJobsByObject returns the jobs created for the given S3 object
+func (k *K8sAPI) FindVideoJobsByObject(ctx context.Context, namespace, bucket, key string) ([]VideoJob, error) {
+	jobs, err := k.Client.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s=%s", LabelManagedBy, ManagedByJobStarter, LabelVideoObject, ObjectLabel(bucket, key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The label is a hash, so the annotations tell the jobs of the object apart on a collision
+	found := make([]VideoJob, 0)
+	for i := range jobs.Items {
+		videoJob := newVideoJob(&jobs.Items[i])
+		if videoJob.Bucket == bucket && videoJob.Key == key {
+			found = append(found, videoJob)
+		}
+	}
+	return found, nil
+}
+
+// ObjectLabel is the value of LabelVideoObject for an S3 object, a hash that fits in a label value
+func ObjectLabel(bucket, key string) string {
+	sum := sha256.Sum256([]byte(bucket + "/" + key))
+	return hex.EncodeToString(sum[:20])
+}
+
+// Ping checks that the API server is reachable by reading its version, within the deadline of ctx
+func (k *K8sAPI) Ping(ctx context.Context) error {
+	if err := k.Client.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Error(); err != nil {
+		return fmt.Errorf("error reaching the kubernetes API: %w", err)
+	}
+	return nil
+}
+
+func newVideoJob(job *batchv1.Job) VideoJob {
+	videoId, _ := strconv.ParseInt(job.Labels[LabelVideoID], 10, 64)
+	userId, _ := strconv.ParseInt(job.Labels[LabelUserID], 10, 64)
+	attempt, err := strconv.Atoi(job.Labels[LabelAttempt])
+	if err != nil {
+		attempt = 1
+	}
+	revision, err := strconv.Atoi(job.Labels[LabelRevision])
+	if err != nil {
+		revision = 1
+	}
+	return VideoJob{
+		Name:      job.Name,
+		Component: job.Labels[LabelComponent],
+		Status:    jobStatus(job),
+		VideoId:   videoId,
+		UserId:    userId,
+		Bucket:    job.Annotations[AnnotationVideoBucket],
+		Key:       job.Annotations[AnnotationVideoKey],
+		Sequencer: job.Annotations[AnnotationVideoSequencer],
+		ETag:      job.Annotations[AnnotationVideoETag],
+		Attempt:   attempt,
+		Revision:  revision,
+	}
+}
+
+func jobStatus(job *batchv1.Job) string {
 	// Check if job has any conditions
 	if len(job.Status.Conditions) == 0 {
 		// If no conditions are set yet, check the job status directly
 		if job.Status.Active > 0 {
-			return "Running", nil
+			return JobStatusRunning
 		}
 		if job.Status.Succeeded > 0 {
-			return "Complete", nil
+			return JobStatusComplete
 		}
 		if job.Status.Failed > 0 {
-			return "Failed", nil
+			return JobStatusFailed
+		}
+		// A suspended job waits for the scheduler to give it capacity
+		if job.Spec.Suspend != nil && *job.Spec.Suspend {
+			return JobStatusQueued
 		}
 		// Job is still pending
-		return "Pending", nil
+		return JobStatusPending
+	}
+
+	condition := job.Status.Conditions[len(job.Status.Conditions)-1]
+	if condition.Type == batchv1.JobSuspended {
+		if condition.Status == v1.ConditionTrue {
+			return JobStatusQueued
+		}
+		// The job was resumed, so its counters tell where it is
+		if job.Status.Active > 0 {
+			return JobStatusRunning
+		}
+		return JobStatusPending
 	}
 
-	return string(job.Status.Conditions[len(job.Status.Conditions)-1].Type), nil
+	return string(condition.Type)
+}
+
+// countIndexes counts the indexes in a compressed interval list such as "1,3-5,7"
+func countIndexes(indexes string) int32 {
+	var count int32
+	for _, interval := range strings.Split(indexes, ",") {
+		interval = strings.TrimSpace(interval)
+		if interval == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(interval, "-")
+		if !isRange {
+			count++
+			continue
+		}
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			continue
+		}
+		end, err := strconv.Atoi(last)
+		if err != nil || end < start {
+			continue
+		}
+		count += int32(end - start + 1)
+	}
+	return count
 }
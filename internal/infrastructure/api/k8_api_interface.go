@@ -6,4 +6,11 @@ import "context"
 type K8sAPIInterface interface {
 	CreateJob(ctx context.Context, jobInput *JobInput) error
 	GetLastJobStatus(ctx context.Context, jobName, namespace string) (string, error)
+	GetJobProgress(ctx context.Context, jobName, namespace string) (*JobProgress, error)
+	CountActiveJobs(ctx context.Context, namespace, labelSelector string) (int, error)
+	ListVideoJobs(ctx context.Context, namespace string, videoId int64) ([]VideoJob, error)
+	DeleteJob(ctx context.Context, namespace, jobName string) error
+	CancelVideoJobs(ctx context.Context, namespace string, videoId int64) ([]VideoJob, error)
+	FindVideoJobsByObject(ctx context.Context, namespace, bucket, key string) ([]VideoJob, error)
+	Ping(ctx context.Context) error
 }
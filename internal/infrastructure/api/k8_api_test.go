@@ -1,11 +1,33 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
 )
 
 func TestValidateParams(t *testing.T) {
@@ -14,10 +36,9 @@ func TestValidateParams(t *testing.T) {
 		namespace := "test-namespace"
 		jobName := "test-job"
 		image := "test-image:latest"
-		cmd := "test-command"
 
 		// Act
-		err := validateParams(namespace, jobName, image, cmd)
+		err := validateParams(namespace, jobName, image)
 
 		// Assert
 		assert.NoError(t, err)
@@ -28,10 +49,9 @@ func TestValidateParams(t *testing.T) {
 		namespace := ""
 		jobName := "test-job"
 		image := "test-image:latest"
-		cmd := "test-command"
 
 		// Act
-		err := validateParams(namespace, jobName, image, cmd)
+		err := validateParams(namespace, jobName, image)
 
 		// Assert
 		assert.Error(t, err)
@@ -43,10 +63,9 @@ func TestValidateParams(t *testing.T) {
 		namespace := "test-namespace"
 		jobName := ""
 		image := "test-image:latest"
-		cmd := "test-command"
 
 		// Act
-		err := validateParams(namespace, jobName, image, cmd)
+		err := validateParams(namespace, jobName, image)
 
 		// Assert
 		assert.Error(t, err)
@@ -58,29 +77,26 @@ func TestValidateParams(t *testing.T) {
 		namespace := "test-namespace"
 		jobName := "test-job"
 		image := ""
-		cmd := "test-command"
 
 		// Act
-		err := validateParams(namespace, jobName, image, cmd)
+		err := validateParams(namespace, jobName, image)
 
 		// Assert
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "mandatory")
 	})
 
-	t.Run("should return error for empty command", func(t *testing.T) {
+	t.Run("should accept an empty command", func(t *testing.T) {
 		// Arrange
 		namespace := "test-namespace"
 		jobName := "test-job"
 		image := "test-image:latest"
-		cmd := ""
 
 		// Act
-		err := validateParams(namespace, jobName, image, cmd)
+		err := validateParams(namespace, jobName, image)
 
 		// Assert
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "mandatory")
+		assert.NoError(t, err)
 	})
 
 	t.Run("should return error for all empty parameters", func(t *testing.T) {
@@ -88,10 +104,9 @@ func TestValidateParams(t *testing.T) {
 		namespace := ""
 		jobName := ""
 		image := ""
-		cmd := ""
 
 		// Act
-		err := validateParams(namespace, jobName, image, cmd)
+		err := validateParams(namespace, jobName, image)
 
 		// Assert
 		assert.Error(t, err)
@@ -192,7 +207,19 @@ func TestNewK8sAPI(t *testing.T) {
 		client := &kubernetes.Clientset{}
 
 		// Act
-		k8sAPI := NewK8sAPI(client)
+		k8sAPI := NewK8sAPI(client, newTestLogger())
+
+		// Assert
+		assert.NotNil(t, k8sAPI)
+		assert.Equal(t, client, k8sAPI.Client)
+	})
+
+	t.Run("should create K8sAPI with a fake clientset", func(t *testing.T) {
+		// Arrange
+		client := fake.NewClientset()
+
+		// Act
+		k8sAPI := NewK8sAPI(client, newTestLogger())
 
 		// Assert
 		assert.NotNil(t, k8sAPI)
@@ -201,10 +228,673 @@ func TestNewK8sAPI(t *testing.T) {
 
 	t.Run("should create K8sAPI with nil client", func(t *testing.T) {
 		// Act
-		k8sAPI := NewK8sAPI(nil)
+		k8sAPI := NewK8sAPI(nil, newTestLogger())
 
 		// Assert
 		assert.NotNil(t, k8sAPI)
 		assert.Nil(t, k8sAPI.Client)
 	})
 }
+
+func TestBuildJobSpec(t *testing.T) {
+	t.Run("should create a single pod job when completions is not set", func(t *testing.T) {
+		// Arrange
+		jobInput := &JobInput{
+			Namespace: "test-namespace",
+			JobName:   "test-job",
+			Image:     "test-image:latest",
+			Cmd:       "test-command",
+		}
+
+		// Act
+		job := buildJobSpec(jobInput)
+
+		// Assert
+		assert.Equal(t, "test-job", job.Name)
+		assert.Nil(t, job.Spec.Completions)
+		assert.Nil(t, job.Spec.Parallelism)
+		assert.Nil(t, job.Spec.CompletionMode)
+	})
+
+	t.Run("should run the command with a shell in the container", func(t *testing.T) {
+		// Arrange
+		jobInput := &JobInput{
+			Namespace: "test-namespace",
+			JobName:   "test-job",
+			Image:     "test-image:latest",
+			Cmd:       "echo \"Hello, World\"",
+			Envs:      map[string]string{"VIDEO_KEY": "videos/1.mp4"},
+		}
+
+		// Act
+		job := buildJobSpec(jobInput)
+
+		// Assert
+		require.Len(t, job.Spec.Template.Spec.Containers, 1)
+		container := job.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, "test-image:latest", container.Image)
+		assert.Equal(t, []string{"/bin/sh", "-c", "echo \"Hello, World\""}, container.Command)
+		assert.Equal(t, []v1.EnvVar{{Name: "VIDEO_KEY", Value: "videos/1.mp4"}}, container.Env)
+	})
+
+	t.Run("should keep the image entrypoint when the command is empty", func(t *testing.T) {
+		// Arrange
+		jobInput := &JobInput{
+			Namespace: "test-namespace",
+			JobName:   "test-job",
+			Image:     "test-image:latest",
+		}
+
+		// Act
+		job := buildJobSpec(jobInput)
+
+		// Assert
+		assert.Nil(t, job.Spec.Template.Spec.Containers[0].Command)
+	})
+
+	t.Run("should create an indexed job when completions is greater than one", func(t *testing.T) {
+		// Arrange
+		jobInput := &JobInput{
+			Namespace:   "test-namespace",
+			JobName:     "test-job",
+			Image:       "test-image:latest",
+			Cmd:         "test-command",
+			Completions: 5,
+			Parallelism: 2,
+		}
+
+		// Act
+		job := buildJobSpec(jobInput)
+
+		// Assert
+		assert.Equal(t, int32(5), *job.Spec.Completions)
+		assert.Equal(t, int32(2), *job.Spec.Parallelism)
+		assert.Equal(t, batchv1.IndexedCompletion, *job.Spec.CompletionMode)
+	})
+
+	t.Run("should set labels on the job and on its pods", func(t *testing.T) {
+		// Arrange
+		labels := map[string]string{LabelVideoID: "123", LabelUserID: "456"}
+		jobInput := &JobInput{
+			Namespace: "test-namespace",
+			JobName:   "test-job",
+			Image:     "test-image:latest",
+			Cmd:       "test-command",
+			Labels:    labels,
+		}
+
+		// Act
+		job := buildJobSpec(jobInput)
+
+		// Assert
+		assert.Equal(t, labels, job.Labels)
+		assert.Equal(t, labels, job.Spec.Template.Labels)
+	})
+
+	t.Run("should create a suspended job with the queue label", func(t *testing.T) {
+		// Arrange
+		jobInput := &JobInput{
+			Namespace:  "test-namespace",
+			JobName:    "test-job",
+			Image:      "test-image:latest",
+			Cmd:        "test-command",
+			Labels:     map[string]string{LabelVideoID: "123"},
+			Suspend:    true,
+			QueueLabel: "kueue.x-k8s.io/queue-name",
+			QueueName:  "video-queue",
+		}
+
+		// Act
+		job := buildJobSpec(jobInput)
+
+		// Assert
+		assert.True(t, *job.Spec.Suspend)
+		assert.Equal(t, "video-queue", job.Labels["kueue.x-k8s.io/queue-name"])
+		assert.Equal(t, "123", job.Labels[LabelVideoID])
+		assert.NotContains(t, jobInput.Labels, "kueue.x-k8s.io/queue-name")
+	})
+
+	t.Run("should cap parallelism at the number of completions", func(t *testing.T) {
+		// Arrange
+		jobInput := &JobInput{
+			Namespace:   "test-namespace",
+			JobName:     "test-job",
+			Image:       "test-image:latest",
+			Cmd:         "test-command",
+			Completions: 3,
+		}
+
+		// Act
+		job := buildJobSpec(jobInput)
+
+		// Assert
+		assert.Equal(t, int32(3), *job.Spec.Parallelism)
+	})
+}
+
+func TestCountIndexes(t *testing.T) {
+	t.Run("should count single indexes and ranges", func(t *testing.T) {
+		assert.Equal(t, int32(0), countIndexes(""))
+		assert.Equal(t, int32(1), countIndexes("0"))
+		assert.Equal(t, int32(3), countIndexes("0-2"))
+		assert.Equal(t, int32(5), countIndexes("1,3-5,7"))
+	})
+
+	t.Run("should ignore malformed intervals", func(t *testing.T) {
+		assert.Equal(t, int32(1), countIndexes("a-b,4,5-3"))
+	})
+}
+
+func TestJobStatus(t *testing.T) {
+	suspended := true
+	resumed := false
+
+	testCases := []struct {
+		name     string
+		job      batchv1.Job
+		expected string
+	}{
+		{
+			name:     "pending job without conditions",
+			job:      batchv1.Job{},
+			expected: JobStatusPending,
+		},
+		{
+			name:     "running job without conditions",
+			job:      batchv1.Job{Status: batchv1.JobStatus{Active: 1}},
+			expected: JobStatusRunning,
+		},
+		{
+			name:     "suspended job without conditions",
+			job:      batchv1.Job{Spec: batchv1.JobSpec{Suspend: &suspended}},
+			expected: JobStatusQueued,
+		},
+		{
+			name: "suspended job with condition",
+			job: batchv1.Job{
+				Spec: batchv1.JobSpec{Suspend: &suspended},
+				Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+					{Type: batchv1.JobSuspended, Status: v1.ConditionTrue},
+				}},
+			},
+			expected: JobStatusQueued,
+		},
+		{
+			name: "resumed job",
+			job: batchv1.Job{
+				Spec: batchv1.JobSpec{Suspend: &resumed},
+				Status: batchv1.JobStatus{
+					Active: 1,
+					Conditions: []batchv1.JobCondition{
+						{Type: batchv1.JobSuspended, Status: v1.ConditionFalse},
+					},
+				},
+			},
+			expected: JobStatusRunning,
+		},
+		{
+			name: "completed job",
+			job: batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobComplete, Status: v1.ConditionTrue},
+			}}},
+			expected: JobStatusComplete,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, jobStatus(&tc.job))
+		})
+	}
+}
+
+func TestNewVideoJob(t *testing.T) {
+	t.Run("should read video, user and object from labels and annotations", func(t *testing.T) {
+		// Arrange
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "video-processor-movie",
+				Labels: map[string]string{LabelVideoID: "123", LabelUserID: "456", LabelComponent: ComponentProcessor, LabelAttempt: "2", LabelRevision: "3"},
+				Annotations: map[string]string{
+					AnnotationVideoBucket:    "uploads",
+					AnnotationVideoKey:       "videos/movie.mp4",
+					AnnotationVideoSequencer: "0055AED6DCD90281E5",
+					AnnotationVideoETag:      "d41d8cd98f00b204e9800998ecf8427e",
+				},
+			},
+			Status: batchv1.JobStatus{Active: 1},
+		}
+
+		// Act
+		videoJob := newVideoJob(job)
+
+		// Assert
+		assert.Equal(t, VideoJob{
+			Name:      "video-processor-movie",
+			Component: ComponentProcessor,
+			Status:    JobStatusRunning,
+			VideoId:   123,
+			UserId:    456,
+			Bucket:    "uploads",
+			Key:       "videos/movie.mp4",
+			Sequencer: "0055AED6DCD90281E5",
+			ETag:      "d41d8cd98f00b204e9800998ecf8427e",
+			Attempt:   2,
+			Revision:  3,
+		}, videoJob)
+	})
+
+	t.Run("should leave IDs empty when labels are missing", func(t *testing.T) {
+		// Act
+		videoJob := newVideoJob(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "other-job"}})
+
+		// Assert
+		assert.Equal(t, int64(0), videoJob.VideoId)
+		assert.Equal(t, int64(0), videoJob.UserId)
+		assert.Empty(t, videoJob.Key)
+		assert.Equal(t, 1, videoJob.Attempt)
+		assert.Equal(t, 1, videoJob.Revision)
+	})
+}
+
+// newWatchedClientset returns a fake clientset whose job watches emit the given jobs
+func newTestLogger() *logger.Logger {
+	return &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+func newWatchedClientset(events ...*batchv1.Job) *fake.Clientset {
+	clientset := fake.NewClientset()
+	clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watcher := watch.NewFakeWithChanSize(len(events), false)
+		for _, event := range events {
+			watcher.Modify(event)
+		}
+		return true, watcher, nil
+	})
+	return clientset
+}
+
+func newTestJobInput() *JobInput {
+	return &JobInput{
+		Namespace: "test-namespace",
+		JobName:   "test-job",
+		Image:     "test-image:latest",
+		Cmd:       "test-command",
+	}
+}
+
+func TestCreateJob(t *testing.T) {
+	t.Run("should pass the trace context to the job", func(t *testing.T) {
+		// Arrange
+		clientset := newWatchedClientset(&batchv1.Job{Status: batchv1.JobStatus{Active: 1}})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35},
+			SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa},
+			TraceFlags: trace.FlagsSampled,
+			Remote:     true,
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+		jobInput := newTestJobInput()
+		jobInput.Annotations = map[string]string{AnnotationVideoKey: "test-key"}
+
+		// Act
+		err := k8sAPI.CreateJob(ctx, jobInput)
+
+		// Assert
+		assert.NoError(t, err)
+		job, err := clientset.BatchV1().Jobs("test-namespace").Get(context.Background(), "test-job", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Contains(t, job.Annotations["traceparent"], spanContext.TraceID().String())
+		assert.Equal(t, "test-key", job.Annotations[AnnotationVideoKey])
+		assert.Contains(t, job.Spec.Template.Spec.Containers[0].Env, v1.EnvVar{Name: "TRACEPARENT", Value: job.Annotations["traceparent"]})
+		assert.NotContains(t, jobInput.Annotations, "traceparent")
+	})
+
+	t.Run("should return when the job becomes active", func(t *testing.T) {
+		// Arrange
+		clientset := newWatchedClientset(
+			&batchv1.Job{},
+			&batchv1.Job{Status: batchv1.JobStatus{Active: 1}},
+		)
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		err := k8sAPI.CreateJob(context.Background(), newTestJobInput())
+
+		// Assert
+		assert.NoError(t, err)
+		job, err := clientset.BatchV1().Jobs("test-namespace").Get(context.Background(), "test-job", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, "test-image:latest", job.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("should return error when the job fails", func(t *testing.T) {
+		// Arrange
+		clientset := newWatchedClientset(&batchv1.Job{Status: batchv1.JobStatus{Failed: 1}})
+		_, err := clientset.CoreV1().Pods("test-namespace").Create(context.Background(), &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-job-abcde", Labels: map[string]string{"job-name": "test-job"}},
+			Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+				State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+			}}},
+		}, metav1.CreateOptions{})
+		assert.NoError(t, err)
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		err = k8sAPI.CreateJob(context.Background(), newTestJobInput())
+
+		// Assert
+		assert.EqualError(t, err, "job test-job failed to start")
+		assert.ErrorIs(t, err, domain.ErrJobFailed)
+	})
+
+	t.Run("should return a retryable error when the watch closes before the job starts", func(t *testing.T) {
+		// Arrange
+		clientset := fake.NewClientset()
+		clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+			watcher := watch.NewFake()
+			watcher.Stop()
+			return true, watcher, nil
+		})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		err := k8sAPI.CreateJob(context.Background(), newTestJobInput())
+
+		// Assert
+		assert.EqualError(t, err, "watch of job test-job closed before it started")
+		assert.False(t, domain.IsPermanent(err))
+	})
+
+	t.Run("should return error when the watch sends an error event", func(t *testing.T) {
+		// Arrange
+		clientset := fake.NewClientset()
+		clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+			watcher := watch.NewFakeWithChanSize(1, false)
+			watcher.Error(&metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: "too old resource version",
+				Reason:  metav1.StatusReasonExpired,
+				Code:    http.StatusGone,
+			})
+			return true, watcher, nil
+		})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		err := k8sAPI.CreateJob(context.Background(), newTestJobInput())
+
+		// Assert
+		assert.EqualError(t, err, "error watching job test-job: too old resource version")
+		assert.True(t, apierrors.IsResourceExpired(err))
+		assert.False(t, domain.IsPermanent(err))
+	})
+
+	t.Run("should return error when the job doesn't start before the context is done", func(t *testing.T) {
+		// Arrange
+		clientset := fake.NewClientset()
+		ctx, cancel := context.WithCancel(context.Background())
+		clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+			watcher := watch.NewFake()
+			cancel()
+			watcher.Stop()
+			return true, watcher, nil
+		})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		err := k8sAPI.CreateJob(ctx, newTestJobInput())
+
+		// Assert
+		assert.ErrorIs(t, err, domain.ErrJobStartTimeout)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("should return error when the job doesn't start before the start timeout", func(t *testing.T) {
+		// Arrange
+		clientset := newWatchedClientset(&batchv1.Job{})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+		jobInput := newTestJobInput()
+		jobInput.StartTimeout = 10 * time.Millisecond
+
+		// Act
+		err := k8sAPI.CreateJob(context.Background(), jobInput)
+
+		// Assert
+		assert.ErrorIs(t, err, domain.ErrJobStartTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, domain.CodeJobStartTimeout, domain.CodeOf(err))
+	})
+
+	t.Run("should return error when the watch cannot be opened", func(t *testing.T) {
+		// Arrange
+		clientset := fake.NewClientset()
+		clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+			return true, nil, errors.New("watch forbidden")
+		})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		err := k8sAPI.CreateJob(context.Background(), newTestJobInput())
+
+		// Assert
+		assert.EqualError(t, err, "watch forbidden")
+	})
+
+	t.Run("should watch the created job by name", func(t *testing.T) {
+		// Arrange
+		clientset := newWatchedClientset(&batchv1.Job{Status: batchv1.JobStatus{Active: 1}})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		err := k8sAPI.CreateJob(context.Background(), newTestJobInput())
+
+		// Assert
+		assert.NoError(t, err)
+		var watchAction k8stesting.WatchAction
+		for _, action := range clientset.Actions() {
+			if action.GetVerb() == "watch" {
+				watchAction = action.(k8stesting.WatchAction)
+			}
+		}
+		assert.NotNil(t, watchAction)
+		assert.Equal(t, "metadata.name=test-job", watchAction.GetWatchRestrictions().Fields.String())
+	})
+
+	t.Run("should not watch a suspended job", func(t *testing.T) {
+		// Arrange
+		clientset := newWatchedClientset()
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+		jobInput := newTestJobInput()
+		jobInput.Suspend = true
+
+		// Act
+		err := k8sAPI.CreateJob(context.Background(), jobInput)
+
+		// Assert
+		assert.NoError(t, err)
+		for _, action := range clientset.Actions() {
+			assert.NotEqual(t, "watch", action.GetVerb())
+		}
+	})
+
+	t.Run("should return error when the job already exists", func(t *testing.T) {
+		// Arrange
+		clientset := newWatchedClientset(&batchv1.Job{Status: batchv1.JobStatus{Active: 1}})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+		assert.NoError(t, k8sAPI.CreateJob(context.Background(), newTestJobInput()))
+
+		// Act
+		err := k8sAPI.CreateJob(context.Background(), newTestJobInput())
+
+		// Assert
+		assert.True(t, apierrors.IsAlreadyExists(err))
+		assert.ErrorIs(t, err, domain.ErrJobAlreadyExists)
+	})
+
+	t.Run("should not create the job when parameters are invalid", func(t *testing.T) {
+		// Arrange
+		clientset := newWatchedClientset()
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+		jobInput := newTestJobInput()
+		jobInput.Image = ""
+
+		// Act
+		err := k8sAPI.CreateJob(context.Background(), jobInput)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Empty(t, clientset.Actions())
+	})
+
+	t.Run("should not create the job when the context is canceled", func(t *testing.T) {
+		// Arrange
+		requests := 0
+		clientset := newServedClientset(t, func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusCreated)
+		})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Act
+		err := k8sAPI.CreateJob(ctx, newTestJobInput())
+
+		// Assert
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, requests)
+	})
+}
+
+// newServedClientset returns a clientset talking to a test API server answering with handler
+func newServedClientset(t *testing.T, handler http.HandlerFunc) kubernetes.Interface {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	require.NoError(t, err)
+	return clientset
+}
+
+func TestFindVideoJobsByObject(t *testing.T) {
+	t.Run("should list the jobs of the object by its label", func(t *testing.T) {
+		// Arrange
+		objectJob := newObjectJob("video-42-processor", "videos", "users/7/video.mp4")
+		otherJob := newObjectJob("video-43-processor", "videos", "users/7/other.mp4")
+		clientset := fake.NewClientset(objectJob, otherJob)
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		jobs, err := k8sAPI.FindVideoJobsByObject(context.Background(), "test-namespace", "videos", "users/7/video.mp4")
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "video-42-processor", jobs[0].Name)
+		listAction := clientset.Actions()[0].(k8stesting.ListAction)
+		selector := listAction.GetListRestrictions().Labels
+		assert.True(t, selector.Matches(labels.Set(objectJob.Labels)))
+		assert.False(t, selector.Matches(labels.Set(otherJob.Labels)))
+	})
+
+	t.Run("should skip the jobs of another object with the same label", func(t *testing.T) {
+		// Arrange
+		job := newObjectJob("video-42-processor", "videos", "users/7/video.mp4")
+		job.Annotations[AnnotationVideoKey] = "users/7/other.mp4"
+		k8sAPI := NewK8sAPI(fake.NewClientset(job), newTestLogger())
+
+		// Act
+		jobs, err := k8sAPI.FindVideoJobsByObject(context.Background(), "test-namespace", "videos", "users/7/video.mp4")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+	})
+}
+
+func TestObjectLabel(t *testing.T) {
+	t.Run("should be a valid label value for a long key", func(t *testing.T) {
+		// Act
+		value := ObjectLabel("videos", strings.Repeat("a/b c", 100))
+
+		// Assert
+		assert.Empty(t, validation.IsValidLabelValue(value))
+		assert.Equal(t, value, ObjectLabel("videos", strings.Repeat("a/b c", 100)))
+		assert.NotEqual(t, value, ObjectLabel("other-videos", strings.Repeat("a/b c", 100)))
+	})
+}
+
+func newObjectJob(name, bucket, key string) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-namespace",
+			Labels: map[string]string{
+				LabelManagedBy:   ManagedByJobStarter,
+				LabelVideoObject: ObjectLabel(bucket, key),
+			},
+			Annotations: map[string]string{
+				AnnotationVideoBucket: bucket,
+				AnnotationVideoKey:    key,
+			},
+		},
+	}
+}
+
+func TestPing(t *testing.T) {
+	t.Run("should reach the API server", func(t *testing.T) {
+		// Arrange
+		var path string
+		clientset := newServedClientset(t, func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"major":"1","minor":"31"}`))
+		})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		err := k8sAPI.Ping(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "/version", path)
+	})
+
+	t.Run("should return error when the API server fails", func(t *testing.T) {
+		// Arrange
+		clientset := newServedClientset(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+
+		// Act
+		err := k8sAPI.Ping(context.Background())
+
+		// Assert
+		assert.ErrorContains(t, err, "error reaching the kubernetes API")
+	})
+
+	t.Run("should stop waiting for the API server when the context is done", func(t *testing.T) {
+		// Arrange
+		release := make(chan struct{})
+		defer close(release)
+		clientset := newServedClientset(t, func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		})
+		k8sAPI := NewK8sAPI(clientset, newTestLogger())
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// Act
+		err := k8sAPI.Ping(ctx)
+
+		// Assert
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
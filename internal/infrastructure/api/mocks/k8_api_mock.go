@@ -1,5 +1,10 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: k8_api_interface.go
+//
+// Generated by this command:
+//
+//	mockgen -source=k8_api_interface.go -destination=mocks/k8_api_mock.go
+//
 
 // Package mock_api is a generated GoMock package.
 package mock_api
@@ -8,7 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 
-	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+	api "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -36,6 +41,36 @@ func (m *MockK8sAPIInterface) EXPECT() *MockK8sAPIInterfaceMockRecorder {
 	return m.recorder
 }
 
+// CancelVideoJobs mocks base method.
+func (m *MockK8sAPIInterface) CancelVideoJobs(ctx context.Context, namespace string, videoId int64) ([]api.VideoJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelVideoJobs", ctx, namespace, videoId)
+	ret0, _ := ret[0].([]api.VideoJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelVideoJobs indicates an expected call of CancelVideoJobs.
+func (mr *MockK8sAPIInterfaceMockRecorder) CancelVideoJobs(ctx, namespace, videoId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelVideoJobs", reflect.TypeOf((*MockK8sAPIInterface)(nil).CancelVideoJobs), ctx, namespace, videoId)
+}
+
+// CountActiveJobs mocks base method.
+func (m *MockK8sAPIInterface) CountActiveJobs(ctx context.Context, namespace, labelSelector string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountActiveJobs", ctx, namespace, labelSelector)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountActiveJobs indicates an expected call of CountActiveJobs.
+func (mr *MockK8sAPIInterfaceMockRecorder) CountActiveJobs(ctx, namespace, labelSelector any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountActiveJobs", reflect.TypeOf((*MockK8sAPIInterface)(nil).CountActiveJobs), ctx, namespace, labelSelector)
+}
+
 // CreateJob mocks base method.
 func (m *MockK8sAPIInterface) CreateJob(ctx context.Context, jobInput *api.JobInput) error {
 	m.ctrl.T.Helper()
@@ -50,6 +85,50 @@ func (mr *MockK8sAPIInterfaceMockRecorder) CreateJob(ctx, jobInput any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockK8sAPIInterface)(nil).CreateJob), ctx, jobInput)
 }
 
+// DeleteJob mocks base method.
+func (m *MockK8sAPIInterface) DeleteJob(ctx context.Context, namespace, jobName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteJob", ctx, namespace, jobName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteJob indicates an expected call of DeleteJob.
+func (mr *MockK8sAPIInterfaceMockRecorder) DeleteJob(ctx, namespace, jobName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteJob", reflect.TypeOf((*MockK8sAPIInterface)(nil).DeleteJob), ctx, namespace, jobName)
+}
+
+// FindVideoJobsByObject mocks base method.
+func (m *MockK8sAPIInterface) FindVideoJobsByObject(ctx context.Context, namespace, bucket, key string) ([]api.VideoJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindVideoJobsByObject", ctx, namespace, bucket, key)
+	ret0, _ := ret[0].([]api.VideoJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindVideoJobsByObject indicates an expected call of FindVideoJobsByObject.
+func (mr *MockK8sAPIInterfaceMockRecorder) FindVideoJobsByObject(ctx, namespace, bucket, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindVideoJobsByObject", reflect.TypeOf((*MockK8sAPIInterface)(nil).FindVideoJobsByObject), ctx, namespace, bucket, key)
+}
+
+// GetJobProgress mocks base method.
+func (m *MockK8sAPIInterface) GetJobProgress(ctx context.Context, jobName, namespace string) (*api.JobProgress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJobProgress", ctx, jobName, namespace)
+	ret0, _ := ret[0].(*api.JobProgress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJobProgress indicates an expected call of GetJobProgress.
+func (mr *MockK8sAPIInterfaceMockRecorder) GetJobProgress(ctx, jobName, namespace any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJobProgress", reflect.TypeOf((*MockK8sAPIInterface)(nil).GetJobProgress), ctx, jobName, namespace)
+}
+
 // GetLastJobStatus mocks base method.
 func (m *MockK8sAPIInterface) GetLastJobStatus(ctx context.Context, jobName, namespace string) (string, error) {
 	m.ctrl.T.Helper()
@@ -64,3 +143,32 @@ func (mr *MockK8sAPIInterfaceMockRecorder) GetLastJobStatus(ctx, jobName, namesp
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastJobStatus", reflect.TypeOf((*MockK8sAPIInterface)(nil).GetLastJobStatus), ctx, jobName, namespace)
 }
+
+// ListVideoJobs mocks base method.
+func (m *MockK8sAPIInterface) ListVideoJobs(ctx context.Context, namespace string, videoId int64) ([]api.VideoJob, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVideoJobs", ctx, namespace, videoId)
+	ret0, _ := ret[0].([]api.VideoJob)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVideoJobs indicates an expected call of ListVideoJobs.
+func (mr *MockK8sAPIInterfaceMockRecorder) ListVideoJobs(ctx, namespace, videoId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVideoJobs", reflect.TypeOf((*MockK8sAPIInterface)(nil).ListVideoJobs), ctx, namespace, videoId)
+}
+
+// Ping mocks base method.
+func (m *MockK8sAPIInterface) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockK8sAPIInterfaceMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockK8sAPIInterface)(nil).Ping), ctx)
+}
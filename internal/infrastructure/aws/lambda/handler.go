@@ -0,0 +1,226 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sqs"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+)
+
+// Event sources of the records of an event
+const (
+	eventSourceSQS = "aws:sqs"
+	eventSourceS3  = "aws:s3"
+)
+
+// ErrUnsupportedEvent is returned for an event that is neither an SQS nor an S3 event
+var ErrUnsupportedEvent = errors.New("unsupported event")
+
+// Handler handles the invocations of the starter as an AWS Lambda function, from an SQS
+// event source mapping or directly from S3 event notifications. The records are processed by
+// the same processor as the messages of the polling consumer.
+type Handler struct {
+	processor    sqs.Processor
+	queueURL     func(ctx context.Context, arn string) (string, error)
+	queueHandler func(queueURL string) *sqs.SqsHandler
+	flush        func(ctx context.Context) error
+	logger       *logger.Logger
+}
+
+// NewHandler creates a handler running processor on the records. queueURL returns the URL of
+// the queue of an SQS event from its ARN, such as QueueURLResolver.QueueURL, and queueHandler
+// returns the handler of the queue, which decides what becomes of failed records.
+func NewHandler(processor sqs.Processor, queueURL func(ctx context.Context, arn string) (string, error), queueHandler func(queueURL string) *sqs.SqsHandler, logger *logger.Logger) *Handler {
+	return &Handler{
+		processor:    processor,
+		queueURL:     queueURL,
+		queueHandler: queueHandler,
+		logger:       logger,
+	}
+}
+
+// WithFlush calls flush at the end of each invocation, such as tracing.Flush, as the execution
+// environment is frozen between invocations and may never be thawed
+func (h *Handler) WithFlush(flush func(ctx context.Context) error) *Handler {
+	h.flush = flush
+	return h
+}
+
+// Invoke handles an SQS event, answering with the records that failed, or an S3 event
+func (h *Handler) Invoke(ctx context.Context, payload json.RawMessage) (any, error) {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		ctx = logger.WithFields(ctx, "requestId", lc.AwsRequestID)
+	}
+
+	response, err := h.invoke(ctx, payload)
+	if h.flush != nil {
+		if flushErr := h.flush(ctx); flushErr != nil {
+			h.logger.ErrorContext(ctx, "Failed to flush spans", "error", flushErr.Error())
+		}
+	}
+	return response, err
+}
+
+func (h *Handler) invoke(ctx context.Context, payload json.RawMessage) (any, error) {
+	var probe struct {
+		Records []struct {
+			EventSource string          `json:"eventSource"`
+			S3          json.RawMessage `json:"s3"`
+		} `json:"Records"`
+	}
+	if err := json.Unmarshal(payload, &probe); err != nil || len(probe.Records) == 0 {
+		return nil, ErrUnsupportedEvent
+	}
+
+	record := probe.Records[0]
+	switch {
+	case record.EventSource == eventSourceSQS:
+		var event events.SQSEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal SQS event: %w", err)
+		}
+		return h.HandleSQSEvent(ctx, event), nil
+	case record.EventSource == eventSourceS3 || record.S3 != nil:
+		return nil, h.HandleS3Event(ctx, payload)
+	default:
+		return nil, ErrUnsupportedEvent
+	}
+}
+
+// HandleSQSEvent processes the records of an SQS event with the handler of their queue. The
+// records that failed are returned as batch item failures, so the event source mapping only
+// deletes the others, which requires ReportBatchItemFailures on the mapping.
+func (h *Handler) HandleSQSEvent(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
+	// An event comes from a single queue, grouping keeps it right for any event
+	var arns []string
+	messages := make(map[string][]types.Message)
+	for _, record := range event.Records {
+		if _, ok := messages[record.EventSourceARN]; !ok {
+			arns = append(arns, record.EventSourceARN)
+		}
+		messages[record.EventSourceARN] = append(messages[record.EventSourceARN], toMessage(record))
+	}
+
+	response := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+	for _, arn := range arns {
+		failed, err := h.processQueue(ctx, arn, messages[arn])
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Failed to resolve the queue of the records", "queueArn", arn, "error", err.Error())
+		}
+		for _, messageId := range failed {
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: messageId})
+		}
+	}
+	if len(response.BatchItemFailures) > 0 {
+		h.logger.WarnContext(ctx, "Reporting failed records", "failed", len(response.BatchItemFailures), "records", len(event.Records))
+	}
+	return response
+}
+
+// processQueue processes the messages of the queue of arn and returns the ids of the failed
+// ones. All of them fail when the URL of the queue can't be resolved.
+func (h *Handler) processQueue(ctx context.Context, arn string, messages []types.Message) ([]string, error) {
+	queueURL, err := h.queueURL(ctx, arn)
+	if err != nil {
+		failed := make([]string, 0, len(messages))
+		for _, message := range messages {
+			failed = append(failed, aws.ToString(message.MessageId))
+		}
+		return failed, err
+	}
+	return h.queueHandler(queueURL).ProcessMessages(ctx, messages, h.processor), nil
+}
+
+// HandleS3Event processes an S3 event notification delivered to the function. Errors are
+// returned so Lambda retries the invocation, except the permanent ones, which can't succeed.
+func (h *Handler) HandleS3Event(ctx context.Context, payload json.RawMessage) error {
+	messageId := "s3-event"
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		messageId = lc.AwsRequestID
+	}
+
+	err := h.processor(ctx, types.Message{
+		MessageId: aws.String(messageId),
+		Body:      aws.String(string(payload)),
+	})
+	if err != nil && domain.IsPermanent(err) {
+		h.logger.ErrorContext(ctx, "Failed to process S3 event, discarding it",
+			"error", err.Error(),
+			"code", domain.CodeOf(err),
+		)
+		return nil
+	}
+	return err
+}
+
+// QueueURLClient looks up the URL of a queue from its name and owner
+type QueueURLClient interface {
+	GetQueueURL(ctx context.Context, name string, ownerAccountId string) (string, error)
+}
+
+// QueueURLResolver resolves the URL of a queue from its ARN with SQS, since the form of the URL
+// depends on the partition and the endpoint. The URLs are kept for the next invocations.
+type QueueURLResolver struct {
+	client QueueURLClient
+	mu     sync.Mutex
+	urls   map[string]string
+}
+
+func NewQueueURLResolver(client QueueURLClient) *QueueURLResolver {
+	return &QueueURLResolver{
+		client: client,
+		urls:   make(map[string]string),
+	}
+}
+
+// QueueURL returns the URL of the queue of arn, such as "arn:aws:sqs:us-east-1:000000000000:uploads"
+func (r *QueueURLResolver) QueueURL(ctx context.Context, arn string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if url, ok := r.urls[arn]; ok {
+		return url, nil
+	}
+
+	parts := strings.Split(arn, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sqs" {
+		return "", fmt.Errorf("%q is not an SQS queue ARN", arn)
+	}
+	url, err := r.client.GetQueueURL(ctx, parts[5], parts[4])
+	if err != nil {
+		return "", err
+	}
+	r.urls[arn] = url
+	return url, nil
+}
+
+// toMessage converts an SQS record to the message the SqsHandler processes
+func toMessage(record events.SQSMessage) types.Message {
+	attributes := make(map[string]types.MessageAttributeValue, len(record.MessageAttributes))
+	for name, attribute := range record.MessageAttributes {
+		attributes[name] = types.MessageAttributeValue{
+			DataType:    aws.String(attribute.DataType),
+			StringValue: attribute.StringValue,
+			BinaryValue: attribute.BinaryValue,
+		}
+	}
+
+	return types.Message{
+		MessageId:         aws.String(record.MessageId),
+		ReceiptHandle:     aws.String(record.ReceiptHandle),
+		Body:              aws.String(record.Body),
+		Attributes:        record.Attributes,
+		MessageAttributes: attributes,
+	}
+}
@@ -0,0 +1,320 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sqs"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+)
+
+const (
+	testQueueARN = "arn:aws:sqs:us-east-1:000000000000:uploads"
+	testQueueURL = "https://sqs.us-east-1.amazonaws.com/000000000000/uploads"
+)
+
+// fakeMessageClient records the visibility changes of the queue handler. The event source
+// mapping receives and deletes the messages, so nothing else is expected.
+type fakeMessageClient struct {
+	visibility map[string]int
+}
+
+func (f *fakeMessageClient) ReceiveMessages(ctx context.Context, queueURL string, maxMessages int, waitTimeSeconds int) ([]types.Message, error) {
+	return nil, errors.New("unexpected receive")
+}
+
+func (f *fakeMessageClient) DeleteMessage(ctx context.Context, queueURL string, receiptHandle string) error {
+	return errors.New("unexpected delete")
+}
+
+func (f *fakeMessageClient) DeleteMessageBatch(ctx context.Context, queueURL string, receiptHandles []string) ([]sqs.BatchEntryError, error) {
+	return nil, errors.New("unexpected delete")
+}
+
+func (f *fakeMessageClient) ChangeMessageVisibility(ctx context.Context, queueURL string, receiptHandle string, visibilityTimeout int) error {
+	f.visibility[receiptHandle] = visibilityTimeout
+	return nil
+}
+
+func (f *fakeMessageClient) ChangeMessageVisibilityBatch(ctx context.Context, queueURL string, receiptHandles []string, visibilityTimeout int) ([]sqs.BatchEntryError, error) {
+	for _, receiptHandle := range receiptHandles {
+		f.visibility[receiptHandle] = visibilityTimeout
+	}
+	return nil, nil
+}
+
+func (f *fakeMessageClient) SendMessage(ctx context.Context, queueURL string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	return nil, errors.New("unexpected send")
+}
+
+func (f *fakeMessageClient) SendGroupMessage(ctx context.Context, queueURL string, groupId string, deduplicationId string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	return nil, errors.New("unexpected send")
+}
+
+// fakeQueueURLClient serves the URLs of its queues by owner and name, and counts the lookups
+type fakeQueueURLClient struct {
+	urls    map[string]string
+	lookups int
+}
+
+func (f *fakeQueueURLClient) GetQueueURL(ctx context.Context, name string, ownerAccountId string) (string, error) {
+	f.lookups++
+	url, ok := f.urls[ownerAccountId+"/"+name]
+	if !ok {
+		return "", errors.New("queue does not exist")
+	}
+	return url, nil
+}
+
+func newFakeQueueURLClient() *fakeQueueURLClient {
+	return &fakeQueueURLClient{urls: map[string]string{"000000000000/uploads": testQueueURL}}
+}
+
+func newTestHandler(processor sqs.Processor) (*Handler, *fakeMessageClient) {
+	client := &fakeMessageClient{visibility: make(map[string]int)}
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	queueHandler := func(queueURL string) *sqs.SqsHandler {
+		return sqs.NewSqsHandler(client, queueURL, 0, 0, log, nil)
+	}
+	return NewHandler(processor, NewQueueURLResolver(newFakeQueueURLClient()).QueueURL, queueHandler, log), client
+}
+
+func sqsRecord(id string, body string) events.SQSMessage {
+	return events.SQSMessage{
+		MessageId:      id,
+		ReceiptHandle:  "receipt-" + id,
+		Body:           body,
+		EventSource:    "aws:sqs",
+		EventSourceARN: testQueueARN,
+		Attributes:     map[string]string{"ApproximateReceiveCount": "1"},
+		MessageAttributes: map[string]events.SQSMessageAttribute{
+			"traceparent": {DataType: "String", StringValue: aws.String("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+		},
+	}
+}
+
+func TestHandler_Invoke(t *testing.T) {
+	t.Run("should report the failed records of an SQS event", func(t *testing.T) {
+		// Arrange
+		var bodies []string
+		handler, _ := newTestHandler(func(ctx context.Context, message types.Message) error {
+			bodies = append(bodies, aws.ToString(message.Body))
+			switch aws.ToString(message.Body) {
+			case "retryable":
+				return domain.NewError(domain.CodeInternal, "error creating job", errors.New("connection refused"))
+			case "permanent":
+				return domain.NewError(domain.CodeInvalidS3Event, "failed to unmarshal S3 event", nil)
+			}
+			return nil
+		})
+		payload, err := json.Marshal(events.SQSEvent{Records: []events.SQSMessage{
+			sqsRecord("1", "processed"),
+			sqsRecord("2", "retryable"),
+			sqsRecord("3", "permanent"),
+		}})
+		require.NoError(t, err)
+
+		// Act
+		response, err := handler.Invoke(context.Background(), payload)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"processed", "retryable", "permanent"}, bodies)
+		assert.Equal(t, events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{{ItemIdentifier: "2"}}}, response)
+	})
+
+	t.Run("should report and delay a throttled record", func(t *testing.T) {
+		// Arrange
+		handler, client := newTestHandler(func(ctx context.Context, message types.Message) error {
+			return domain.NewThrottledError("too many active jobs", 30*time.Second, domain.ErrTooManyActiveJobs)
+		})
+		payload, err := json.Marshal(events.SQSEvent{Records: []events.SQSMessage{sqsRecord("1", "{}")}})
+		require.NoError(t, err)
+
+		// Act
+		response, err := handler.Invoke(context.Background(), payload)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{{ItemIdentifier: "1"}}}, response)
+		assert.Equal(t, map[string]int{"receipt-1": 30}, client.visibility)
+	})
+
+	t.Run("should pass the attributes of the records to the processor", func(t *testing.T) {
+		// Arrange
+		var received types.Message
+		handler, _ := newTestHandler(func(ctx context.Context, message types.Message) error {
+			received = message
+			return nil
+		})
+		payload, err := json.Marshal(events.SQSEvent{Records: []events.SQSMessage{sqsRecord("1", "{}")}})
+		require.NoError(t, err)
+
+		// Act
+		_, err = handler.Invoke(context.Background(), payload)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "1", aws.ToString(received.MessageId))
+		assert.Equal(t, "receipt-1", aws.ToString(received.ReceiptHandle))
+		assert.Equal(t, "1", received.Attributes["ApproximateReceiveCount"])
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", aws.ToString(received.MessageAttributes["traceparent"].StringValue))
+	})
+
+	t.Run("should report the records of a queue whose URL can't be resolved", func(t *testing.T) {
+		// Arrange
+		handler, _ := newTestHandler(func(ctx context.Context, message types.Message) error {
+			t.Fatal("unexpected processing")
+			return nil
+		})
+		record := sqsRecord("1", "{}")
+		record.EventSourceARN = "arn:aws:sqs:us-east-1:000000000000:deleted"
+		payload, err := json.Marshal(events.SQSEvent{Records: []events.SQSMessage{record}})
+		require.NoError(t, err)
+
+		// Act
+		response, err := handler.Invoke(context.Background(), payload)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{{ItemIdentifier: "1"}}}, response)
+	})
+
+	t.Run("should process a direct S3 event", func(t *testing.T) {
+		// Arrange
+		payload, err := os.ReadFile("../../../../test/data/s3_event_payload.json")
+		require.NoError(t, err)
+		var body string
+		handler, _ := newTestHandler(func(ctx context.Context, message types.Message) error {
+			body = aws.ToString(message.Body)
+			return nil
+		})
+
+		// Act
+		response, err := handler.Invoke(context.Background(), payload)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Nil(t, response)
+		assert.JSONEq(t, string(payload), body)
+	})
+
+	t.Run("should return the retryable failures of an S3 event", func(t *testing.T) {
+		// Arrange
+		retryable := domain.NewError(domain.CodeInternal, "error creating job", errors.New("connection refused"))
+		handler, _ := newTestHandler(func(ctx context.Context, message types.Message) error {
+			return retryable
+		})
+
+		// Act
+		_, err := handler.Invoke(context.Background(), json.RawMessage(`{"Records":[{"eventSource":"aws:s3","s3":{}}]}`))
+
+		// Assert
+		assert.ErrorIs(t, err, retryable)
+	})
+
+	t.Run("should discard the permanent failures of an S3 event", func(t *testing.T) {
+		// Arrange
+		handler, _ := newTestHandler(func(ctx context.Context, message types.Message) error {
+			return domain.NewError(domain.CodeMissingMetadata, "missing video-id metadata", nil)
+		})
+
+		// Act
+		_, err := handler.Invoke(context.Background(), json.RawMessage(`{"Records":[{"eventSource":"aws:s3","s3":{}}]}`))
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should flush at the end of each invocation", func(t *testing.T) {
+		// Arrange
+		var flushes int
+		handler, _ := newTestHandler(func(ctx context.Context, message types.Message) error {
+			assert.Zero(t, flushes)
+			return nil
+		})
+		handler.WithFlush(func(ctx context.Context) error {
+			flushes++
+			return errors.New("collector unreachable")
+		})
+
+		// Act
+		_, err := handler.Invoke(context.Background(), json.RawMessage(`{"Records":[{"eventSource":"aws:s3","s3":{}}]}`))
+		_, unsupportedErr := handler.Invoke(context.Background(), json.RawMessage(`{"detail-type":"Scheduled Event"}`))
+
+		// Assert
+		assert.NoError(t, err)
+		assert.ErrorIs(t, unsupportedErr, ErrUnsupportedEvent)
+		assert.Equal(t, 2, flushes)
+	})
+
+	t.Run("should refuse other events", func(t *testing.T) {
+		// Arrange
+		handler, _ := newTestHandler(func(ctx context.Context, message types.Message) error {
+			t.Fatal("unexpected processing")
+			return nil
+		})
+
+		// Act
+		_, err := handler.Invoke(context.Background(), json.RawMessage(`{"detail-type":"Scheduled Event"}`))
+
+		// Assert
+		assert.ErrorIs(t, err, ErrUnsupportedEvent)
+	})
+}
+
+func TestQueueURLResolver_QueueURL(t *testing.T) {
+	t.Run("should look up the queue of an ARN once", func(t *testing.T) {
+		// Arrange
+		client := newFakeQueueURLClient()
+		resolver := NewQueueURLResolver(client)
+
+		// Act
+		first, err := resolver.QueueURL(context.Background(), testQueueARN)
+		require.NoError(t, err)
+		second, err := resolver.QueueURL(context.Background(), testQueueARN)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, testQueueURL, first)
+		assert.Equal(t, testQueueURL, second)
+		assert.Equal(t, 1, client.lookups)
+	})
+
+	t.Run("should return error when the queue can't be looked up", func(t *testing.T) {
+		// Arrange
+		resolver := NewQueueURLResolver(newFakeQueueURLClient())
+
+		// Act
+		_, err := resolver.QueueURL(context.Background(), "arn:aws:sqs:us-east-1:000000000000:deleted")
+
+		// Assert
+		assert.ErrorContains(t, err, "queue does not exist")
+	})
+
+	t.Run("should refuse an ARN that isn't of an SQS queue", func(t *testing.T) {
+		// Arrange
+		client := newFakeQueueURLClient()
+		resolver := NewQueueURLResolver(client)
+
+		// Act
+		_, err := resolver.QueueURL(context.Background(), "arn:aws:sns:us-east-1:000000000000:topic")
+
+		// Assert
+		assert.ErrorContains(t, err, "is not an SQS queue ARN")
+		assert.Zero(t, client.lookups)
+	})
+}
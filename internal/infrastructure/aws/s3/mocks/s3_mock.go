@@ -0,0 +1,72 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/infrastructure/aws/s3/s3_interface.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/infrastructure/aws/s3/s3_interface.go -destination=internal/infrastructure/aws/s3/mocks/s3_mock.go
+//
+
+// Package mock_s3 is a generated GoMock package.
+package mock_s3
+
+import (
+	context "context"
+	reflect "reflect"
+
+	s3 "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/s3"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockS3Interface is a mock of S3Interface interface.
+type MockS3Interface struct {
+	ctrl     *gomock.Controller
+	recorder *MockS3InterfaceMockRecorder
+	isgomock struct{}
+}
+
+// MockS3InterfaceMockRecorder is the mock recorder for MockS3Interface.
+type MockS3InterfaceMockRecorder struct {
+	mock *MockS3Interface
+}
+
+// NewMockS3Interface creates a new mock instance.
+func NewMockS3Interface(ctrl *gomock.Controller) *MockS3Interface {
+	mock := &MockS3Interface{ctrl: ctrl}
+	mock.recorder = &MockS3InterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockS3Interface) EXPECT() *MockS3InterfaceMockRecorder {
+	return m.recorder
+}
+
+// GetObjectInfo mocks base method.
+func (m *MockS3Interface) GetObjectInfo(ctx context.Context, bucket, key, versionId string) (*s3.ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetObjectInfo", ctx, bucket, key, versionId)
+	ret0, _ := ret[0].(*s3.ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetObjectInfo indicates an expected call of GetObjectInfo.
+func (mr *MockS3InterfaceMockRecorder) GetObjectInfo(ctx, bucket, key, versionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectInfo", reflect.TypeOf((*MockS3Interface)(nil).GetObjectInfo), ctx, bucket, key, versionId)
+}
+
+// GetObjectMetadata mocks base method.
+func (m *MockS3Interface) GetObjectMetadata(ctx context.Context, bucket, key string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetObjectMetadata", ctx, bucket, key)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetObjectMetadata indicates an expected call of GetObjectMetadata.
+func (mr *MockS3InterfaceMockRecorder) GetObjectMetadata(ctx, bucket, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObjectMetadata", reflect.TypeOf((*MockS3Interface)(nil).GetObjectMetadata), ctx, bucket, key)
+}
@@ -4,11 +4,20 @@ import (
 	"context"
 
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/tracing"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ObjectInfo holds the user metadata and the size of an S3 object
+type ObjectInfo struct {
+	Metadata map[string]string
+	Size     int64
+}
+
 type S3 struct {
 	Client *s3.Client
 }
@@ -20,13 +29,39 @@ func NewS3(cfg *config.Config) *S3 {
 }
 
 func (s *S3) GetObjectMetadata(ctx context.Context, bucket string, key string) (map[string]string, error) {
-	object, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{
+	info, err := s.GetObjectInfo(ctx, bucket, key, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return info.Metadata, nil
+}
+
+// GetObjectInfo returns the user metadata and the content length of an object, of the given
+// version in a versioned bucket or of its latest version when versionId is empty
+func (s *S3) GetObjectInfo(ctx context.Context, bucket string, key string, versionId string) (*ObjectInfo, error) {
+	ctx, span := tracing.Start(ctx, "s3.HeadObject",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.AWSS3Bucket(bucket), semconv.AWSS3Key(key)),
+	)
+	defer span.End()
+
+	input := &s3.HeadObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
-	})
+	}
+	if versionId != "" {
+		input.VersionId = aws.String(versionId)
+	}
+
+	object, err := s.Client.HeadObject(ctx, input)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
-	return object.Metadata, nil
+	return &ObjectInfo{
+		Metadata: object.Metadata,
+		Size:     aws.ToInt64(object.ContentLength),
+	}, nil
 }
@@ -0,0 +1,9 @@
+package s3
+
+import "context"
+
+// S3Interface defines the contract for S3 operations
+type S3Interface interface {
+	GetObjectMetadata(ctx context.Context, bucket string, key string) (map[string]string, error)
+	GetObjectInfo(ctx context.Context, bucket string, key string, versionId string) (*ObjectInfo, error)
+}
@@ -2,12 +2,16 @@ package sns
 
 import (
 	"context"
-	"log"
+	"fmt"
 
 	myConfig "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/tracing"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SNS struct {
@@ -23,11 +27,22 @@ func NewSNS(cfg *myConfig.Config) *SNS {
 	}
 }
 
+// Publish sends a message to the topic, with the trace context of ctx in its attributes
+// so that the subscribers can continue the trace. The error is left to the caller to log.
 func (s *SNS) Publish(ctx context.Context, message string) error {
-	publishInput := sns.PublishInput{TopicArn: aws.String(s.TopicArn), Message: aws.String(message)}
-	_, err := s.Client.Publish(ctx, &publishInput)
-	if err != nil {
-		log.Printf("Couldn't publish message to topic %v. Here's why: %v", s.TopicArn, err)
+	ctx, span := tracing.Start(ctx, "sns.Publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystemAWSSNS, semconv.MessagingOperationTypeSend, semconv.AWSSNSTopicARN(s.TopicArn)),
+	)
+	defer span.End()
+
+	attributes := make(map[string]types.MessageAttributeValue)
+	tracing.InjectSNS(ctx, attributes)
+
+	publishInput := sns.PublishInput{TopicArn: aws.String(s.TopicArn), Message: aws.String(message), MessageAttributes: attributes}
+	if _, err := s.Client.Publish(ctx, &publishInput); err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("error publishing message to topic %s: %w", s.TopicArn, err)
 	}
-	return err
+	return nil
 }
@@ -2,7 +2,10 @@ package sqs
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
@@ -11,6 +14,26 @@ import (
 	awsclient "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws"
 )
 
+// maxBatchEntries is the largest number of entries SQS accepts in a batch request
+const maxBatchEntries = 10
+
+// BatchEntryError is the failure of one entry of a batch request
+type BatchEntryError struct {
+	ReceiptHandle string
+	Code          string
+	Message       string
+	// SenderFault tells if the entry was refused, such as an expired receipt handle, rather
+	// than failed on the side of SQS
+	SenderFault bool
+}
+
+func (e BatchEntryError) Error() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
 type SqsClient struct {
 	client *sqs.Client
 }
@@ -24,33 +47,80 @@ func NewSqsClient(awsClientFactory *awsclient.ClientFactory) (*SqsClient, error)
 	}, nil
 }
 
-// SendMessage sends a message to an SQS queue
-func (s *SqsClient) SendMessage(ctx context.Context, queueURL string, messageBody string) (*types.Message, error) {
-	input := &sqs.SendMessageInput{
-		QueueUrl:    aws.String(queueURL),
-		MessageBody: aws.String(messageBody),
-	}
+// IsFifo tells if the queue at queueURL is a FIFO queue, whose name ends with ".fifo"
+func IsFifo(queueURL string) bool {
+	return strings.HasSuffix(queueURL, ".fifo")
+}
+
+// SendMessage sends a message to an SQS queue, with the given string attributes
+func (s *SqsClient) SendMessage(ctx context.Context, queueURL string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	return s.send(ctx, &sqs.SendMessageInput{
+		QueueUrl:          aws.String(queueURL),
+		MessageBody:       aws.String(messageBody),
+		MessageAttributes: messageAttributes(attributes),
+	})
+}
 
+// SendGroupMessage sends a message to a FIFO queue, delivered in order with the other messages
+// of its group. Messages sent again with the same deduplicationId within five minutes are
+// dropped by SQS.
+func (s *SqsClient) SendGroupMessage(ctx context.Context, queueURL string, groupId string, deduplicationId string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	return s.send(ctx, &sqs.SendMessageInput{
+		QueueUrl:               aws.String(queueURL),
+		MessageBody:            aws.String(messageBody),
+		MessageAttributes:      messageAttributes(attributes),
+		MessageGroupId:         aws.String(groupId),
+		MessageDeduplicationId: aws.String(deduplicationId),
+	})
+}
+
+func (s *SqsClient) send(ctx context.Context, input *sqs.SendMessageInput) (*types.Message, error) {
 	result, err := s.client.SendMessage(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send message to queue %s: %w", queueURL, err)
+		return nil, fmt.Errorf("failed to send message to queue %s: %w", aws.ToString(input.QueueUrl), err)
 	}
 
 	// Create a Message struct to return
 	message := &types.Message{
 		MessageId: result.MessageId,
-		Body:      aws.String(messageBody),
+		Body:      input.MessageBody,
 	}
 
 	return message, nil
 }
 
+// messageAttributes converts string attributes to SQS message attributes
+func messageAttributes(attributes map[string]string) map[string]types.MessageAttributeValue {
+	if len(attributes) == 0 {
+		return nil
+	}
+	converted := make(map[string]types.MessageAttributeValue, len(attributes))
+	for name, value := range attributes {
+		converted[name] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+	return converted
+}
+
 // ReceiveMessages receives messages from an SQS queue
 func (s *SqsClient) ReceiveMessages(ctx context.Context, queueURL string, maxMessages int, waitTimeSeconds int) ([]types.Message, error) {
 	input := &sqs.ReceiveMessageInput{
 		QueueUrl:            aws.String(queueURL),
 		MaxNumberOfMessages: int32(maxMessages),
 		WaitTimeSeconds:     int32(waitTimeSeconds),
+		// The trace context is carried in the message attributes
+		MessageAttributeNames: []string{"All"},
+		// The receive count decides when a failing message is given up on, the sent timestamp
+		// measures how long messages wait in the queue, and the group of the messages of a FIFO
+		// queue decides which ones are processed in order
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+			types.MessageSystemAttributeNameSentTimestamp,
+			types.MessageSystemAttributeNameMessageGroupId,
+			types.MessageSystemAttributeNameMessageDeduplicationId,
+		},
 	}
 
 	result, err := s.client.ReceiveMessage(ctx, input)
@@ -76,6 +146,146 @@ func (s *SqsClient) DeleteMessage(ctx context.Context, queueURL string, receiptH
 	return nil
 }
 
+// DeleteMessageBatch deletes messages from the SQS queue, in as many requests as needed. It
+// returns the entries that could not be deleted, and the errors of the requests that failed,
+// whose entries are returned as well.
+func (s *SqsClient) DeleteMessageBatch(ctx context.Context, queueURL string, receiptHandles []string) ([]BatchEntryError, error) {
+	var failed []BatchEntryError
+	var errs []error
+	for start := 0; start < len(receiptHandles); start += maxBatchEntries {
+		chunk := receiptHandles[start:min(start+maxBatchEntries, len(receiptHandles))]
+		entries := make([]types.DeleteMessageBatchRequestEntry, len(chunk))
+		for i, receiptHandle := range chunk {
+			entries[i] = types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: aws.String(receiptHandle),
+			}
+		}
+
+		result, err := s.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to delete messages from queue %s: %w", queueURL, err)
+			errs = append(errs, err)
+			failed = append(failed, failAll(chunk, err)...)
+			continue
+		}
+		failed = append(failed, batchEntryErrors(chunk, result.Failed)...)
+	}
+
+	return failed, errors.Join(errs...)
+}
+
+// ChangeMessageVisibilityBatch changes how long received messages stay invisible in the queue,
+// in as many requests as needed. It returns the entries that could not be changed, and the
+// errors of the requests that failed, whose entries are returned as well.
+func (s *SqsClient) ChangeMessageVisibilityBatch(ctx context.Context, queueURL string, receiptHandles []string, visibilityTimeout int) ([]BatchEntryError, error) {
+	var failed []BatchEntryError
+	var errs []error
+	for start := 0; start < len(receiptHandles); start += maxBatchEntries {
+		chunk := receiptHandles[start:min(start+maxBatchEntries, len(receiptHandles))]
+		entries := make([]types.ChangeMessageVisibilityBatchRequestEntry, len(chunk))
+		for i, receiptHandle := range chunk {
+			entries[i] = types.ChangeMessageVisibilityBatchRequestEntry{
+				Id:                aws.String(strconv.Itoa(i)),
+				ReceiptHandle:     aws.String(receiptHandle),
+				VisibilityTimeout: int32(visibilityTimeout),
+			}
+		}
+
+		result, err := s.client.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to change message visibility in queue %s: %w", queueURL, err)
+			errs = append(errs, err)
+			failed = append(failed, failAll(chunk, err)...)
+			continue
+		}
+		failed = append(failed, batchEntryErrors(chunk, result.Failed)...)
+	}
+
+	return failed, errors.Join(errs...)
+}
+
+// batchEntryErrors maps the failed entries of a batch result back to their receipt handle,
+// the id of an entry being its index in the chunk
+func batchEntryErrors(chunk []string, results []types.BatchResultErrorEntry) []BatchEntryError {
+	failed := make([]BatchEntryError, 0, len(results))
+	for _, result := range results {
+		index, err := strconv.Atoi(aws.ToString(result.Id))
+		if err != nil || index < 0 || index >= len(chunk) {
+			continue
+		}
+		failed = append(failed, BatchEntryError{
+			ReceiptHandle: chunk[index],
+			Code:          aws.ToString(result.Code),
+			Message:       aws.ToString(result.Message),
+			SenderFault:   result.SenderFault,
+		})
+	}
+	return failed
+}
+
+// failAll reports every entry of a chunk whose request failed
+func failAll(chunk []string, err error) []BatchEntryError {
+	failed := make([]BatchEntryError, len(chunk))
+	for i, receiptHandle := range chunk {
+		failed[i] = BatchEntryError{ReceiptHandle: receiptHandle, Message: err.Error()}
+	}
+	return failed
+}
+
+// ChangeMessageVisibility changes how long a received message stays invisible in the queue
+func (s *SqsClient) ChangeMessageVisibility(ctx context.Context, queueURL string, receiptHandle string, visibilityTimeout int) error {
+	input := &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queueURL),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: int32(visibilityTimeout),
+	}
+
+	_, err := s.client.ChangeMessageVisibility(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to change message visibility in queue %s: %w", queueURL, err)
+	}
+
+	return nil
+}
+
+// GetQueueURL returns the URL of the queue named name, owned by ownerAccountId or by the account
+// of the credentials when ownerAccountId is empty
+func (s *SqsClient) GetQueueURL(ctx context.Context, name string, ownerAccountId string) (string, error) {
+	input := &sqs.GetQueueUrlInput{QueueName: aws.String(name)}
+	if ownerAccountId != "" {
+		input.QueueOwnerAWSAccountId = aws.String(ownerAccountId)
+	}
+
+	result, err := s.client.GetQueueUrl(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the URL of queue %s: %w", name, err)
+	}
+
+	return aws.ToString(result.QueueUrl), nil
+}
+
+// Ping checks that the queue is reachable with the current credentials
+func (s *SqsClient) Ping(ctx context.Context, queueURL string) error {
+	input := &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+	}
+
+	_, err := s.client.GetQueueAttributes(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to reach queue %s: %w", queueURL, err)
+	}
+
+	return nil
+}
+
 // GetClient returns the underlying SQS client
 func (s *SqsClient) GetClient() *sqs.Client {
 	return s.client
@@ -3,72 +3,419 @@ package sqs
 import (
 	"context"
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/metrics"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/tracing"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxVisibilityTimeoutSeconds is the largest visibility timeout accepted by SQS (12 hours)
+const maxVisibilityTimeoutSeconds = 43200
+
+// Attributes added to the messages forwarded to the dead-letter queue
+const (
+	AttributeFailureReason = "failure-reason"
+	AttributeFailureCode   = "failure-code"
+	AttributeSourceQueue   = "source-queue"
+	AttributeReceiveCount  = "receive-count"
+	AttributeJobName       = "job-name"
+	// AttributeGroupId keeps the group of a message of a FIFO queue, so it is redriven to it
+	AttributeGroupId = "group-id"
 )
 
 type SqsHandler struct {
-	sqsClient       *SqsClient
+	sqsClient       MessageClient
 	queueURL        string
 	maxMessages     int
 	waitTimeSeconds int
 	logger          *logger.Logger
+	metrics         *metrics.Metrics
+	// fifo processes the message groups of a receive in parallel, and the messages of a group in order
+	fifo bool
+
+	// deadLetterQueueURL receives the messages failing permanently, which are dropped when empty
+	deadLetterQueueURL string
+	// maxReceiveCount is how many times a message failing for a transient reason is received
+	// before being forwarded to the dead-letter queue. Zero leaves it to the redrive policy.
+	maxReceiveCount int
 }
 
-// NewSqsHandler creates a new SQS handler with the provided SQS client
-func NewSqsHandler(sqsClient *SqsClient, queueURL string, maxMessages int, waitTimeSeconds int, logger *logger.Logger) *SqsHandler {
+// NewSqsHandler creates a new SQS handler with the provided SQS client. Metrics may be nil.
+func NewSqsHandler(sqsClient MessageClient, queueURL string, maxMessages int, waitTimeSeconds int, logger *logger.Logger, metrics *metrics.Metrics) *SqsHandler {
 	return &SqsHandler{
 		sqsClient:       sqsClient,
 		logger:          logger,
+		metrics:         metrics,
 		queueURL:        queueURL,
 		maxMessages:     maxMessages,
 		waitTimeSeconds: waitTimeSeconds,
+		fifo:            IsFifo(queueURL),
 	}
 }
 
-// ReceiveMessages processes messages and optionally deletes them after processing
-func (h *SqsHandler) ReceiveMessages(ctx context.Context, processor func(types.Message) (bool, error)) error {
-	messages, err := h.sqsClient.ReceiveMessages(ctx, h.queueURL, h.maxMessages, h.waitTimeSeconds)
+// Processor handles a received message. The context carries the trace of the message.
+// The error decides what becomes of the message:
+//   - nil deletes it
+//   - a domain.ThrottledError keeps it invisible until it can be retried
+//   - a domain.Error of a permanent code deletes it, after forwarding it to the dead-letter
+//     queue if any
+//   - any other error leaves it in the queue to be received again, until it was received as
+//     many times as allowed by WithDeadLetterQueue
+//
+// The processor is called concurrently for the message groups of a FIFO queue.
+type Processor func(ctx context.Context, message types.Message) error
+
+// WithDeadLetterQueue forwards to the queue at url the messages failing permanently, and the
+// ones received maxReceiveCount times when it is not zero, before deleting them
+func (h *SqsHandler) WithDeadLetterQueue(url string, maxReceiveCount int) *SqsHandler {
+	h.deadLetterQueueURL = url
+	h.maxReceiveCount = maxReceiveCount
+	return h
+}
+
+// ReceiveMessages processes messages, then deletes and delays them in batches
+func (h *SqsHandler) ReceiveMessages(ctx context.Context, processor Processor) error {
+	_, err := h.poll(ctx, processor, h.waitTimeSeconds)
+	return err
+}
+
+// poll receives one batch, waiting up to waitTimeSeconds for messages, and processes it. It
+// returns how many messages were received.
+func (h *SqsHandler) poll(ctx context.Context, processor Processor, waitTimeSeconds int) (int, error) {
+	messages, err := h.receive(ctx, waitTimeSeconds)
 	if err != nil {
-		return fmt.Errorf("failed to receive messages: %w", err)
+		h.metrics.ReceiveError(h.queueURL)
+		return 0, fmt.Errorf("failed to receive messages: %w", err)
 	}
+	h.metrics.MessagesReceived(h.queueURL, len(messages))
+
+	s := h.process(ctx, messages, processor)
+	h.deleteSettled(ctx, s)
+	h.delay(ctx, s)
+
+	return len(messages), nil
+}
 
+// ProcessMessages processes messages delivered by a Lambda event source mapping, which
+// deletes the ones reported processed. Throttled messages are delayed as by ReceiveMessages.
+// It returns the ids of the messages to leave in the queue.
+func (h *SqsHandler) ProcessMessages(ctx context.Context, messages []types.Message, processor Processor) []string {
+	h.metrics.MessagesReceived(h.queueURL, len(messages))
+
+	s := h.process(ctx, messages, processor)
+	h.delay(ctx, s)
+
+	settled := make(map[string]bool, len(s.processed)+len(s.discarded))
+	for _, message := range s.processed {
+		settled[aws.ToString(message.MessageId)] = true
+		h.metrics.MessageDeleted(h.queueURL)
+	}
+	for _, message := range s.discarded {
+		settled[aws.ToString(message.MessageId)] = true
+	}
+	var failed []string
 	for _, message := range messages {
-		if reprocess, err := processor(message); err != nil {
-			h.logger.Error("Failed to process message",
-				"error", err.Error(),
-				"messageId", *message.MessageId,
-				"messageBody", *message.Body,
-			)
+		if id := aws.ToString(message.MessageId); !settled[id] {
+			failed = append(failed, id)
+		}
+	}
+	return failed
+}
 
-			if reprocess {
-				h.logger.Info("Reprocessing message", "messageId", *message.MessageId)
-				continue // Reprocess the message
-			}
+// process runs the processor on the messages, one after the other, or by message group for
+// a FIFO queue
+func (h *SqsHandler) process(ctx context.Context, messages []types.Message, processor Processor) *settlement {
+	s := newSettlement()
+	if h.fifo {
+		h.processGroups(ctx, messages, processor, s)
+	} else {
+		for _, message := range messages {
+			h.processMessage(ctx, message, processor, s)
+		}
+	}
+	return s
+}
 
-			h.logger.Info("Sending message to dead-letter queue", "messageId", *message.MessageId)
+// processGroups processes each message group of a FIFO queue in its own goroutine. Once a
+// message of a group stays in the queue, the next ones are released unprocessed, so they are
+// received again after it and the order of the group holds.
+func (h *SqsHandler) processGroups(ctx context.Context, messages []types.Message, processor Processor, s *settlement) {
+	var groupIds []string
+	groups := make(map[string][]types.Message)
+	for _, message := range messages {
+		groupId := message.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]
+		if _, ok := groups[groupId]; !ok {
+			groupIds = append(groupIds, groupId)
+		}
+		groups[groupId] = append(groups[groupId], message)
+	}
 
-			if err != nil {
-				// se deu altum tipo de erro, irá para a próxima mensagem
-				// e irá cair na retententativa da fila e eventualmente na DLQ
-				continue
+	settlements := make([]*settlement, len(groupIds))
+	var wg sync.WaitGroup
+	for i, groupId := range groupIds {
+		settlements[i] = newSettlement()
+		wg.Add(1)
+		go func(groupId string, group []types.Message, s *settlement) {
+			defer wg.Done()
+			for j, message := range group {
+				if h.processMessage(ctx, message, processor, s) {
+					continue
+				}
+				if next := group[j+1:]; len(next) > 0 {
+					h.logger.InfoContext(ctx, "Releasing the next messages of the group", "groupId", groupId, "count", len(next))
+					s.delayed[0] = append(s.delayed[0], next...)
+				}
+				return
 			}
+		}(groupId, groups[groupId], settlements[i])
+	}
+	wg.Wait()
+
+	for _, groupSettlement := range settlements {
+		s.merge(groupSettlement)
+	}
+}
+
+// settlement collects what becomes of the messages of a receive, so it is applied with
+// batch requests once they are all processed
+type settlement struct {
+	// processed messages are deleted and counted as such
+	processed []types.Message
+	// discarded messages failed and are deleted, after being forwarded to the dead-letter queue if any
+	discarded []types.Message
+	// delayed messages are kept invisible, by visibility timeout
+	delayed map[int][]types.Message
+}
+
+func newSettlement() *settlement {
+	return &settlement{delayed: make(map[int][]types.Message)}
+}
+
+// merge adds the messages of another settlement
+func (s *settlement) merge(other *settlement) {
+	s.processed = append(s.processed, other.processed...)
+	s.discarded = append(s.discarded, other.discarded...)
+	for visibilityTimeout, delayed := range other.delayed {
+		s.delayed[visibilityTimeout] = append(s.delayed[visibilityTimeout], delayed...)
+	}
+}
+
+func (h *SqsHandler) receive(ctx context.Context, waitTimeSeconds int) ([]types.Message, error) {
+	ctx, span := tracing.Start(ctx, "sqs.receive",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.MessagingSystemAWSSQS, semconv.MessagingOperationTypeReceive, semconv.AWSSQSQueueURL(h.queueURL)),
+	)
+	defer span.End()
+
+	messages, err := h.sqsClient.ReceiveMessages(ctx, h.queueURL, h.maxMessages, waitTimeSeconds)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return messages, nil
+}
+
+// processMessage runs the processor in a span that continues the trace of the message. It
+// tells if the message leaves the queue, processed or discarded.
+func (h *SqsHandler) processMessage(ctx context.Context, message types.Message, processor Processor, s *settlement) bool {
+	ctx, span := tracing.Start(tracing.ExtractSQS(ctx, message.MessageAttributes), "sqs.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemAWSSQS,
+			semconv.MessagingOperationTypeProcess,
+			semconv.AWSSQSQueueURL(h.queueURL),
+			semconv.MessagingMessageID(aws.ToString(message.MessageId)),
+		),
+	)
+	defer span.End()
+
+	receiveCount := receiveCount(message)
+	ctx = logger.WithFields(ctx, "messageId", aws.ToString(message.MessageId), "receiveCount", receiveCount)
+	if sentAt, ok := sentTimestamp(message); ok {
+		h.metrics.MessageLag(h.queueURL, time.Since(sentAt))
+	}
+
+	err := processor(ctx, message)
+	if err == nil {
+		s.processed = append(s.processed, message)
+		return true
+	}
+
+	tracing.RecordError(span, err)
+	if retryAfter, ok := domain.RetryAfter(err); ok {
+		visibilityTimeout := min(int(math.Ceil(retryAfter.Seconds())), maxVisibilityTimeoutSeconds)
+		h.logger.InfoContext(ctx, "Delaying throttled message", "visibilityTimeout", visibilityTimeout)
+		s.delayed[visibilityTimeout] = append(s.delayed[visibilityTimeout], message)
+		return false
+	}
+
+	h.metrics.MessageFailed(h.queueURL)
+	switch {
+	case domain.IsPermanent(err):
+		h.logger.ErrorContext(ctx, "Failed to process message, discarding it",
+			"error", err.Error(),
+			"code", domain.CodeOf(err),
+			"messageBody", aws.ToString(message.Body),
+		)
+	case h.deadLetterQueueURL != "" && h.maxReceiveCount > 0 && receiveCount >= h.maxReceiveCount:
+		h.logger.ErrorContext(ctx, "Failed to process message too many times, discarding it",
+			"error", err.Error(),
+			"code", domain.CodeOf(err),
+		)
+	default:
+		// Left in the queue, the message is received again once its visibility timeout
+		// expires, and moved to the dead-letter queue of the redrive policy after too many tries
+		h.logger.WarnContext(ctx, "Failed to process message, leaving it for redelivery",
+			"error", err.Error(),
+			"code", domain.CodeOf(err),
+		)
+		return false
+	}
+
+	if h.deadLetterQueueURL != "" {
+		if err := h.forwardToDeadLetterQueue(ctx, message, err, receiveCount); err != nil {
+			// Kept in the queue rather than lost, to be forwarded on its next delivery
+			h.logger.ErrorContext(ctx, "Failed to forward message to dead-letter queue", "error", err.Error())
+			return false
 		}
+	}
+	s.discarded = append(s.discarded, message)
+	return true
+}
 
-		if err := h.DeleteMessage(ctx, h.queueURL, *message.ReceiptHandle); err != nil {
-			h.logger.Error("Failed to delete message",
-				"error", err.Error(),
-				"messageId", *message.MessageId,
-				"messageBody", *message.Body,
-			)
-			continue
+// forwardToDeadLetterQueue sends a copy of a message that can't be processed to the dead-letter
+// queue. The failure is described in attributes added to the ones of the message.
+func (h *SqsHandler) forwardToDeadLetterQueue(ctx context.Context, message types.Message, cause error, receiveCount int) error {
+	attributes := make(map[string]string, len(message.MessageAttributes)+6)
+	for name, attribute := range message.MessageAttributes {
+		if attribute.StringValue != nil {
+			attributes[name] = *attribute.StringValue
 		}
 	}
+	code := domain.CodeOf(cause)
+	attributes[AttributeFailureReason] = cause.Error()
+	attributes[AttributeFailureCode] = string(code)
+	attributes[AttributeSourceQueue] = h.queueURL
+	if receiveCount > 0 {
+		attributes[AttributeReceiveCount] = strconv.Itoa(receiveCount)
+	}
+	if jobName := domain.JobNameOf(cause); jobName != "" {
+		attributes[AttributeJobName] = jobName
+	}
+	groupId := message.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]
+	if groupId != "" {
+		attributes[AttributeGroupId] = groupId
+	}
 
+	var err error
+	if IsFifo(h.deadLetterQueueURL) {
+		// The message id keeps a message forwarded again on its next delivery from being
+		// duplicated, and stands for the group of a message of a standard queue
+		if groupId == "" {
+			groupId = aws.ToString(message.MessageId)
+		}
+		_, err = h.sqsClient.SendGroupMessage(ctx, h.deadLetterQueueURL, groupId, aws.ToString(message.MessageId), aws.ToString(message.Body), attributes)
+	} else {
+		_, err = h.sqsClient.SendMessage(ctx, h.deadLetterQueueURL, aws.ToString(message.Body), attributes)
+	}
+	if err != nil {
+		return err
+	}
+	h.metrics.MessageDeadLettered(h.queueURL, string(code))
+	h.logger.InfoContext(ctx, "Forwarded message to dead-letter queue", "queue", h.deadLetterQueueURL)
 	return nil
 }
 
+// receiveCount returns how many times the message was received, or zero when SQS didn't
+// return the ApproximateReceiveCount attribute
+func receiveCount(message types.Message) int {
+	count, err := strconv.Atoi(message.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// sentTimestamp returns when the message was sent, from its SentTimestamp attribute in
+// milliseconds since the epoch
+func sentTimestamp(message types.Message) (time.Time, bool) {
+	millis, err := strconv.ParseInt(message.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(millis), true
+}
+
+// deleteSettled deletes the processed and discarded messages of a receive with batch requests.
+// The messages whose entry fails are received again once their visibility timeout expires.
+func (h *SqsHandler) deleteSettled(ctx context.Context, s *settlement) {
+	deleted := slices.Concat(s.processed, s.discarded)
+	if len(deleted) == 0 {
+		return
+	}
+	failed := h.batch(ctx, "Failed to delete message", deleted, func(receiptHandles []string) ([]BatchEntryError, error) {
+		return h.sqsClient.DeleteMessageBatch(ctx, h.queueURL, receiptHandles)
+	})
+	for _, message := range s.processed {
+		if !failed[aws.ToString(message.ReceiptHandle)] {
+			h.metrics.MessageDeleted(h.queueURL)
+		}
+	}
+}
+
+// delay keeps the delayed messages of a receive invisible with batch requests
+func (h *SqsHandler) delay(ctx context.Context, s *settlement) {
+	for visibilityTimeout, delayed := range s.delayed {
+		h.batch(ctx, "Failed to change message visibility", delayed, func(receiptHandles []string) ([]BatchEntryError, error) {
+			return h.sqsClient.ChangeMessageVisibilityBatch(ctx, h.queueURL, receiptHandles, visibilityTimeout)
+		})
+	}
+}
+
+// batch runs a batch request on the messages, logs the entries that failed and returns their
+// receipt handles
+func (h *SqsHandler) batch(ctx context.Context, failure string, messages []types.Message, request func(receiptHandles []string) ([]BatchEntryError, error)) map[string]bool {
+	receiptHandles := make([]string, len(messages))
+	messageIds := make(map[string]string, len(messages))
+	for i, message := range messages {
+		receiptHandles[i] = aws.ToString(message.ReceiptHandle)
+		messageIds[receiptHandles[i]] = aws.ToString(message.MessageId)
+	}
+
+	entries, err := request(receiptHandles)
+	if err != nil {
+		h.logger.ErrorContext(ctx, failure, "error", err.Error())
+	}
+	failed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		failed[entry.ReceiptHandle] = true
+		// A request that failed as a whole was logged above
+		if err == nil || entry.Code != "" {
+			h.logger.ErrorContext(ctx, failure,
+				"messageId", messageIds[entry.ReceiptHandle],
+				"error", entry.Error(),
+				"senderFault", entry.SenderFault,
+			)
+		}
+	}
+	return failed
+}
+
+// QueueURL returns the URL of the queue handled
+func (h *SqsHandler) QueueURL() string {
+	return h.queueURL
+}
+
 // DeleteMessage deletes a specific message from the queue
 func (h *SqsHandler) DeleteMessage(ctx context.Context, queueURL string, receiptHandle string) error {
 	return h.sqsClient.DeleteMessage(ctx, queueURL, receiptHandle)
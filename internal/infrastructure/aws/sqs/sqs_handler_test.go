@@ -0,0 +1,494 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/metrics"
+)
+
+const (
+	testQueueURL     = "https://sqs.us-east-1.amazonaws.com/000000000000/uploads"
+	testDLQURL       = "https://sqs.us-east-1.amazonaws.com/000000000000/uploads-dlq"
+	testFifoQueueURL = "https://sqs.us-east-1.amazonaws.com/000000000000/uploads.fifo"
+	testFifoDLQURL   = "https://sqs.us-east-1.amazonaws.com/000000000000/uploads-dlq.fifo"
+)
+
+// fakeMessageClient serves its messages and records what the handler does with them
+type fakeMessageClient struct {
+	// mu guards the sends, made concurrently for the groups of a FIFO queue
+	mu         sync.Mutex
+	messages   []types.Message
+	deleted    []string
+	visibility map[string]int
+	sent       map[string][]string
+	attributes map[string]string
+	sendErr    error
+	// batches counts the batch requests
+	batches int
+	// deleteFailures fails the deletion of these receipt handles with a code
+	deleteFailures map[string]string
+	// groupIds and deduplicationIds are the ones of the messages sent to FIFO queues
+	groupIds         []string
+	deduplicationIds []string
+}
+
+func newFakeMessageClient(body string) *fakeMessageClient {
+	return newFakeMessageClientReceived(body, 1)
+}
+
+// newFakeMessageClientReceived serves a message received receiveCount times
+func newFakeMessageClientReceived(body string, receiveCount int) *fakeMessageClient {
+	f := &fakeMessageClient{
+		visibility:     make(map[string]int),
+		sent:           make(map[string][]string),
+		deleteFailures: make(map[string]string),
+	}
+	f.add(body, receiveCount)
+	return f
+}
+
+// add serves one more message, numbered after the others
+func (f *fakeMessageClient) add(body string, receiveCount int) {
+	n := strconv.Itoa(len(f.messages) + 1)
+	f.messages = append(f.messages, types.Message{
+		MessageId:     aws.String("message-" + n),
+		ReceiptHandle: aws.String("receipt-" + n),
+		Body:          aws.String(body),
+		Attributes: map[string]string{
+			string(types.MessageSystemAttributeNameApproximateReceiveCount): strconv.Itoa(receiveCount),
+			string(types.MessageSystemAttributeNameSentTimestamp):           strconv.FormatInt(time.Now().Add(-time.Minute).UnixMilli(), 10),
+		},
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"traceparent": {DataType: aws.String("String"), StringValue: aws.String("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+		},
+	})
+}
+
+// addToGroup serves one more message of a FIFO queue, in the given group
+func (f *fakeMessageClient) addToGroup(body string, groupId string) {
+	f.add(body, 1)
+	f.messages[len(f.messages)-1].Attributes[string(types.MessageSystemAttributeNameMessageGroupId)] = groupId
+}
+
+func (f *fakeMessageClient) ReceiveMessages(ctx context.Context, queueURL string, maxMessages int, waitTimeSeconds int) ([]types.Message, error) {
+	return f.messages, nil
+}
+
+func (f *fakeMessageClient) DeleteMessage(ctx context.Context, queueURL string, receiptHandle string) error {
+	f.deleted = append(f.deleted, receiptHandle)
+	return nil
+}
+
+func (f *fakeMessageClient) DeleteMessageBatch(ctx context.Context, queueURL string, receiptHandles []string) ([]BatchEntryError, error) {
+	f.batches++
+	var failed []BatchEntryError
+	for _, receiptHandle := range receiptHandles {
+		if code, ok := f.deleteFailures[receiptHandle]; ok {
+			failed = append(failed, BatchEntryError{ReceiptHandle: receiptHandle, Code: code, Message: "refused", SenderFault: true})
+			continue
+		}
+		f.deleted = append(f.deleted, receiptHandle)
+	}
+	return failed, nil
+}
+
+func (f *fakeMessageClient) ChangeMessageVisibility(ctx context.Context, queueURL string, receiptHandle string, visibilityTimeout int) error {
+	f.visibility[receiptHandle] = visibilityTimeout
+	return nil
+}
+
+func (f *fakeMessageClient) ChangeMessageVisibilityBatch(ctx context.Context, queueURL string, receiptHandles []string, visibilityTimeout int) ([]BatchEntryError, error) {
+	f.batches++
+	for _, receiptHandle := range receiptHandles {
+		f.visibility[receiptHandle] = visibilityTimeout
+	}
+	return nil, nil
+}
+
+func (f *fakeMessageClient) SendMessage(ctx context.Context, queueURL string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.sendErr != nil {
+		return nil, f.sendErr
+	}
+	f.sent[queueURL] = append(f.sent[queueURL], messageBody)
+	f.attributes = attributes
+	return &types.Message{MessageId: aws.String("forwarded-1")}, nil
+}
+
+func (f *fakeMessageClient) SendGroupMessage(ctx context.Context, queueURL string, groupId string, deduplicationId string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	f.mu.Lock()
+	f.groupIds = append(f.groupIds, groupId)
+	f.deduplicationIds = append(f.deduplicationIds, deduplicationId)
+	f.mu.Unlock()
+	return f.SendMessage(ctx, queueURL, messageBody, attributes)
+}
+
+func newTestHandler(client MessageClient) *SqsHandler {
+	return newTestHandlerWithMetrics(client, nil)
+}
+
+func newFifoTestHandler(client MessageClient) *SqsHandler {
+	return NewSqsHandler(client, testFifoQueueURL, 10, 0, &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}, nil)
+}
+
+func newTestHandlerWithMetrics(client MessageClient, m *metrics.Metrics) *SqsHandler {
+	return NewSqsHandler(client, testQueueURL, 10, 0, &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}, m)
+}
+
+func failWith(err error) Processor {
+	return func(ctx context.Context, message types.Message) error {
+		return err
+	}
+}
+
+func TestSqsHandler_ReceiveMessages(t *testing.T) {
+	t.Run("should delete a processed message", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`{}`)
+		handler := newTestHandler(client)
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(nil))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"receipt-1"}, client.deleted)
+	})
+
+	t.Run("should delete the messages of a receive in one batch", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`{}`)
+		client.add(`not json`, 1)
+		client.add(`{}`, 1)
+		handler := newTestHandler(client)
+		processor := func(ctx context.Context, message types.Message) error {
+			if aws.ToString(message.Body) == "not json" {
+				return domain.NewError(domain.CodeInvalidS3Event, "failed to unmarshal S3 event", nil)
+			}
+			return nil
+		}
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), processor)
+
+		// Assert
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"receipt-1", "receipt-2", "receipt-3"}, client.deleted)
+		assert.Equal(t, 1, client.batches)
+	})
+
+	t.Run("should only count the messages whose deletion succeeded", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`{}`)
+		client.add(`{}`, 1)
+		client.deleteFailures["receipt-2"] = "ReceiptHandleIsInvalid"
+		registry := prometheus.NewRegistry()
+		handler := newTestHandlerWithMetrics(client, metrics.NewMetrics(registry))
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(nil))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"receipt-1"}, client.deleted)
+		assert.Equal(t, 1.0, gatheredValue(t, registry, "job_starter_messages_deleted_total"))
+	})
+
+	t.Run("should measure how long the messages waited in the queue", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`{}`)
+		client.add(`{}`, 1)
+		registry := prometheus.NewRegistry()
+		handler := newTestHandlerWithMetrics(client, metrics.NewMetrics(registry))
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(nil))
+
+		// Assert
+		require.NoError(t, err)
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		for _, family := range families {
+			if family.GetName() == "job_starter_message_lag_seconds" {
+				histogram := family.GetMetric()[0].GetHistogram()
+				assert.Equal(t, uint64(2), histogram.GetSampleCount())
+				assert.GreaterOrEqual(t, histogram.GetSampleSum(), 120.0)
+				return
+			}
+		}
+		t.Fatal("message lag not measured")
+	})
+
+	t.Run("should delay the throttled messages of a receive in one batch", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`{}`)
+		client.add(`{}`, 1)
+		handler := newTestHandler(client)
+		throttled := domain.NewThrottledError("too many active jobs", 30*time.Second, domain.ErrTooManyActiveJobs)
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(throttled))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"receipt-1": 30, "receipt-2": 30}, client.visibility)
+		assert.Equal(t, 1, client.batches)
+	})
+
+	t.Run("should delay a throttled message until it can be retried", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`{}`)
+		handler := newTestHandler(client)
+		throttled := domain.NewThrottledError("too many active jobs", 90*time.Second, domain.ErrTooManyActiveJobs)
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(throttled))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, client.deleted)
+		assert.Equal(t, 90, client.visibility["receipt-1"])
+	})
+
+	t.Run("should leave a retryable failure for redelivery", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`{}`)
+		handler := newTestHandler(client).WithDeadLetterQueue(testDLQURL, 0)
+		retryable := domain.NewError(domain.CodeInternal, "error creating job", errors.New("connection refused"))
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(retryable))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, client.deleted)
+		assert.Empty(t, client.visibility)
+		assert.Empty(t, client.sent)
+	})
+
+	t.Run("should forward a retryable failure received too many times with its context", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClientReceived(`{}`, 5)
+		handler := newTestHandler(client).WithDeadLetterQueue(testDLQURL, 5)
+		cause := domain.NewError(domain.CodeJobStartTimeout, "job video-processor-42 did not start in time", nil)
+		retryable := fmt.Errorf("error creating job: %w", domain.WithJob("video-processor-42", cause))
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(retryable))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"{}"}, client.sent[testDLQURL])
+		assert.Equal(t, "JOB_START_TIMEOUT", client.attributes[AttributeFailureCode])
+		assert.Equal(t, "error creating job: job video-processor-42 did not start in time", client.attributes[AttributeFailureReason])
+		assert.Equal(t, "5", client.attributes[AttributeReceiveCount])
+		assert.Equal(t, "video-processor-42", client.attributes[AttributeJobName])
+		assert.Equal(t, []string{"receipt-1"}, client.deleted)
+	})
+
+	t.Run("should leave a retryable failure received fewer times than the threshold", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClientReceived(`{}`, 4)
+		handler := newTestHandler(client).WithDeadLetterQueue(testDLQURL, 5)
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(errors.New("connection refused")))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, client.sent)
+		assert.Empty(t, client.deleted)
+	})
+
+	t.Run("should leave an unclassified failure for redelivery", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`{}`)
+		handler := newTestHandler(client)
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(errors.New("unexpected")))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, client.deleted)
+	})
+
+	t.Run("should delete a permanent failure without a dead-letter queue", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`not json`)
+		handler := newTestHandler(client)
+		permanent := domain.NewError(domain.CodeInvalidS3Event, "failed to unmarshal S3 event", errors.New("invalid character"))
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(permanent))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"receipt-1"}, client.deleted)
+		assert.Empty(t, client.sent)
+	})
+
+	t.Run("should forward a permanent failure to the dead-letter queue with its reason", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`not json`)
+		handler := newTestHandler(client).WithDeadLetterQueue(testDLQURL, 0)
+		permanent := domain.NewError(domain.CodeInvalidS3Event, "failed to unmarshal S3 event", errors.New("invalid character"))
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(permanent))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"not json"}, client.sent[testDLQURL])
+		assert.Equal(t, "failed to unmarshal S3 event: invalid character", client.attributes[AttributeFailureReason])
+		assert.Equal(t, "INVALID_S3_EVENT", client.attributes[AttributeFailureCode])
+		assert.Equal(t, testQueueURL, client.attributes[AttributeSourceQueue])
+		assert.Contains(t, client.attributes, "traceparent")
+		assert.Equal(t, []string{"receipt-1"}, client.deleted)
+	})
+
+	t.Run("should keep a permanent failure that can't be forwarded", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`not json`)
+		client.sendErr = errors.New("access denied")
+		handler := newTestHandler(client).WithDeadLetterQueue(testDLQURL, 0)
+		permanent := domain.NewError(domain.CodeInvalidS3Event, "failed to unmarshal S3 event", nil)
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(permanent))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Empty(t, client.deleted)
+	})
+}
+
+func TestSqsHandler_ReceiveMessages_Fifo(t *testing.T) {
+	t.Run("should process the message groups in parallel", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`first`)
+		client.messages[0].Attributes[string(types.MessageSystemAttributeNameMessageGroupId)] = "user-1"
+		client.addToGroup(`second`, "user-2")
+		handler := newFifoTestHandler(client)
+		secondDone := make(chan struct{})
+		processor := func(ctx context.Context, message types.Message) error {
+			if aws.ToString(message.Body) == "second" {
+				close(secondDone)
+				return nil
+			}
+			// The first group only completes once the second one was processed alongside
+			select {
+			case <-secondDone:
+				return nil
+			case <-time.After(time.Second):
+				return errors.New("groups processed sequentially")
+			}
+		}
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), processor)
+
+		// Assert
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"receipt-1", "receipt-2"}, client.deleted)
+	})
+
+	t.Run("should release the next messages of a group after a failure", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`fails`)
+		client.messages[0].Attributes[string(types.MessageSystemAttributeNameMessageGroupId)] = "user-1"
+		client.addToGroup(`after`, "user-1")
+		client.addToGroup(`other`, "user-2")
+		handler := newFifoTestHandler(client)
+		var mu sync.Mutex
+		var processed []string
+		processor := func(ctx context.Context, message types.Message) error {
+			mu.Lock()
+			processed = append(processed, aws.ToString(message.Body))
+			mu.Unlock()
+			if aws.ToString(message.Body) == "fails" {
+				return domain.NewError(domain.CodeInternal, "error creating job", errors.New("connection refused"))
+			}
+			return nil
+		}
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), processor)
+
+		// Assert
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"fails", "other"}, processed)
+		assert.Equal(t, []string{"receipt-3"}, client.deleted)
+		assert.Equal(t, map[string]int{"receipt-2": 0}, client.visibility)
+	})
+
+	t.Run("should process the messages of a group in order", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`1`)
+		client.messages[0].Attributes[string(types.MessageSystemAttributeNameMessageGroupId)] = "user-1"
+		client.addToGroup(`2`, "user-1")
+		client.addToGroup(`3`, "user-1")
+		handler := newFifoTestHandler(client)
+		var processed []string
+		processor := func(ctx context.Context, message types.Message) error {
+			processed = append(processed, aws.ToString(message.Body))
+			return nil
+		}
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), processor)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "3"}, processed)
+	})
+
+	t.Run("should forward to a FIFO dead-letter queue in the group of the message", func(t *testing.T) {
+		// Arrange
+		client := newFakeMessageClient(`not json`)
+		client.messages[0].Attributes[string(types.MessageSystemAttributeNameMessageGroupId)] = "user-1"
+		handler := newFifoTestHandler(client).WithDeadLetterQueue(testFifoDLQURL, 0)
+		permanent := domain.NewError(domain.CodeInvalidS3Event, "failed to unmarshal S3 event", nil)
+
+		// Act
+		err := handler.ReceiveMessages(context.Background(), failWith(permanent))
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{"not json"}, client.sent[testFifoDLQURL])
+		assert.Equal(t, []string{"user-1"}, client.groupIds)
+		assert.Equal(t, []string{"message-1"}, client.deduplicationIds)
+		assert.Equal(t, "user-1", client.attributes[AttributeGroupId])
+		assert.Equal(t, []string{"receipt-1"}, client.deleted)
+	})
+}
+
+// gatheredValue returns the value of a counter with a single series
+func gatheredValue(t *testing.T, registry *prometheus.Registry, name string) float64 {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	return 0
+}
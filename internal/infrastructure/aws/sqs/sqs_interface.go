@@ -2,9 +2,22 @@ package sqs
 
 import (
 	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
 // ConsumerInterface defines the interface for SQS consumer
 type ConsumerInterface interface {
 	Start(ctx context.Context) error
 }
+
+// MessageClient defines the queue operations used by the SqsHandler
+type MessageClient interface {
+	ReceiveMessages(ctx context.Context, queueURL string, maxMessages int, waitTimeSeconds int) ([]types.Message, error)
+	DeleteMessage(ctx context.Context, queueURL string, receiptHandle string) error
+	DeleteMessageBatch(ctx context.Context, queueURL string, receiptHandles []string) ([]BatchEntryError, error)
+	ChangeMessageVisibility(ctx context.Context, queueURL string, receiptHandle string, visibilityTimeout int) error
+	ChangeMessageVisibilityBatch(ctx context.Context, queueURL string, receiptHandles []string, visibilityTimeout int) ([]BatchEntryError, error)
+	SendMessage(ctx context.Context, queueURL string, messageBody string, attributes map[string]string) (*types.Message, error)
+	SendGroupMessage(ctx context.Context, queueURL string, groupId string, deduplicationId string, messageBody string, attributes map[string]string) (*types.Message, error)
+}
@@ -0,0 +1,81 @@
+package sqs
+
+import (
+	"context"
+	"sync"
+)
+
+// WeightedHandler is a queue handler polled proportionally to its weight
+type WeightedHandler struct {
+	Handler *SqsHandler
+	Weight  int
+}
+
+// WeightedPoller spreads receives over several queues with smooth weighted round robin,
+// so a queue with weight 3 is polled three times as often as a queue with weight 1
+type WeightedPoller struct {
+	mu       sync.Mutex
+	handlers []WeightedHandler
+	current  []int
+}
+
+// NewWeightedPoller creates a poller over the given handlers. Weights lower than 1 are set to 1.
+func NewWeightedPoller(handlers []WeightedHandler) *WeightedPoller {
+	for i := range handlers {
+		if handlers[i].Weight < 1 {
+			handlers[i].Weight = 1
+		}
+	}
+	return &WeightedPoller{
+		handlers: handlers,
+		current:  make([]int, len(handlers)),
+	}
+}
+
+// Next returns the handler of the next queue to poll
+func (p *WeightedPoller) Next() *SqsHandler {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total := 0
+	best := 0
+	for i, h := range p.handlers {
+		p.current[i] += h.Weight
+		total += h.Weight
+		if p.current[i] > p.current[best] {
+			best = i
+		}
+	}
+	p.current[best] -= total
+
+	return p.handlers[best].Handler
+}
+
+// ReceiveMessages receives and processes one batch from the queues. It short polls the queues
+// in weighted order, so an empty queue doesn't hold back the others, and long polls the next
+// queue only when a whole rotation came back empty.
+func (p *WeightedPoller) ReceiveMessages(ctx context.Context, processor Processor) error {
+	if len(p.handlers) == 1 {
+		return p.handlers[0].Handler.ReceiveMessages(ctx, processor)
+	}
+
+	for range p.rotation() {
+		received, err := p.Next().poll(ctx, processor, 0)
+		if err != nil || received > 0 {
+			return err
+		}
+	}
+
+	next := p.Next()
+	_, err := next.poll(ctx, processor, next.waitTimeSeconds)
+	return err
+}
+
+// rotation returns how many polls it takes to poll every queue at least once
+func (p *WeightedPoller) rotation() int {
+	total := 0
+	for _, h := range p.handlers {
+		total += h.Weight
+	}
+	return total
+}
@@ -0,0 +1,161 @@
+package sqs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+)
+
+// pollRecorder serves the messages of each queue once and records the wait of each receive
+type pollRecorder struct {
+	*fakeMessageClient
+	queued map[string][]types.Message
+	polls  []poll
+}
+
+type poll struct {
+	queueURL        string
+	waitTimeSeconds int
+}
+
+func newPollRecorder() *pollRecorder {
+	return &pollRecorder{fakeMessageClient: newFakeMessageClient("{}"), queued: make(map[string][]types.Message)}
+}
+
+func (r *pollRecorder) ReceiveMessages(ctx context.Context, queueURL string, maxMessages int, waitTimeSeconds int) ([]types.Message, error) {
+	r.polls = append(r.polls, poll{queueURL: queueURL, waitTimeSeconds: waitTimeSeconds})
+	messages := r.queued[queueURL]
+	delete(r.queued, queueURL)
+	return messages, nil
+}
+
+func newPolledHandler(client MessageClient, queueURL string) *SqsHandler {
+	return NewSqsHandler(client, queueURL, 10, 20, &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}, nil)
+}
+
+func TestWeightedPoller_Next(t *testing.T) {
+	t.Run("should poll queues proportionally to their weights", func(t *testing.T) {
+		// Arrange
+		premium := NewSqsHandler(nil, "premium", 10, 0, nil, nil)
+		standard := NewSqsHandler(nil, "standard", 10, 0, nil, nil)
+		poller := NewWeightedPoller([]WeightedHandler{
+			{Handler: premium, Weight: 3},
+			{Handler: standard, Weight: 1},
+		})
+
+		// Act
+		polls := make(map[string]int)
+		for range 8 {
+			polls[poller.Next().QueueURL()]++
+		}
+
+		// Assert
+		assert.Equal(t, 6, polls["premium"])
+		assert.Equal(t, 2, polls["standard"])
+	})
+
+	t.Run("should start with the heaviest queue", func(t *testing.T) {
+		// Arrange
+		premium := NewSqsHandler(nil, "premium", 10, 0, nil, nil)
+		standard := NewSqsHandler(nil, "standard", 10, 0, nil, nil)
+		poller := NewWeightedPoller([]WeightedHandler{
+			{Handler: standard, Weight: 1},
+			{Handler: premium, Weight: 2},
+		})
+
+		// Act
+		first := poller.Next()
+
+		// Assert
+		assert.Equal(t, "premium", first.QueueURL())
+	})
+
+	t.Run("should treat invalid weights as one", func(t *testing.T) {
+		// Arrange
+		a := NewSqsHandler(nil, "a", 10, 0, nil, nil)
+		b := NewSqsHandler(nil, "b", 10, 0, nil, nil)
+		poller := NewWeightedPoller([]WeightedHandler{
+			{Handler: a, Weight: 0},
+			{Handler: b, Weight: -2},
+		})
+
+		// Act
+		polls := make(map[string]int)
+		for range 4 {
+			polls[poller.Next().QueueURL()]++
+		}
+
+		// Assert
+		assert.Equal(t, 2, polls["a"])
+		assert.Equal(t, 2, polls["b"])
+	})
+}
+
+func TestWeightedPoller_ReceiveMessages(t *testing.T) {
+	processor := func(ctx context.Context, message types.Message) error { return nil }
+
+	t.Run("should short poll past an empty queue to the next one", func(t *testing.T) {
+		// Arrange
+		client := newPollRecorder()
+		client.queued["standard"] = client.messages
+		poller := NewWeightedPoller([]WeightedHandler{
+			{Handler: newPolledHandler(client, "premium"), Weight: 3},
+			{Handler: newPolledHandler(client, "standard"), Weight: 1},
+		})
+
+		// Act
+		err := poller.ReceiveMessages(context.Background(), processor)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []poll{
+			{queueURL: "premium", waitTimeSeconds: 0},
+			{queueURL: "premium", waitTimeSeconds: 0},
+			{queueURL: "standard", waitTimeSeconds: 0},
+		}, client.polls)
+		assert.Equal(t, []string{"receipt-1"}, client.deleted)
+	})
+
+	t.Run("should long poll only when every queue came back empty", func(t *testing.T) {
+		// Arrange
+		client := newPollRecorder()
+		poller := NewWeightedPoller([]WeightedHandler{
+			{Handler: newPolledHandler(client, "premium"), Weight: 2},
+			{Handler: newPolledHandler(client, "standard"), Weight: 1},
+		})
+
+		// Act
+		err := poller.ReceiveMessages(context.Background(), processor)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []poll{
+			{queueURL: "premium", waitTimeSeconds: 0},
+			{queueURL: "standard", waitTimeSeconds: 0},
+			{queueURL: "premium", waitTimeSeconds: 0},
+			{queueURL: "premium", waitTimeSeconds: 20},
+		}, client.polls)
+	})
+
+	t.Run("should long poll a single queue", func(t *testing.T) {
+		// Arrange
+		client := newPollRecorder()
+		poller := NewWeightedPoller([]WeightedHandler{
+			{Handler: newPolledHandler(client, "standard"), Weight: 1},
+		})
+
+		// Act
+		err := poller.ReceiveMessages(context.Background(), processor)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []poll{{queueURL: "standard", waitTimeSeconds: 20}}, client.polls)
+	})
+}
@@ -0,0 +1,144 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+)
+
+// maxStatusErrors is the number of consecutive failures to read the job before giving up
+const maxStatusErrors = 3
+
+// Checker follows a processor job and publishes the status changes of its video
+type Checker struct {
+	jobConfig    *config.JobConfig
+	logger       *slog.Logger
+	k8sAPI       api.K8sAPIInterface
+	videoUsecase port.VideoUsecase
+	interval     time.Duration
+
+	// status is the last status published, or the one the starter published for the attempt
+	status dto.VideoProcessingStatus
+}
+
+func NewChecker(jobConfig *config.JobConfig, logger *slog.Logger, k8sAPI api.K8sAPIInterface, videoUsecase port.VideoUsecase, interval time.Duration) *Checker {
+	return &Checker{
+		jobConfig:    jobConfig,
+		logger:       logger,
+		k8sAPI:       k8sAPI,
+		videoUsecase: videoUsecase,
+		interval:     interval,
+	}
+}
+
+// Run polls the job until it completes. It returns an error when the job fails or
+// its status can no longer be read.
+func (c *Checker) Run(ctx context.Context) error {
+	jobConfig := c.jobConfig
+
+	// Reprocessing attempts are announced by the starter
+	if jobConfig.Attempt > 1 {
+		c.status = dto.VideoStatusReprocessing
+	} else {
+		if err := c.updateVideoStatus(ctx, dto.VideoStatusUploaded, nil); err != nil {
+			return err
+		}
+	}
+
+	var backoffLimit = 0
+	var jobPending = false
+	var jobQueued = false
+	var completedChunks int32 = 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.interval):
+		}
+
+		var jobStatus string
+		progress, err := c.k8sAPI.GetJobProgress(ctx, jobConfig.JobName, jobConfig.Namespace)
+		if err == nil {
+			jobStatus = progress.Status
+		}
+		c.logger.InfoContext(ctx, "Job status", "jobStatus", jobStatus)
+		if err != nil {
+			c.logger.ErrorContext(ctx, "Error getting job status",
+				"error", err,
+				"backoffLimit", backoffLimit,
+			)
+			backoffLimit++
+			if backoffLimit > maxStatusErrors {
+				c.logger.ErrorContext(ctx, "Job failed", "backoffLimit", backoffLimit)
+				return fmt.Errorf("failed to get status of job %s: %w", jobConfig.JobName, err)
+			}
+			continue
+		}
+		c.logger.InfoContext(ctx, fmt.Sprintf("Job %s", strings.ToLower(jobStatus)))
+		switch jobStatus {
+		case api.JobStatusComplete:
+			// This status will be updated by the Video Processor Job
+			return nil
+		case api.JobStatusFailed:
+			if err := c.updateVideoStatus(ctx, dto.VideoStatusFailed, nil); err != nil {
+				return err
+			}
+			return domain.NewError(domain.CodeJobFailed, fmt.Sprintf("job %s failed", jobConfig.JobName), nil)
+		case api.JobStatusPending:
+		case api.JobStatusQueued:
+			// The batch scheduler holds the job until there is capacity
+			if !jobQueued {
+				if err := c.updateVideoStatus(ctx, dto.VideoStatusQueued, nil); err != nil {
+					return err
+				}
+				jobQueued = true
+			}
+		case api.JobStatusRunning:
+			if !jobPending {
+				if err := c.updateVideoStatus(ctx, dto.VideoStatusProcessing, nil); err != nil {
+					return err
+				}
+				jobPending = true
+			}
+			// Indexed jobs report each finished chunk as progress of the video
+			if jobConfig.Completions > 1 && progress.CompletedIndexes > completedChunks {
+				completedChunks = progress.CompletedIndexes
+				c.logger.InfoContext(ctx, "Job progress", "completedChunks", completedChunks, "totalChunks", jobConfig.Completions)
+				if err := c.updateVideoStatus(ctx, dto.VideoStatusProcessing, &dto.VideoProgress{
+					CompletedChunks: completedChunks,
+					TotalChunks:     jobConfig.Completions,
+				}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (c *Checker) updateVideoStatus(ctx context.Context, status dto.VideoProcessingStatus, progress *dto.VideoProgress) error {
+	if c.status != "" && !c.status.CanTransitionTo(status) {
+		return domain.NewError(domain.CodeInvalidStatusTransition, fmt.Sprintf("video %d can't move from %s to %s", c.jobConfig.VideoId, c.status, status), nil)
+	}
+
+	err := c.videoUsecase.UpdateVideoStatus(ctx, dto.UpdateVideoStatusInput{
+		VideoId:  c.jobConfig.VideoId,
+		UserId:   c.jobConfig.UserId,
+		Status:   status,
+		Progress: progress,
+	})
+	if err != nil {
+		c.logger.ErrorContext(ctx, "Error updating video status", "error", err)
+		return fmt.Errorf("failed to update video status to %s: %w", status, err)
+	}
+	c.status = status
+	return nil
+}
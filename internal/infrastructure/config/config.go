@@ -15,6 +15,20 @@ type Config struct {
 	// Environment
 	Environment string
 
+	// Log level (debug, info, warn or error) and format (json, text or pretty), empty to use
+	// the default of the environment
+	LogLevel  string
+	LogFormat string
+
+	// How often the configuration file is checked for changes, zero disables the reload
+	ReloadInterval time.Duration
+
+	// Address of the health, readiness and metrics endpoints, empty disables the server
+	HTTPAddr string
+
+	// OTLP/HTTP endpoint the spans are exported to, such as http://localhost:4318, empty disables tracing
+	OTLPEndpoint string
+
 	// K8S Settings
 	K8S struct {
 		Namespace          string
@@ -27,12 +41,46 @@ type Config struct {
 			Command                 string
 			Envs                    map[string]string
 			TtlSecondsAfterFinished time.Duration
+			StartTimeout            time.Duration
 			BackOffLimit            int32
 			JobName                 string
 			ImageChecker            string
+			Suspend                 bool
+			QueueLabel              string
+			QueueName               string
+			Indexed                 struct {
+				Enabled        bool
+				ChunkSizeBytes int64
+				MaxCompletions int32
+				Parallelism    int32
+			}
 		}
 	}
 
+	// Priority lanes, mapping a user tier to a PriorityClass and a job template
+	Priority struct {
+		DefaultTier string
+		Tiers       map[string]JobTier
+		UserTiers   map[int64]string
+		Queues      []WeightedQueue
+	}
+
+	// Admission limits applied before creating the jobs of a new video
+	Admission struct {
+		MaxActiveJobs        int
+		MaxActiveJobsPerUser int
+		RetryDelay           time.Duration
+		MaxRetryDelay        time.Duration
+	}
+
+	// DeadLetter receives the messages that can never be processed, such as malformed events
+	DeadLetter struct {
+		QueueURL string
+		// MaxReceiveCount is how many times a message failing for a transient reason is received
+		// before being forwarded to the queue. Zero leaves it to the redrive policy.
+		MaxReceiveCount int
+	}
+
 	AWS struct {
 		Region          string
 		AccessKey       string
@@ -50,76 +98,162 @@ type Config struct {
 	}
 }
 
+// JobTier overrides the processor job template for the videos of a tier. Empty fields keep the default.
+type JobTier struct {
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	Image             string `json:"image,omitempty"`
+	Command           string `json:"command,omitempty"`
+}
+
+// WeightedQueue is an SQS queue polled proportionally to its weight
+type WeightedQueue struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight,omitempty"`
+}
+
 type JobConfig struct {
-	JobName   string
-	Namespace string
-	VideoId   int64
-	UserId    int64
+	JobName     string
+	Namespace   string
+	VideoId     int64
+	UserId      int64
+	Completions int32
+	Attempt     int
 }
 
-func LoadLambdaConfig() *Config {
-	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
-		log.Printf("Warning: .env file not found or error loading it: %v", err)
+// LoadLambdaConfig loads the configuration file named by CONFIG_FILE, if any, and applies the
+// environment variables over it. See LoadLambdaConfigFrom.
+func LoadLambdaConfig() (*Config, error) {
+	loadDotEnv()
+	return LoadLambdaConfigFrom(getEnv("CONFIG_FILE", ""))
+}
+
+// LoadLambdaConfigFrom layers the defaults, the configuration file at path (YAML or JSON, optional
+// when path is empty) and the environment variables, in this order. The effective configuration is
+// returned even when it is invalid, along with a ValidationError listing every problem found.
+func LoadLambdaConfigFrom(path string) (*Config, error) {
+	loadDotEnv()
+
+	config := defaultConfig()
+	env := &envLoader{}
+	if path != "" {
+		problems, err := loadFile(path, config)
+		if err != nil {
+			return config, err
+		}
+		env.problems = problems
 	}
 
 	// Environment
-	environment := getEnv("ENVIRONMENT", "development")
+	env.string("ENVIRONMENT", &config.Environment)
+	env.string("LOG_LEVEL", &config.LogLevel)
+	env.string("LOG_FORMAT", &config.LogFormat)
+	env.duration("CONFIG_RELOAD_INTERVAL", &config.ReloadInterval)
+	env.string("HTTP_ADDR", &config.HTTPAddr)
+	env.string("OTEL_EXPORTER_OTLP_ENDPOINT", &config.OTLPEndpoint)
 
 	// K8S Settings
-	k8sNamespace := getEnv("K8S_NAMESPACE", "default")
-	k8sImage := getEnv("K8S_JOB_IMAGE", "ghcr.io/fiap-soat-g20/hackathon-job-starter-lambda:latest")
-	k8sCommand := getEnv("K8S_JOB_COMMAND", "echo \"Hello, World\"")
-	k8sJobPrefix := getEnv("K8S_JOB_PREFIX", "video-processor")
-	k8sServiceAccountName := getEnv("K8S_SERVICE_ACCOUNT_NAME", "job-checker-sa")
-	k8sJobEnvs := getEnvsWithPrefix("K8S_JOB_ENV_")
-	k8sJobTtlSecondsAfterFinished, err := time.ParseDuration(getEnv("K8S_JOB_TTL_SECONDS_AFTER_FINISHED", "10s"))
-	if err != nil {
-		log.Printf("Warning: K8S_JOB_TTL_SECONDS_AFTER_FINISHED is not a valid duration: %v. Setting to 10s", err)
-		k8sJobTtlSecondsAfterFinished = 10 * time.Second
-	}
-	k8sJobBackOffLimit, err := strconv.Atoi(getEnv("K8S_JOB_BACK_OFF_LIMIT", "3"))
-	if err != nil {
-		log.Printf("Warning: K8S_JOB_BACK_OFF_LIMIT is not a valid integer: %v. Setting to 3", err)
-		k8sJobBackOffLimit = 3
-	}
-	k8sJobImageChecker := getEnv("K8S_JOB_IMAGE_CHECKER", "docker.io/library/job-checker:latest")
+	env.string("K8S_NAMESPACE", &config.K8S.Namespace)
+	env.string("K8S_CONTEXT_NAME", &config.K8S.ContextName)
+	env.string("K8S_MASTER_URL", &config.K8S.MasterUrl)
+	env.string("K8S_SERVICE_ACCOUNT_NAME", &config.K8S.ServiceAccountName)
+	env.string("K8S_JOB_IMAGE", &config.K8S.Job.Image)
+	env.string("K8S_JOB_COMMAND", &config.K8S.Job.Command)
+	env.string("K8S_JOB_PREFIX", &config.K8S.Job.Prefix)
+	env.envs("K8S_JOB_ENV_", config.K8S.Job.Envs)
+	env.duration("K8S_JOB_TTL_SECONDS_AFTER_FINISHED", &config.K8S.Job.TtlSecondsAfterFinished)
+	env.duration("K8S_JOB_START_TIMEOUT", &config.K8S.Job.StartTimeout)
+	env.int32("K8S_JOB_BACK_OFF_LIMIT", &config.K8S.Job.BackOffLimit)
+	env.string("K8S_JOB_IMAGE_CHECKER", &config.K8S.Job.ImageChecker)
 
-	awsRegion := getEnv("AWS_REGION", "us-east-1")
-	awsAccessKey := getEnv("AWS_ACCESS_KEY_ID", "")
-	awsSecretAccessKey := getEnv("AWS_SECRET_ACCESS_KEY", "")
-	awsSnsTopicArn := getEnv("AWS_SNS_TOPIC_ARN", "")
-	awsSqsQueueURL := getEnv("AWS_SQS_QUEUE_URL", "")
-	awsSessionToken := getEnv("AWS_SESSION_TOKEN", "")
+	// Batch scheduler settings, jobs are created suspended in a local queue
+	env.bool("K8S_JOB_SUSPEND", &config.K8S.Job.Suspend)
+	env.string("K8S_JOB_QUEUE_LABEL", &config.K8S.Job.QueueLabel)
+	env.string("K8S_JOB_QUEUE_NAME", &config.K8S.Job.QueueName)
+
+	// Indexed job settings, used to split long videos in chunks
+	env.bool("K8S_JOB_INDEXED_ENABLED", &config.K8S.Job.Indexed.Enabled)
+	env.int64("K8S_JOB_INDEXED_CHUNK_SIZE_BYTES", &config.K8S.Job.Indexed.ChunkSizeBytes)
+	env.int32("K8S_JOB_INDEXED_MAX_COMPLETIONS", &config.K8S.Job.Indexed.MaxCompletions)
+	env.int32("K8S_JOB_INDEXED_PARALLELISM", &config.K8S.Job.Indexed.Parallelism)
+
+	// AWS Settings
+	env.string("AWS_REGION", &config.AWS.Region)
+	env.string("AWS_ACCESS_KEY_ID", &config.AWS.AccessKey)
+	env.string("AWS_SECRET_ACCESS_KEY", &config.AWS.SecretAccessKey)
+	env.string("AWS_SESSION_TOKEN", &config.AWS.SessionToken)
+	env.string("AWS_SNS_TOPIC_ARN", &config.AWS.SNS.TopicArn)
+	env.string("AWS_SQS_QUEUE_URL", &config.AWS.SQS.QueueURL)
 
 	// SQS Consumer settings
-	sqsWorkerPoolSize := getIntEnv("SQS_WORKER_POOL_SIZE", 5)
+	env.int("SQS_WORKER_POOL_SIZE", &config.AWS.SQS.WorkerPoolSize)
+	env.int("SQS_MAX_MESSAGES_BATCH", &config.AWS.SQS.MaxMessagesBatch)
+	env.int("SQS_WAIT_TIME_SECONDS", &config.AWS.SQS.WaitTimeSeconds)
+
+	// Priority settings
+	env.string("PRIORITY_DEFAULT_TIER", &config.Priority.DefaultTier)
+	env.jobTiers("K8S_JOB_TIER_", config.Priority.Tiers)
+	env.userTiers("PRIORITY_USER_TIERS", config.Priority.UserTiers)
+	env.weightedQueues("AWS_SQS_PRIORITY_QUEUES", &config.Priority.Queues)
+
+	// Admission settings, zero disables the limit
+	env.int("ADMISSION_MAX_ACTIVE_JOBS", &config.Admission.MaxActiveJobs)
+	env.int("ADMISSION_MAX_ACTIVE_JOBS_PER_USER", &config.Admission.MaxActiveJobsPerUser)
+	env.duration("ADMISSION_RETRY_DELAY", &config.Admission.RetryDelay)
+	env.duration("ADMISSION_MAX_RETRY_DELAY", &config.Admission.MaxRetryDelay)
+
+	// Dead-letter settings, messages failing permanently are dropped without a queue
+	env.string("AWS_SQS_DLQ_URL", &config.DeadLetter.QueueURL)
+	env.int("AWS_SQS_DLQ_MAX_RECEIVE_COUNT", &config.DeadLetter.MaxReceiveCount)
 
-	sqsMaxMessagesBatch := getIntEnv("SQS_MAX_MESSAGES_BATCH", 10)
-	sqsWaitTimeSeconds := getIntEnv("SQS_WAIT_TIME_SECONDS", 20)
+	config.Priority.DefaultTier = strings.ToLower(config.Priority.DefaultTier)
+
+	problems := append(env.problems, config.validate()...)
+	if len(problems) > 0 {
+		return config, &ValidationError{Problems: problems}
+	}
+	return config, nil
+}
+
+// defaultConfig returns the configuration used when neither the file nor the environment set a value
+func defaultConfig() *Config {
 	config := &Config{}
 
-	config.Environment = environment
-	config.K8S.Namespace = k8sNamespace
-	config.K8S.ServiceAccountName = k8sServiceAccountName
-	config.K8S.Job.Image = k8sImage
-	config.K8S.Job.Command = k8sCommand
-	config.K8S.Job.Prefix = k8sJobPrefix
-	config.K8S.Job.Envs = k8sJobEnvs
-	config.K8S.Job.TtlSecondsAfterFinished = k8sJobTtlSecondsAfterFinished
-	config.K8S.Job.BackOffLimit = int32(k8sJobBackOffLimit)
-	config.K8S.Job.ImageChecker = k8sJobImageChecker
-	config.AWS.Region = awsRegion
-	config.AWS.AccessKey = awsAccessKey
-	config.AWS.SecretAccessKey = awsSecretAccessKey
-	config.AWS.SNS.TopicArn = awsSnsTopicArn
-	config.AWS.SQS.QueueURL = awsSqsQueueURL
-	config.AWS.SQS.WorkerPoolSize = sqsWorkerPoolSize
-	config.AWS.SQS.MaxMessagesBatch = sqsMaxMessagesBatch
-	config.AWS.SQS.WaitTimeSeconds = sqsWaitTimeSeconds
-	config.AWS.SessionToken = awsSessionToken
+	config.Environment = "development"
+	config.ReloadInterval = 10 * time.Second
+	config.HTTPAddr = ":8080"
+	config.K8S.Namespace = "default"
+	config.K8S.ServiceAccountName = "job-checker-sa"
+	config.K8S.Job.Image = "ghcr.io/fiap-soat-g20/hackathon-job-starter-lambda:latest"
+	config.K8S.Job.Command = "echo \"Hello, World\""
+	config.K8S.Job.Prefix = "video-processor"
+	config.K8S.Job.Envs = make(map[string]string)
+	config.K8S.Job.TtlSecondsAfterFinished = 10 * time.Second
+	config.K8S.Job.StartTimeout = 5 * time.Minute
+	config.K8S.Job.BackOffLimit = 3
+	config.K8S.Job.ImageChecker = "docker.io/library/job-checker:latest"
+	config.K8S.Job.QueueLabel = "kueue.x-k8s.io/queue-name"
+	config.K8S.Job.Indexed.ChunkSizeBytes = 500 * 1024 * 1024
+	config.K8S.Job.Indexed.MaxCompletions = 10
+	config.K8S.Job.Indexed.Parallelism = 3
+	config.Priority.DefaultTier = "standard"
+	config.Priority.Tiers = make(map[string]JobTier)
+	config.Priority.UserTiers = make(map[int64]string)
+	config.Priority.Queues = make([]WeightedQueue, 0)
+	config.Admission.RetryDelay = 30 * time.Second
+	config.Admission.MaxRetryDelay = 15 * time.Minute
+	config.AWS.Region = "us-east-1"
+	config.AWS.SQS.WorkerPoolSize = 5
+	config.AWS.SQS.MaxMessagesBatch = 10
+	config.AWS.SQS.WaitTimeSeconds = 20
 	return config
 }
 
+func loadDotEnv() {
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: .env file not found or error loading it: %v", err)
+	}
+}
+
 func LoadJobConfig() *JobConfig {
 	jobName := getEnv("JOB_NAME", "video-processor")
 	namespace := getEnv("JOB_NAMESPACE", "default")
@@ -133,11 +267,15 @@ func LoadJobConfig() *JobConfig {
 		log.Printf("Warning: JOB_USER_ID is not a valid integer: %v. Setting to 0", err)
 		userId = 0
 	}
+	completions := getIntEnv("JOB_COMPLETIONS", 1)
+	attempt := getIntEnv("JOB_ATTEMPT", 1)
 	return &JobConfig{
-		JobName:   jobName,
-		Namespace: namespace,
-		VideoId:   videoId,
-		UserId:    userId,
+		JobName:     jobName,
+		Namespace:   namespace,
+		VideoId:     videoId,
+		UserId:      userId,
+		Completions: int32(completions),
+		Attempt:     attempt,
 	}
 }
 
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clearEnv blanks the variables read by LoadLambdaConfigFrom, blank variables being ignored
+func clearEnv(t *testing.T) {
+	t.Helper()
+
+	for _, key := range []string{
+		"ENVIRONMENT", "LOG_LEVEL", "LOG_FORMAT", "CONFIG_FILE", "CONFIG_RELOAD_INTERVAL", "HTTP_ADDR",
+		"OTEL_EXPORTER_OTLP_ENDPOINT",
+		"K8S_NAMESPACE", "K8S_CONTEXT_NAME", "K8S_MASTER_URL", "K8S_SERVICE_ACCOUNT_NAME",
+		"K8S_JOB_IMAGE", "K8S_JOB_COMMAND", "K8S_JOB_PREFIX", "K8S_JOB_TTL_SECONDS_AFTER_FINISHED",
+		"K8S_JOB_START_TIMEOUT",
+		"K8S_JOB_BACK_OFF_LIMIT", "K8S_JOB_IMAGE_CHECKER", "K8S_JOB_SUSPEND", "K8S_JOB_QUEUE_LABEL",
+		"K8S_JOB_QUEUE_NAME", "K8S_JOB_INDEXED_ENABLED", "K8S_JOB_INDEXED_CHUNK_SIZE_BYTES",
+		"K8S_JOB_INDEXED_MAX_COMPLETIONS", "K8S_JOB_INDEXED_PARALLELISM",
+		"AWS_REGION", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"AWS_SNS_TOPIC_ARN", "AWS_SQS_QUEUE_URL", "AWS_SQS_PRIORITY_QUEUES", "AWS_SQS_DLQ_URL", "AWS_SQS_DLQ_MAX_RECEIVE_COUNT",
+		"SQS_WORKER_POOL_SIZE", "SQS_MAX_MESSAGES_BATCH", "SQS_WAIT_TIME_SECONDS",
+		"PRIORITY_DEFAULT_TIER", "PRIORITY_USER_TIERS",
+		"ADMISSION_MAX_ACTIVE_JOBS", "ADMISSION_MAX_ACTIVE_JOBS_PER_USER",
+		"ADMISSION_RETRY_DELAY", "ADMISSION_MAX_RETRY_DELAY",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+// setRequiredEnv sets the variables without default
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("AWS_SNS_TOPIC_ARN", "arn:aws:sns:us-east-1:123456789012:video-status")
+	t.Setenv("AWS_SQS_QUEUE_URL", "https://sqs.us-east-1.amazonaws.com/123456789012/uploads")
+}
+
+func TestLoadLambdaConfigFrom(t *testing.T) {
+	t.Run("should load the defaults and the environment without a file", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		setRequiredEnv(t)
+		t.Setenv("K8S_JOB_TTL_SECONDS_AFTER_FINISHED", "600s")
+		t.Setenv("K8S_JOB_START_TIMEOUT", "2m")
+		t.Setenv("PRIORITY_USER_TIERS", "42=premium")
+		t.Setenv("K8S_JOB_TIER_PREMIUM_IMAGE", "video-processor-gpu:latest")
+
+		// Act
+		cfg, err := LoadLambdaConfigFrom("")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "default", cfg.K8S.Namespace)
+		assert.Equal(t, 600*time.Second, cfg.K8S.Job.TtlSecondsAfterFinished)
+		assert.Equal(t, 2*time.Minute, cfg.K8S.Job.StartTimeout)
+		assert.Equal(t, "premium", cfg.Priority.UserTiers[42])
+		assert.Equal(t, "video-processor-gpu:latest", cfg.Priority.Tiers["premium"].Image)
+		assert.Equal(t, 30*time.Second, cfg.Admission.RetryDelay)
+	})
+
+	t.Run("should load a YAML file", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		cfg, err := LoadLambdaConfigFrom("testdata/config.yaml")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "staging", cfg.Environment)
+		assert.Equal(t, "video", cfg.K8S.Namespace)
+		assert.Equal(t, "video-processor:1.2.0", cfg.K8S.Job.Image)
+		assert.Equal(t, 10*time.Minute, cfg.K8S.Job.TtlSecondsAfterFinished)
+		assert.Equal(t, "zip", cfg.K8S.Job.Envs["OUTPUT_FORMAT"])
+		assert.Equal(t, "standard", cfg.Priority.DefaultTier)
+		assert.Equal(t, JobTier{PriorityClassName: "video-premium", Image: "video-processor-gpu:1.2.0"}, cfg.Priority.Tiers["premium"])
+		assert.Equal(t, "premium", cfg.Priority.UserTiers[42])
+		assert.Equal(t, 20, cfg.Admission.MaxActiveJobs)
+		assert.Equal(t, time.Minute, cfg.Admission.RetryDelay)
+		assert.Equal(t, 15*time.Minute, cfg.Admission.MaxRetryDelay)
+		assert.Equal(t, "docker.io/library/job-checker:latest", cfg.K8S.Job.ImageChecker)
+	})
+
+	t.Run("should load a JSON file", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		cfg, err := LoadLambdaConfigFrom("testdata/config.json")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "video-json", cfg.K8S.Namespace)
+	})
+
+	t.Run("should override the file with the environment", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		t.Setenv("K8S_NAMESPACE", "video-override")
+		t.Setenv("ADMISSION_MAX_ACTIVE_JOBS", "5")
+
+		// Act
+		cfg, err := LoadLambdaConfigFrom("testdata/config.yaml")
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "video-override", cfg.K8S.Namespace)
+		assert.Equal(t, 5, cfg.Admission.MaxActiveJobs)
+		assert.Equal(t, "video-processor:1.2.0", cfg.K8S.Job.Image)
+	})
+
+	t.Run("should reject unknown fields in the file", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		_, err := LoadLambdaConfigFrom("testdata/unknown_field.yaml")
+
+		// Assert
+		assert.ErrorContains(t, err, "namespce")
+	})
+
+	t.Run("should return an error when the file does not exist", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		_, err := LoadLambdaConfigFrom("testdata/missing.yaml")
+
+		// Assert
+		assert.ErrorContains(t, err, "error reading config file")
+	})
+
+	t.Run("should list the invalid durations of the file along with the other problems", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+
+		// Act
+		_, err := LoadLambdaConfigFrom("testdata/invalid.yaml")
+
+		// Assert
+		var validationErr *ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Problems, 4)
+		assert.Contains(t, validationErr.Problems[0], "k8s.job.ttlSecondsAfterFinished")
+		assert.Contains(t, validationErr.Problems[1], "admission.retryDelay")
+		assert.Contains(t, validationErr.Problems, "AWS_SNS_TOPIC_ARN is required")
+		assert.Contains(t, validationErr.Problems, "AWS_SQS_QUEUE_URL is required")
+	})
+
+	t.Run("should list every invalid variable instead of falling back to the default", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		setRequiredEnv(t)
+		t.Setenv("K8S_JOB_TTL_SECONDS_AFTER_FINISHED", "ten")
+		t.Setenv("K8S_JOB_BACK_OFF_LIMIT", "three")
+		t.Setenv("K8S_JOB_SUSPEND", "maybe")
+		t.Setenv("PRIORITY_USER_TIERS", "abc=premium")
+
+		// Act
+		cfg, err := LoadLambdaConfigFrom("")
+
+		// Assert
+		var validationErr *ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Len(t, validationErr.Problems, 4)
+		assert.ErrorContains(t, err, "K8S_JOB_TTL_SECONDS_AFTER_FINISHED")
+		assert.ErrorContains(t, err, "K8S_JOB_BACK_OFF_LIMIT")
+		assert.ErrorContains(t, err, "K8S_JOB_SUSPEND")
+		assert.ErrorContains(t, err, "PRIORITY_USER_TIERS")
+		assert.NotNil(t, cfg)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("should accept the defaults with the required settings", func(t *testing.T) {
+		// Arrange
+		cfg := defaultConfig()
+		cfg.AWS.SNS.TopicArn = "arn:aws:sns:us-east-1:123456789012:video-status"
+		cfg.AWS.SQS.QueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/uploads"
+
+		// Act
+		err := cfg.Validate()
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should accept priority queues instead of the queue URL", func(t *testing.T) {
+		// Arrange
+		cfg := defaultConfig()
+		cfg.AWS.SNS.TopicArn = "arn:aws:sns:us-east-1:123456789012:video-status"
+		cfg.Priority.Queues = []WeightedQueue{{URL: "https://sqs.us-east-1.amazonaws.com/123456789012/premium", Weight: 3}}
+
+		// Act
+		err := cfg.Validate()
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should accept a retry delay without upper bound", func(t *testing.T) {
+		// Arrange
+		cfg := defaultConfig()
+		cfg.AWS.SNS.TopicArn = "arn:aws:sns:us-east-1:123456789012:video-status"
+		cfg.AWS.SQS.QueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/uploads"
+		cfg.Admission.MaxRetryDelay = 0
+
+		// Act
+		err := cfg.Validate()
+
+		// Assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should require a dead-letter queue for the receive count threshold", func(t *testing.T) {
+		// Arrange
+		cfg := defaultConfig()
+		cfg.AWS.SNS.TopicArn = "arn:aws:sns:us-east-1:123456789012:video-status"
+		cfg.AWS.SQS.QueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/uploads"
+		cfg.DeadLetter.MaxReceiveCount = 5
+
+		// Act
+		err := cfg.Validate()
+
+		// Assert
+		var validationErr *ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []string{"AWS_SQS_DLQ_MAX_RECEIVE_COUNT requires AWS_SQS_DLQ_URL"}, validationErr.Problems)
+	})
+
+	t.Run("should list all problems", func(t *testing.T) {
+		// Arrange
+		cfg := defaultConfig()
+		cfg.AWS.SNS.TopicArn = "video-status"
+		cfg.K8S.Job.Image = ""
+		cfg.K8S.Job.TtlSecondsAfterFinished = -time.Second
+		cfg.K8S.Job.StartTimeout = -time.Second
+		cfg.AWS.SQS.MaxMessagesBatch = 20
+		cfg.Priority.UserTiers = map[int64]string{42: "gold"}
+		cfg.Admission.MaxRetryDelay = time.Second
+		cfg.HTTPAddr = "8080"
+		cfg.LogLevel = "verbose"
+		cfg.LogFormat = "xml"
+		cfg.OTLPEndpoint = "localhost:4318"
+		cfg.DeadLetter.QueueURL = "uploads-dlq"
+		cfg.DeadLetter.MaxReceiveCount = -1
+
+		// Act
+		err := cfg.Validate()
+
+		// Assert
+		var validationErr *ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		assert.ElementsMatch(t, []string{
+			"K8S_JOB_IMAGE is required",
+			"K8S_JOB_TTL_SECONDS_AFTER_FINISHED must not be negative, got -1s",
+			"K8S_JOB_START_TIMEOUT must not be negative, got -1s",
+			`AWS_SNS_TOPIC_ARN "video-status" is not a valid SNS topic ARN`,
+			"AWS_SQS_QUEUE_URL is required",
+			"SQS_MAX_MESSAGES_BATCH must be between 1 and 10, got 20",
+			`PRIORITY_USER_TIERS assigns user 42 to tier "gold", which has no K8S_JOB_TIER_GOLD_* template`,
+			"ADMISSION_MAX_RETRY_DELAY (1s) must not be shorter than ADMISSION_RETRY_DELAY (30s)",
+			`HTTP_ADDR must be a host:port address such as :8080, got "8080"`,
+			`LOG_LEVEL must be debug, info, warn or error, got "verbose"`,
+			`LOG_FORMAT must be json, text or pretty, got "xml"`,
+			`OTEL_EXPORTER_OTLP_ENDPOINT "localhost:4318" is not a valid http(s) URL`,
+			`AWS_SQS_DLQ_URL "uploads-dlq" is not a valid queue URL`,
+			"AWS_SQS_DLQ_MAX_RECEIVE_COUNT must not be negative, got -1",
+		}, validationErr.Problems)
+		assert.Contains(t, err.Error(), "invalid configuration:\n  - ")
+	})
+}
+
+func TestPrint(t *testing.T) {
+	t.Run("should print the configuration with secrets redacted", func(t *testing.T) {
+		// Arrange
+		cfg := defaultConfig()
+		cfg.AWS.AccessKey = "AKIAEXAMPLE"
+		cfg.AWS.SecretAccessKey = "secret-access-key"
+		cfg.K8S.Job.Envs["API_KEY"] = "api-key"
+		cfg.K8S.Job.Envs["OUTPUT_FORMAT"] = "zip"
+		var out bytes.Buffer
+
+		// Act
+		err := Print(&out, cfg)
+
+		// Assert
+		require.NoError(t, err)
+		assert.NotContains(t, out.String(), "AKIAEXAMPLE")
+		assert.NotContains(t, out.String(), "secret-access-key")
+		assert.NotContains(t, out.String(), "api-key")
+		assert.Contains(t, out.String(), "accessKeyId: <redacted>")
+		assert.Contains(t, out.String(), "OUTPUT_FORMAT: zip")
+		assert.Contains(t, out.String(), "ttlSecondsAfterFinished: 10s")
+		assert.Contains(t, out.String(), "startTimeout: 5m0s")
+	})
+
+	t.Run("should print a configuration that can be loaded back", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		cfg, err := LoadLambdaConfigFrom("testdata/config.yaml")
+		require.NoError(t, err)
+		var out bytes.Buffer
+		require.NoError(t, Print(&out, cfg))
+		path := t.TempDir() + "/printed.yaml"
+		require.NoError(t, os.WriteFile(path, out.Bytes(), 0o600))
+
+		// Act
+		reloaded, err := LoadLambdaConfigFrom(path)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, cfg.K8S, reloaded.K8S)
+		assert.Equal(t, cfg.Priority, reloaded.Priority)
+		assert.Equal(t, cfg.Admission, reloaded.Admission)
+	})
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envLoader overrides configuration values with the environment variables that are set,
+// collecting the variables that cannot be parsed instead of falling back to a default
+type envLoader struct {
+	problems []string
+}
+
+func (l *envLoader) invalid(key, value, kind string, err error) {
+	l.problems = append(l.problems, fmt.Sprintf("%s: %q is not a valid %s: %v", key, value, kind, err))
+}
+
+func (l *envLoader) string(key string, target *string) {
+	if value, ok := lookupEnv(key); ok {
+		*target = value
+	}
+}
+
+func (l *envLoader) int(key string, target *int) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		l.invalid(key, value, "integer", err)
+		return
+	}
+	*target = parsed
+}
+
+func (l *envLoader) int32(key string, target *int32) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		l.invalid(key, value, "integer", err)
+		return
+	}
+	*target = int32(parsed)
+}
+
+func (l *envLoader) int64(key string, target *int64) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		l.invalid(key, value, "integer", err)
+		return
+	}
+	*target = parsed
+}
+
+func (l *envLoader) bool(key string, target *bool) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		l.invalid(key, value, "boolean", err)
+		return
+	}
+	*target = parsed
+}
+
+func (l *envLoader) duration(key string, target *time.Duration) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		l.invalid(key, value, "duration", err)
+		return
+	}
+	*target = parsed
+}
+
+// envs adds the variables starting with prefix, without the prefix, to target
+func (l *envLoader) envs(prefix string, target map[string]string) {
+	for key, value := range getEnvsWithPrefix(prefix) {
+		target[key] = value
+	}
+}
+
+// jobTiers reads variables such as K8S_JOB_TIER_PREMIUM_PRIORITY_CLASS, K8S_JOB_TIER_PREMIUM_IMAGE
+// and K8S_JOB_TIER_PREMIUM_COMMAND into a map keyed by the lower case tier name
+func (l *envLoader) jobTiers(prefix string, target map[string]JobTier) {
+	for key, value := range getEnvsWithPrefix(prefix) {
+		var field string
+		for _, suffix := range []string{"_PRIORITY_CLASS", "_IMAGE", "_COMMAND"} {
+			if strings.HasSuffix(key, suffix) {
+				field = suffix
+				break
+			}
+		}
+		if field == "" {
+			l.problems = append(l.problems, fmt.Sprintf("%s%s: not a valid tier setting, expected a _PRIORITY_CLASS, _IMAGE or _COMMAND suffix", prefix, key))
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(key, field))
+		tier := target[name]
+		switch field {
+		case "_PRIORITY_CLASS":
+			tier.PriorityClassName = value
+		case "_IMAGE":
+			tier.Image = value
+		case "_COMMAND":
+			tier.Command = value
+		}
+		target[name] = tier
+	}
+}
+
+// userTiers reads a list such as "42=premium,7=premium" into a map of user ID to tier
+func (l *envLoader) userTiers(key string, target map[int64]string) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		userId, tier, found := strings.Cut(entry, "=")
+		if !found {
+			l.problems = append(l.problems, fmt.Sprintf("%s: %q is not a user_id=tier pair", key, entry))
+			continue
+		}
+		id, err := strconv.ParseInt(userId, 10, 64)
+		if err != nil {
+			l.invalid(key, userId, "user ID", err)
+			continue
+		}
+		target[id] = strings.ToLower(tier)
+	}
+}
+
+// weightedQueues reads a list such as "https://sqs/premium=3,https://sqs/standard=1", replacing
+// the queues of the file. A queue without weight gets weight 1.
+func (l *envLoader) weightedQueues(key string, target *[]WeightedQueue) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	queues := make([]WeightedQueue, 0)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		queue := WeightedQueue{URL: entry, Weight: 1}
+		if i := strings.LastIndex(entry, "="); i > 0 {
+			weight, err := strconv.Atoi(entry[i+1:])
+			if err != nil {
+				l.invalid(key, entry[i+1:], "weight", err)
+				continue
+			}
+			queue = WeightedQueue{URL: entry[:i], Weight: weight}
+		}
+		queues = append(queues, queue)
+	}
+	*target = queues
+}
+
+// lookupEnv returns the value of a variable. Blank variables are treated as unset so they
+// don't erase the value of the configuration file.
+func lookupEnv(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	return value, ok && strings.TrimSpace(value) != ""
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// redacted replaces secrets when the configuration is printed
+const redacted = "<redacted>"
+
+// fileConfig is the schema of the configuration file. Fields left out of the file keep their
+// default value, and durations are written as Go durations such as "30s" or "15m".
+type fileConfig struct {
+	Environment    *string         `json:"environment,omitempty"`
+	LogLevel       *string         `json:"logLevel,omitempty"`
+	LogFormat      *string         `json:"logFormat,omitempty"`
+	ReloadInterval *string         `json:"reloadInterval,omitempty"`
+	HTTPAddr       *string         `json:"httpAddr,omitempty"`
+	OTLPEndpoint   *string         `json:"otlpEndpoint,omitempty"`
+	K8S            *fileK8S        `json:"k8s,omitempty"`
+	Priority       *filePriority   `json:"priority,omitempty"`
+	Admission      *fileAdmission  `json:"admission,omitempty"`
+	DeadLetter     *fileDeadLetter `json:"deadLetter,omitempty"`
+	AWS            *fileAWS        `json:"aws,omitempty"`
+}
+
+type fileK8S struct {
+	Namespace          *string  `json:"namespace,omitempty"`
+	ContextName        *string  `json:"contextName,omitempty"`
+	MasterUrl          *string  `json:"masterUrl,omitempty"`
+	ServiceAccountName *string  `json:"serviceAccountName,omitempty"`
+	Job                *fileJob `json:"job,omitempty"`
+}
+
+type fileJob struct {
+	Prefix                  *string           `json:"prefix,omitempty"`
+	Image                   *string           `json:"image,omitempty"`
+	Command                 *string           `json:"command,omitempty"`
+	Envs                    map[string]string `json:"envs,omitempty"`
+	TtlSecondsAfterFinished *string           `json:"ttlSecondsAfterFinished,omitempty"`
+	StartTimeout            *string           `json:"startTimeout,omitempty"`
+	BackOffLimit            *int32            `json:"backOffLimit,omitempty"`
+	ImageChecker            *string           `json:"imageChecker,omitempty"`
+	Suspend                 *bool             `json:"suspend,omitempty"`
+	QueueLabel              *string           `json:"queueLabel,omitempty"`
+	QueueName               *string           `json:"queueName,omitempty"`
+	Indexed                 *fileIndexed      `json:"indexed,omitempty"`
+}
+
+type fileIndexed struct {
+	Enabled        *bool  `json:"enabled,omitempty"`
+	ChunkSizeBytes *int64 `json:"chunkSizeBytes,omitempty"`
+	MaxCompletions *int32 `json:"maxCompletions,omitempty"`
+	Parallelism    *int32 `json:"parallelism,omitempty"`
+}
+
+type filePriority struct {
+	DefaultTier *string            `json:"defaultTier,omitempty"`
+	Tiers       map[string]JobTier `json:"tiers,omitempty"`
+	UserTiers   map[int64]string   `json:"userTiers,omitempty"`
+	Queues      []WeightedQueue    `json:"queues,omitempty"`
+}
+
+type fileAdmission struct {
+	MaxActiveJobs        *int    `json:"maxActiveJobs,omitempty"`
+	MaxActiveJobsPerUser *int    `json:"maxActiveJobsPerUser,omitempty"`
+	RetryDelay           *string `json:"retryDelay,omitempty"`
+	MaxRetryDelay        *string `json:"maxRetryDelay,omitempty"`
+}
+
+type fileDeadLetter struct {
+	QueueURL        *string `json:"queueUrl,omitempty"`
+	MaxReceiveCount *int    `json:"maxReceiveCount,omitempty"`
+}
+
+type fileAWS struct {
+	Region          *string  `json:"region,omitempty"`
+	AccessKey       *string  `json:"accessKeyId,omitempty"`
+	SecretAccessKey *string  `json:"secretAccessKey,omitempty"`
+	SessionToken    *string  `json:"sessionToken,omitempty"`
+	SNS             *fileSNS `json:"sns,omitempty"`
+	SQS             *fileSQS `json:"sqs,omitempty"`
+}
+
+type fileSNS struct {
+	TopicArn *string `json:"topicArn,omitempty"`
+}
+
+type fileSQS struct {
+	QueueURL         *string `json:"queueUrl,omitempty"`
+	WorkerPoolSize   *int    `json:"workerPoolSize,omitempty"`
+	MaxMessagesBatch *int    `json:"maxMessagesBatch,omitempty"`
+	WaitTimeSeconds  *int    `json:"waitTimeSeconds,omitempty"`
+}
+
+// loadFile applies a YAML or JSON configuration file over config and returns the values that
+// cannot be parsed. Unknown fields are rejected so that typos don't go unnoticed.
+func loadFile(path string, config *Config) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var file fileConfig
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	return file.apply(config), nil
+}
+
+// apply copies the values set in the file to config and returns the values that cannot be parsed
+func (f *fileConfig) apply(config *Config) []string {
+	var problems []string
+	setDuration := func(field string, value *string, target *time.Duration) {
+		if value == nil {
+			return
+		}
+		duration, err := time.ParseDuration(*value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %q is not a valid duration: %v", field, *value, err))
+			return
+		}
+		*target = duration
+	}
+
+	set(f.Environment, &config.Environment)
+	set(f.LogLevel, &config.LogLevel)
+	set(f.LogFormat, &config.LogFormat)
+	setDuration("reloadInterval", f.ReloadInterval, &config.ReloadInterval)
+	set(f.HTTPAddr, &config.HTTPAddr)
+	set(f.OTLPEndpoint, &config.OTLPEndpoint)
+
+	if k8s := f.K8S; k8s != nil {
+		set(k8s.Namespace, &config.K8S.Namespace)
+		set(k8s.ContextName, &config.K8S.ContextName)
+		set(k8s.MasterUrl, &config.K8S.MasterUrl)
+		set(k8s.ServiceAccountName, &config.K8S.ServiceAccountName)
+
+		if job := k8s.Job; job != nil {
+			set(job.Prefix, &config.K8S.Job.Prefix)
+			set(job.Image, &config.K8S.Job.Image)
+			set(job.Command, &config.K8S.Job.Command)
+			for key, value := range job.Envs {
+				config.K8S.Job.Envs[key] = value
+			}
+			setDuration("k8s.job.ttlSecondsAfterFinished", job.TtlSecondsAfterFinished, &config.K8S.Job.TtlSecondsAfterFinished)
+			setDuration("k8s.job.startTimeout", job.StartTimeout, &config.K8S.Job.StartTimeout)
+			set(job.BackOffLimit, &config.K8S.Job.BackOffLimit)
+			set(job.ImageChecker, &config.K8S.Job.ImageChecker)
+			set(job.Suspend, &config.K8S.Job.Suspend)
+			set(job.QueueLabel, &config.K8S.Job.QueueLabel)
+			set(job.QueueName, &config.K8S.Job.QueueName)
+
+			if indexed := job.Indexed; indexed != nil {
+				set(indexed.Enabled, &config.K8S.Job.Indexed.Enabled)
+				set(indexed.ChunkSizeBytes, &config.K8S.Job.Indexed.ChunkSizeBytes)
+				set(indexed.MaxCompletions, &config.K8S.Job.Indexed.MaxCompletions)
+				set(indexed.Parallelism, &config.K8S.Job.Indexed.Parallelism)
+			}
+		}
+	}
+
+	if priority := f.Priority; priority != nil {
+		set(priority.DefaultTier, &config.Priority.DefaultTier)
+		for name, tier := range priority.Tiers {
+			config.Priority.Tiers[strings.ToLower(name)] = tier
+		}
+		for userId, tier := range priority.UserTiers {
+			config.Priority.UserTiers[userId] = strings.ToLower(tier)
+		}
+		for _, queue := range priority.Queues {
+			if queue.Weight == 0 {
+				queue.Weight = 1
+			}
+			config.Priority.Queues = append(config.Priority.Queues, queue)
+		}
+	}
+
+	if admission := f.Admission; admission != nil {
+		set(admission.MaxActiveJobs, &config.Admission.MaxActiveJobs)
+		set(admission.MaxActiveJobsPerUser, &config.Admission.MaxActiveJobsPerUser)
+		setDuration("admission.retryDelay", admission.RetryDelay, &config.Admission.RetryDelay)
+		setDuration("admission.maxRetryDelay", admission.MaxRetryDelay, &config.Admission.MaxRetryDelay)
+	}
+
+	if deadLetter := f.DeadLetter; deadLetter != nil {
+		set(deadLetter.QueueURL, &config.DeadLetter.QueueURL)
+		set(deadLetter.MaxReceiveCount, &config.DeadLetter.MaxReceiveCount)
+	}
+
+	if aws := f.AWS; aws != nil {
+		set(aws.Region, &config.AWS.Region)
+		set(aws.AccessKey, &config.AWS.AccessKey)
+		set(aws.SecretAccessKey, &config.AWS.SecretAccessKey)
+		set(aws.SessionToken, &config.AWS.SessionToken)
+		if aws.SNS != nil {
+			set(aws.SNS.TopicArn, &config.AWS.SNS.TopicArn)
+		}
+		if sqs := aws.SQS; sqs != nil {
+			set(sqs.QueueURL, &config.AWS.SQS.QueueURL)
+			set(sqs.WorkerPoolSize, &config.AWS.SQS.WorkerPoolSize)
+			set(sqs.MaxMessagesBatch, &config.AWS.SQS.MaxMessagesBatch)
+			set(sqs.WaitTimeSeconds, &config.AWS.SQS.WaitTimeSeconds)
+		}
+	}
+
+	return problems
+}
+
+func set[T any](value *T, target *T) {
+	if value != nil {
+		*target = *value
+	}
+}
+
+// Print writes the effective configuration as YAML, in the format of the configuration file,
+// with the AWS credentials and the job variables that look like secrets redacted
+func Print(w io.Writer, config *Config) error {
+	data, err := yaml.Marshal(toFile(config))
+	if err != nil {
+		return fmt.Errorf("error printing config: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// toFile converts the configuration to the file schema, with secrets redacted
+func toFile(config *Config) fileConfig {
+	secret := func(value string) *string {
+		if value == "" {
+			return &value
+		}
+		return ptr(redacted)
+	}
+	durationString := func(duration time.Duration) *string {
+		return ptr(duration.String())
+	}
+
+	return fileConfig{
+		Environment:    &config.Environment,
+		LogLevel:       &config.LogLevel,
+		LogFormat:      &config.LogFormat,
+		ReloadInterval: durationString(config.ReloadInterval),
+		HTTPAddr:       &config.HTTPAddr,
+		OTLPEndpoint:   &config.OTLPEndpoint,
+		K8S: &fileK8S{
+			Namespace:          &config.K8S.Namespace,
+			ContextName:        &config.K8S.ContextName,
+			MasterUrl:          &config.K8S.MasterUrl,
+			ServiceAccountName: &config.K8S.ServiceAccountName,
+			Job: &fileJob{
+				Prefix:                  &config.K8S.Job.Prefix,
+				Image:                   &config.K8S.Job.Image,
+				Command:                 &config.K8S.Job.Command,
+				Envs:                    redactEnvs(config.K8S.Job.Envs),
+				TtlSecondsAfterFinished: durationString(config.K8S.Job.TtlSecondsAfterFinished),
+				StartTimeout:            durationString(config.K8S.Job.StartTimeout),
+				BackOffLimit:            &config.K8S.Job.BackOffLimit,
+				ImageChecker:            &config.K8S.Job.ImageChecker,
+				Suspend:                 &config.K8S.Job.Suspend,
+				QueueLabel:              &config.K8S.Job.QueueLabel,
+				QueueName:               &config.K8S.Job.QueueName,
+				Indexed: &fileIndexed{
+					Enabled:        &config.K8S.Job.Indexed.Enabled,
+					ChunkSizeBytes: &config.K8S.Job.Indexed.ChunkSizeBytes,
+					MaxCompletions: &config.K8S.Job.Indexed.MaxCompletions,
+					Parallelism:    &config.K8S.Job.Indexed.Parallelism,
+				},
+			},
+		},
+		Priority: &filePriority{
+			DefaultTier: &config.Priority.DefaultTier,
+			Tiers:       config.Priority.Tiers,
+			UserTiers:   config.Priority.UserTiers,
+			Queues:      config.Priority.Queues,
+		},
+		Admission: &fileAdmission{
+			MaxActiveJobs:        &config.Admission.MaxActiveJobs,
+			MaxActiveJobsPerUser: &config.Admission.MaxActiveJobsPerUser,
+			RetryDelay:           durationString(config.Admission.RetryDelay),
+			MaxRetryDelay:        durationString(config.Admission.MaxRetryDelay),
+		},
+		DeadLetter: &fileDeadLetter{
+			QueueURL:        &config.DeadLetter.QueueURL,
+			MaxReceiveCount: &config.DeadLetter.MaxReceiveCount,
+		},
+		AWS: &fileAWS{
+			Region:          &config.AWS.Region,
+			AccessKey:       secret(config.AWS.AccessKey),
+			SecretAccessKey: secret(config.AWS.SecretAccessKey),
+			SessionToken:    secret(config.AWS.SessionToken),
+			SNS:             &fileSNS{TopicArn: &config.AWS.SNS.TopicArn},
+			SQS: &fileSQS{
+				QueueURL:         &config.AWS.SQS.QueueURL,
+				WorkerPoolSize:   &config.AWS.SQS.WorkerPoolSize,
+				MaxMessagesBatch: &config.AWS.SQS.MaxMessagesBatch,
+				WaitTimeSeconds:  &config.AWS.SQS.WaitTimeSeconds,
+			},
+		},
+	}
+}
+
+// redactEnvs hides the values of variables named like a secret
+func redactEnvs(envs map[string]string) map[string]string {
+	redactedEnvs := make(map[string]string, len(envs))
+	for key, value := range envs {
+		upperKey := strings.ToUpper(key)
+		for _, marker := range []string{"SECRET", "TOKEN", "PASSWORD", "KEY"} {
+			if strings.Contains(upperKey, marker) {
+				value = redacted
+				break
+			}
+		}
+		redactedEnvs[key] = value
+	}
+	return redactedEnvs
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// snsTopicArnPattern matches ARNs such as arn:aws:sns:us-east-1:123456789012:video-status
+var snsTopicArnPattern = regexp.MustCompile(`^arn:aws(-[a-z]+)*:sns:[a-z0-9-]+:\d{12}:[A-Za-z0-9_-]+(\.fifo)?$`)
+
+// ValidationError lists every problem found in the configuration, so that all of them can be
+// fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the configuration and returns a ValidationError listing every problem found
+func (c *Config) Validate() error {
+	if problems := c.validate(); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	required := func(name, value string) {
+		if strings.TrimSpace(value) == "" {
+			problem("%s is required", name)
+		}
+	}
+
+	if c.LogLevel != "" && !oneOf(strings.ToLower(c.LogLevel), "debug", "info", "warn", "error") {
+		problem("LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+	if c.LogFormat != "" && !oneOf(strings.ToLower(c.LogFormat), "json", "text", "pretty") {
+		problem("LOG_FORMAT must be json, text or pretty, got %q", c.LogFormat)
+	}
+	if c.ReloadInterval < 0 {
+		problem("CONFIG_RELOAD_INTERVAL must not be negative, got %s", c.ReloadInterval)
+	}
+	if c.HTTPAddr != "" {
+		if _, _, err := net.SplitHostPort(c.HTTPAddr); err != nil {
+			problem("HTTP_ADDR must be a host:port address such as :8080, got %q", c.HTTPAddr)
+		}
+	}
+	if c.OTLPEndpoint != "" && !isHTTPURL(c.OTLPEndpoint) {
+		problem("OTEL_EXPORTER_OTLP_ENDPOINT %q is not a valid http(s) URL", c.OTLPEndpoint)
+	}
+
+	// K8S Settings
+	required("K8S_NAMESPACE", c.K8S.Namespace)
+	required("K8S_JOB_PREFIX", c.K8S.Job.Prefix)
+	required("K8S_JOB_IMAGE", c.K8S.Job.Image)
+	required("K8S_JOB_IMAGE_CHECKER", c.K8S.Job.ImageChecker)
+	required("K8S_JOB_COMMAND", c.K8S.Job.Command)
+	if c.K8S.Job.TtlSecondsAfterFinished < 0 {
+		problem("K8S_JOB_TTL_SECONDS_AFTER_FINISHED must not be negative, got %s", c.K8S.Job.TtlSecondsAfterFinished)
+	}
+	if c.K8S.Job.StartTimeout < 0 {
+		problem("K8S_JOB_START_TIMEOUT must not be negative, got %s", c.K8S.Job.StartTimeout)
+	}
+	if c.K8S.Job.BackOffLimit < 0 {
+		problem("K8S_JOB_BACK_OFF_LIMIT must not be negative, got %d", c.K8S.Job.BackOffLimit)
+	}
+	if c.K8S.Job.Suspend && c.K8S.Job.QueueName != "" {
+		required("K8S_JOB_QUEUE_LABEL", c.K8S.Job.QueueLabel)
+	}
+	if indexed := c.K8S.Job.Indexed; indexed.Enabled {
+		if indexed.ChunkSizeBytes < 0 {
+			problem("K8S_JOB_INDEXED_CHUNK_SIZE_BYTES must not be negative, got %d", indexed.ChunkSizeBytes)
+		}
+		if indexed.MaxCompletions < 1 {
+			problem("K8S_JOB_INDEXED_MAX_COMPLETIONS must be at least 1, got %d", indexed.MaxCompletions)
+		}
+		if indexed.Parallelism < 0 {
+			problem("K8S_JOB_INDEXED_PARALLELISM must not be negative, got %d", indexed.Parallelism)
+		}
+	}
+
+	// AWS Settings
+	required("AWS_REGION", c.AWS.Region)
+	if c.AWS.SNS.TopicArn == "" {
+		problem("AWS_SNS_TOPIC_ARN is required")
+	} else if !snsTopicArnPattern.MatchString(c.AWS.SNS.TopicArn) {
+		problem("AWS_SNS_TOPIC_ARN %q is not a valid SNS topic ARN", c.AWS.SNS.TopicArn)
+	}
+	if c.AWS.SQS.QueueURL == "" && len(c.Priority.Queues) == 0 {
+		problem("AWS_SQS_QUEUE_URL is required")
+	} else if c.AWS.SQS.QueueURL != "" && !isHTTPURL(c.AWS.SQS.QueueURL) {
+		problem("AWS_SQS_QUEUE_URL %q is not a valid queue URL", c.AWS.SQS.QueueURL)
+	}
+	if batch := c.AWS.SQS.MaxMessagesBatch; batch < 1 || batch > 10 {
+		problem("SQS_MAX_MESSAGES_BATCH must be between 1 and 10, got %d", batch)
+	}
+	if wait := c.AWS.SQS.WaitTimeSeconds; wait < 0 || wait > 20 {
+		problem("SQS_WAIT_TIME_SECONDS must be between 0 and 20, got %d", wait)
+	}
+	if c.AWS.SQS.WorkerPoolSize < 1 {
+		problem("SQS_WORKER_POOL_SIZE must be at least 1, got %d", c.AWS.SQS.WorkerPoolSize)
+	}
+
+	// Priority settings
+	required("PRIORITY_DEFAULT_TIER", c.Priority.DefaultTier)
+	for userId, tier := range c.Priority.UserTiers {
+		if _, ok := c.Priority.Tiers[tier]; !ok && tier != c.Priority.DefaultTier {
+			problem("PRIORITY_USER_TIERS assigns user %d to tier %q, which has no K8S_JOB_TIER_%s_* template", userId, tier, strings.ToUpper(tier))
+		}
+	}
+	for _, queue := range c.Priority.Queues {
+		if !isHTTPURL(queue.URL) {
+			problem("AWS_SQS_PRIORITY_QUEUES has an invalid queue URL %q", queue.URL)
+		}
+		if queue.Weight < 1 {
+			problem("AWS_SQS_PRIORITY_QUEUES weight of %s must be at least 1, got %d", queue.URL, queue.Weight)
+		}
+	}
+
+	// Admission settings
+	if c.Admission.MaxActiveJobs < 0 {
+		problem("ADMISSION_MAX_ACTIVE_JOBS must not be negative, got %d", c.Admission.MaxActiveJobs)
+	}
+	if c.Admission.MaxActiveJobsPerUser < 0 {
+		problem("ADMISSION_MAX_ACTIVE_JOBS_PER_USER must not be negative, got %d", c.Admission.MaxActiveJobsPerUser)
+	}
+	if c.Admission.RetryDelay <= 0 {
+		problem("ADMISSION_RETRY_DELAY must be positive, got %s", c.Admission.RetryDelay)
+	}
+	// Zero leaves the retry delay without an upper bound
+	if c.Admission.MaxRetryDelay < 0 {
+		problem("ADMISSION_MAX_RETRY_DELAY must not be negative, got %s", c.Admission.MaxRetryDelay)
+	} else if c.Admission.MaxRetryDelay > 0 && c.Admission.MaxRetryDelay < c.Admission.RetryDelay {
+		problem("ADMISSION_MAX_RETRY_DELAY (%s) must not be shorter than ADMISSION_RETRY_DELAY (%s)", c.Admission.MaxRetryDelay, c.Admission.RetryDelay)
+	}
+
+	// Dead-letter settings
+	if c.DeadLetter.QueueURL != "" && !isHTTPURL(c.DeadLetter.QueueURL) {
+		problem("AWS_SQS_DLQ_URL %q is not a valid queue URL", c.DeadLetter.QueueURL)
+	}
+	if c.DeadLetter.MaxReceiveCount < 0 {
+		problem("AWS_SQS_DLQ_MAX_RECEIVE_COUNT must not be negative, got %d", c.DeadLetter.MaxReceiveCount)
+	} else if c.DeadLetter.MaxReceiveCount > 0 && c.DeadLetter.QueueURL == "" {
+		problem("AWS_SQS_DLQ_MAX_RECEIVE_COUNT requires AWS_SQS_DLQ_URL")
+	}
+
+	return problems
+}
+
+// isHTTPURL tells if value is an absolute http(s) URL, such as the URLs returned by SQS GetQueueUrl
+func isHTTPURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
+
+func oneOf(value string, allowed ...string) bool {
+	return slices.Contains(allowed, value)
+}
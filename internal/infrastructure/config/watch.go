@@ -0,0 +1,148 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileWatcher reloads the configuration when the content of the configuration file changes.
+// ConfigMap volumes are updated through a symlink swap, so the content is compared instead of
+// relying on file events.
+type FileWatcher struct {
+	path     string
+	checksum [sha256.Size]byte
+}
+
+// NewFileWatcher takes the current content of the file as the reference, so it should be
+// created right after the configuration is loaded
+func NewFileWatcher(path string) (*FileWatcher, error) {
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileWatcher{path: path, checksum: checksum}, nil
+}
+
+// Watch checks the file every interval and calls onChange with the new configuration, environment
+// overrides included, when the content changed. Unreadable files and invalid configurations are
+// reported to onError and skipped, keeping the configuration in use. Watch blocks until ctx is done.
+func (w *FileWatcher) Watch(ctx context.Context, interval time.Duration, onChange func(*Config), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checksum, err := fileChecksum(w.path)
+		if err != nil {
+			onError(err)
+			continue
+		}
+		if checksum == w.checksum {
+			continue
+		}
+		w.checksum = checksum
+
+		config, err := LoadLambdaConfigFrom(w.path)
+		if err != nil {
+			onError(err)
+			continue
+		}
+		onChange(config)
+	}
+}
+
+func fileChecksum(path string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("error reading config file: %w", err)
+	}
+	return sha256.Sum256(data), nil
+}
+
+// Diff lists the settings that differ between two configurations, as "path: old -> new" using
+// the field names of the configuration file. Secrets are compared redacted.
+func Diff(previous, current *Config) []string {
+	before, after := flatten(toFile(previous)), flatten(toFile(current))
+
+	changes := make([]string, 0)
+	for key, value := range after {
+		if old, ok := before[key]; !ok {
+			changes = append(changes, fmt.Sprintf("%s: <unset> -> %s", key, value))
+		} else if old != value {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", key, old, value))
+		}
+	}
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, fmt.Sprintf("%s: %s -> <unset>", key, value))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// startupSettings are the prefixes of the changes listed by Diff to the settings only applied on
+// startup, such as the AWS clients and the queues polled
+var startupSettings = []string{"environment:", "logLevel:", "logFormat:", "reloadInterval:", "httpAddr:", "otlpEndpoint:", "aws.", "deadLetter.", "priority.queues:", "k8s.contextName:", "k8s.masterUrl:"}
+
+// RequiresRestart tells if a change listed by Diff is only applied on startup
+func RequiresRestart(change string) bool {
+	for _, prefix := range startupSettings {
+		if strings.HasPrefix(change, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeepStartupSettings returns a copy of current with the settings only applied on startup taken
+// from previous, so a reload doesn't hand new AWS values or queues to the jobs while the clients
+// and pollers still use the previous ones
+func KeepStartupSettings(previous, current *Config) *Config {
+	kept := *current
+	kept.Environment = previous.Environment
+	kept.LogLevel = previous.LogLevel
+	kept.LogFormat = previous.LogFormat
+	kept.ReloadInterval = previous.ReloadInterval
+	kept.HTTPAddr = previous.HTTPAddr
+	kept.OTLPEndpoint = previous.OTLPEndpoint
+	kept.AWS = previous.AWS
+	kept.DeadLetter = previous.DeadLetter
+	kept.Priority.Queues = previous.Priority.Queues
+	kept.K8S.ContextName = previous.K8S.ContextName
+	kept.K8S.MasterUrl = previous.K8S.MasterUrl
+	return &kept
+}
+
+// flatten maps each leaf of the file schema to its dotted path, e.g. k8s.job.image
+func flatten(file fileConfig) map[string]string {
+	data, _ := json.Marshal(file)
+	var tree map[string]any
+	_ = json.Unmarshal(data, &tree)
+
+	leaves := make(map[string]string)
+	var walk func(prefix string, node any)
+	walk = func(prefix string, node any) {
+		if children, ok := node.(map[string]any); ok {
+			for key, child := range children {
+				walk(prefix+"."+key, child)
+			}
+			return
+		}
+		value, _ := json.Marshal(node)
+		leaves[prefix[1:]] = string(value)
+	}
+	walk("", tree)
+	return leaves
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watchedConfig = `
+k8s:
+  job:
+    image: %s
+aws:
+  sns:
+    topicArn: arn:aws:sns:us-east-1:123456789012:video-status
+  sqs:
+    queueUrl: https://sqs.us-east-1.amazonaws.com/123456789012/uploads
+`
+
+func TestFileWatcher(t *testing.T) {
+	t.Run("should report the new configuration when the file changes", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, fmt.Appendf(nil, watchedConfig, "video-processor:1.0.0"), 0o600))
+		changes := make(chan *Config, 1)
+		errs := make(chan error, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watcher, err := NewFileWatcher(path)
+		require.NoError(t, err)
+		go watcher.Watch(ctx, 5*time.Millisecond, func(cfg *Config) { changes <- cfg }, func(err error) { errs <- err })
+
+		// Act
+		require.NoError(t, os.WriteFile(path, fmt.Appendf(nil, watchedConfig, "video-processor:2.0.0"), 0o600))
+
+		// Assert
+		select {
+		case cfg := <-changes:
+			assert.Equal(t, "video-processor:2.0.0", cfg.K8S.Job.Image)
+		case err := <-errs:
+			t.Fatalf("unexpected error: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("configuration change was not reported")
+		}
+	})
+
+	t.Run("should return an error when the file does not exist", func(t *testing.T) {
+		// Act
+		_, err := NewFileWatcher(filepath.Join(t.TempDir(), "missing.yaml"))
+
+		// Assert
+		assert.ErrorContains(t, err, "error reading config file")
+	})
+
+	t.Run("should report an invalid configuration as an error", func(t *testing.T) {
+		// Arrange
+		clearEnv(t)
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, fmt.Appendf(nil, watchedConfig, "video-processor:1.0.0"), 0o600))
+		changes := make(chan *Config, 1)
+		errs := make(chan error, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watcher, err := NewFileWatcher(path)
+		require.NoError(t, err)
+		go watcher.Watch(ctx, 5*time.Millisecond, func(cfg *Config) { changes <- cfg }, func(err error) { errs <- err })
+
+		// Act
+		require.NoError(t, os.WriteFile(path, fmt.Appendf(nil, watchedConfig, `""`), 0o600))
+
+		// Assert
+		select {
+		case cfg := <-changes:
+			t.Fatalf("unexpected configuration: %v", cfg)
+		case err := <-errs:
+			assert.ErrorContains(t, err, "K8S_JOB_IMAGE is required")
+		case <-time.After(5 * time.Second):
+			t.Fatal("invalid configuration was not reported")
+		}
+	})
+}
+
+func TestDiff(t *testing.T) {
+	t.Run("should list the changed settings", func(t *testing.T) {
+		// Arrange
+		previous := defaultConfig()
+		current := defaultConfig()
+		current.K8S.Job.Image = "video-processor:2.0.0"
+		current.Admission.MaxActiveJobs = 5
+		current.Priority.Tiers["premium"] = JobTier{PriorityClassName: "video-premium"}
+
+		// Act
+		changes := Diff(previous, current)
+
+		// Assert
+		assert.Equal(t, []string{
+			`admission.maxActiveJobs: 0 -> 5`,
+			`k8s.job.image: "ghcr.io/fiap-soat-g20/hackathon-job-starter-lambda:latest" -> "video-processor:2.0.0"`,
+			`priority.tiers.premium.priorityClassName: <unset> -> "video-premium"`,
+		}, changes)
+	})
+
+	t.Run("should not reveal secrets", func(t *testing.T) {
+		// Arrange
+		previous := defaultConfig()
+		previous.AWS.SecretAccessKey = "old-secret"
+		current := defaultConfig()
+		current.AWS.SecretAccessKey = "new-secret"
+
+		// Act
+		changes := Diff(previous, current)
+
+		// Assert
+		assert.Empty(t, changes)
+	})
+
+	t.Run("should return no changes for the same configuration", func(t *testing.T) {
+		// Act
+		changes := Diff(defaultConfig(), defaultConfig())
+
+		// Assert
+		assert.Empty(t, changes)
+	})
+}
+
+func TestRequiresRestart(t *testing.T) {
+	t.Run("should require a restart for the settings applied on startup", func(t *testing.T) {
+		assert.True(t, RequiresRestart(`aws.sns.topicArn: "old" -> "new"`))
+		assert.True(t, RequiresRestart(`priority.queues: [] -> ["premium"]`))
+		assert.True(t, RequiresRestart(`logLevel: "info" -> "debug"`))
+	})
+
+	t.Run("should not require a restart for the job settings", func(t *testing.T) {
+		assert.False(t, RequiresRestart(`k8s.job.image: "old" -> "new"`))
+		assert.False(t, RequiresRestart(`priority.tiers.premium.image: <unset> -> "new"`))
+	})
+}
+
+func TestKeepStartupSettings(t *testing.T) {
+	t.Run("should keep the startup settings and take the others from the new configuration", func(t *testing.T) {
+		// Arrange
+		previous := defaultConfig()
+		previous.AWS.Region = "us-east-1"
+		previous.AWS.SNS.TopicArn = "arn:aws:sns:us-east-1:123456789012:video-status"
+		previous.LogLevel = "info"
+		current := defaultConfig()
+		current.AWS.Region = "sa-east-1"
+		current.AWS.SNS.TopicArn = "arn:aws:sns:sa-east-1:123456789012:video-status"
+		current.LogLevel = "debug"
+		current.Priority.Queues = []WeightedQueue{{URL: "https://sqs.sa-east-1.amazonaws.com/123456789012/premium", Weight: 3}}
+		current.K8S.Job.Image = "video-processor:2.0.0"
+
+		// Act
+		kept := KeepStartupSettings(previous, current)
+
+		// Assert
+		assert.Equal(t, []string{
+			`k8s.job.image: "ghcr.io/fiap-soat-g20/hackathon-job-starter-lambda:latest" -> "video-processor:2.0.0"`,
+		}, Diff(previous, kept))
+		assert.Equal(t, "sa-east-1", current.AWS.Region)
+	})
+}
@@ -0,0 +1,287 @@
+package dlq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sqs"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/starter"
+)
+
+const (
+	// receiveBatch is the largest number of messages SQS returns per receive
+	receiveBatch = 10
+	// receiveWaitSeconds is long enough for a receive to find the messages of a small queue,
+	// which short polling may miss
+	receiveWaitSeconds = 1
+)
+
+// failureAttributes are added by the SqsHandler when forwarding a message, and left out when
+// the message is redriven
+var failureAttributes = []string{
+	sqs.AttributeFailureReason,
+	sqs.AttributeFailureCode,
+	sqs.AttributeSourceQueue,
+	sqs.AttributeReceiveCount,
+	sqs.AttributeJobName,
+	sqs.AttributeGroupId,
+}
+
+// Message is a message of the dead-letter queue, with its failure attributes and its
+// decoded body
+type Message struct {
+	MessageId     string            `json:"messageId"`
+	Body          string            `json:"body"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+	FailureCode   string            `json:"failureCode,omitempty"`
+	FailureReason string            `json:"failureReason,omitempty"`
+	SourceQueue   string            `json:"sourceQueue,omitempty"`
+	JobName       string            `json:"jobName,omitempty"`
+	ReceiveCount  int               `json:"receiveCount,omitempty"`
+	// GroupId is the group of the message in the queue it came from, when a FIFO queue
+	GroupId string `json:"groupId,omitempty"`
+
+	// Action is the action of a control message, empty for an S3 event
+	Action    string `json:"action,omitempty"`
+	EventName string `json:"eventName,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	Key       string `json:"key,omitempty"`
+	VideoId   int64  `json:"videoId,omitempty"`
+	UserId    int64  `json:"userId,omitempty"`
+
+	receiptHandle string
+}
+
+// Filter selects messages. Zero fields match any message.
+type Filter struct {
+	VideoId int64
+	UserId  int64
+	Code    string
+}
+
+// Match tells if the message is selected by the filter
+func (f Filter) Match(message Message) bool {
+	return (f.VideoId == 0 || f.VideoId == message.VideoId) &&
+		(f.UserId == 0 || f.UserId == message.UserId) &&
+		(f.Code == "" || f.Code == message.FailureCode)
+}
+
+// Inspector lists, redrives and discards the messages of a dead-letter queue. The messages
+// are received, so they stay invisible to other consumers until released at the end of
+// each operation.
+type Inspector struct {
+	queue    sqs.MessageClient
+	queueURL string
+	objects  port.ObjectGateway
+}
+
+// NewInspector creates an inspector of the queue at queueURL. When objects is not nil, the
+// video and user of the S3 events are read from the metadata of their object.
+func NewInspector(queue sqs.MessageClient, queueURL string, objects port.ObjectGateway) *Inspector {
+	return &Inspector{
+		queue:    queue,
+		queueURL: queueURL,
+		objects:  objects,
+	}
+}
+
+// List returns the messages selected by the filter among the first maxMessages messages of the queue
+func (i *Inspector) List(ctx context.Context, filter Filter, maxMessages int) ([]Message, error) {
+	messages, err := i.receive(ctx, maxMessages)
+	defer i.release(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []Message
+	for _, message := range messages {
+		if filter.Match(message) {
+			selected = append(selected, message)
+		}
+	}
+	return selected, nil
+}
+
+// Redrive sends the selected messages back to target, or to the queue they came from when
+// target is empty, without their failure attributes, and deletes them from the dead-letter
+// queue. A dry run only returns the messages that would be redriven.
+func (i *Inspector) Redrive(ctx context.Context, filter Filter, maxMessages int, target string, dryRun bool) ([]Message, error) {
+	return i.apply(ctx, filter, maxMessages, dryRun, func(message Message) error {
+		queueURL := target
+		if queueURL == "" {
+			queueURL = message.SourceQueue
+		}
+		if queueURL == "" {
+			return fmt.Errorf("message %s has no source queue, a target queue is required", message.MessageId)
+		}
+		if err := i.send(ctx, queueURL, message); err != nil {
+			return err
+		}
+		return i.queue.DeleteMessage(ctx, i.queueURL, message.receiptHandle)
+	})
+}
+
+// send sends a message back without its failure attributes. A FIFO queue receives it in its
+// original group, or in a group of its own when it came from a standard queue. Its deduplication
+// ID is new on every redrive, as the queue would drop a message redriven again within the
+// deduplication interval while it is deleted from the dead-letter queue.
+func (i *Inspector) send(ctx context.Context, queueURL string, message Message) error {
+	attributes := redriveAttributes(message.Attributes)
+	if !sqs.IsFifo(queueURL) {
+		_, err := i.queue.SendMessage(ctx, queueURL, message.Body, attributes)
+		return err
+	}
+
+	groupId := message.GroupId
+	if groupId == "" {
+		groupId = message.MessageId
+	}
+	deduplicationId := message.MessageId + "-" + rand.Text()
+	_, err := i.queue.SendGroupMessage(ctx, queueURL, groupId, deduplicationId, message.Body, attributes)
+	return err
+}
+
+// Discard deletes the selected messages. A dry run only returns the messages that would be
+// deleted.
+func (i *Inspector) Discard(ctx context.Context, filter Filter, maxMessages int, dryRun bool) ([]Message, error) {
+	return i.apply(ctx, filter, maxMessages, dryRun, func(message Message) error {
+		return i.queue.DeleteMessage(ctx, i.queueURL, message.receiptHandle)
+	})
+}
+
+// apply runs action on the selected messages and releases the others. It returns the
+// messages action succeeded on, and the errors of the others.
+func (i *Inspector) apply(ctx context.Context, filter Filter, maxMessages int, dryRun bool, action func(Message) error) ([]Message, error) {
+	messages, err := i.receive(ctx, maxMessages)
+	if err != nil {
+		i.release(ctx, messages)
+		return nil, err
+	}
+
+	var done, left []Message
+	var errs []error
+	for _, message := range messages {
+		switch {
+		case !filter.Match(message):
+			left = append(left, message)
+		case dryRun:
+			done = append(done, message)
+			left = append(left, message)
+		default:
+			if err := action(message); err != nil {
+				errs = append(errs, fmt.Errorf("message %s: %w", message.MessageId, err))
+				left = append(left, message)
+				continue
+			}
+			done = append(done, message)
+		}
+	}
+	i.release(ctx, left)
+	return done, errors.Join(errs...)
+}
+
+// receive receives messages until maxMessages were received or the queue looks empty
+func (i *Inspector) receive(ctx context.Context, maxMessages int) ([]Message, error) {
+	var messages []Message
+	for len(messages) < maxMessages {
+		received, err := i.queue.ReceiveMessages(ctx, i.queueURL, min(receiveBatch, maxMessages-len(messages)), receiveWaitSeconds)
+		if err != nil {
+			return messages, err
+		}
+		if len(received) == 0 {
+			break
+		}
+		for _, message := range received {
+			messages = append(messages, i.decode(ctx, message))
+		}
+	}
+	return messages, nil
+}
+
+// release makes the messages visible again right away
+func (i *Inspector) release(ctx context.Context, messages []Message) {
+	if len(messages) == 0 {
+		return
+	}
+	receiptHandles := make([]string, len(messages))
+	for j, message := range messages {
+		receiptHandles[j] = message.receiptHandle
+	}
+	// The messages become visible anyway once their visibility timeout expires
+	_, _ = i.queue.ChangeMessageVisibilityBatch(ctx, i.queueURL, receiptHandles, 0)
+}
+
+// decode reads the failure attributes and the body of a message
+func (i *Inspector) decode(ctx context.Context, received types.Message) Message {
+	attributes := make(map[string]string, len(received.MessageAttributes))
+	for name, attribute := range received.MessageAttributes {
+		if attribute.StringValue != nil {
+			attributes[name] = *attribute.StringValue
+		}
+	}
+	receiveCount, _ := strconv.Atoi(attributes[sqs.AttributeReceiveCount])
+
+	message := Message{
+		MessageId:     aws.ToString(received.MessageId),
+		Body:          aws.ToString(received.Body),
+		Attributes:    attributes,
+		FailureCode:   attributes[sqs.AttributeFailureCode],
+		FailureReason: attributes[sqs.AttributeFailureReason],
+		SourceQueue:   attributes[sqs.AttributeSourceQueue],
+		JobName:       attributes[sqs.AttributeJobName],
+		ReceiveCount:  receiveCount,
+		GroupId:       attributes[sqs.AttributeGroupId],
+		receiptHandle: aws.ToString(received.ReceiptHandle),
+	}
+
+	var control starter.ControlMessage
+	if err := json.Unmarshal([]byte(message.Body), &control); err == nil && control.Action != "" {
+		message.Action = control.Action
+		message.VideoId = control.VideoId
+		message.UserId = control.UserId
+		message.Bucket = control.Bucket
+		message.Key = control.Key
+		return message
+	}
+
+	var event starter.S3Event
+	if err := json.Unmarshal([]byte(message.Body), &event); err != nil || len(event.Records) == 0 {
+		return message
+	}
+	record := event.Records[0]
+	message.EventName = record.EventName
+	message.Bucket = record.S3.Bucket.Name
+	message.Key = record.S3.Object.Key
+	if key, err := record.S3.Object.DecodedKey(); err == nil {
+		message.Key = key
+	}
+
+	// The video and the user are only known from the metadata of the object, which may be gone
+	if i.objects != nil {
+		if info, err := i.objects.GetObjectInfo(ctx, message.Bucket, message.Key, record.S3.Object.VersionId); err == nil {
+			message.VideoId, _ = strconv.ParseInt(info.Metadata["video-id"], 10, 64)
+			message.UserId, _ = strconv.ParseInt(info.Metadata["user-id"], 10, 64)
+		}
+	}
+	return message
+}
+
+// redriveAttributes returns the attributes of the message without the ones describing its failure
+func redriveAttributes(attributes map[string]string) map[string]string {
+	redriven := make(map[string]string, len(attributes))
+	for name, value := range attributes {
+		redriven[name] = value
+	}
+	for _, name := range failureAttributes {
+		delete(redriven, name)
+	}
+	return redriven
+}
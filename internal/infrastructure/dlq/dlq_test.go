@@ -0,0 +1,430 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sqs"
+)
+
+const (
+	testDLQURL   = "https://sqs.us-east-1.amazonaws.com/000000000000/uploads-dlq"
+	testQueueURL = "https://sqs.us-east-1.amazonaws.com/000000000000/uploads"
+	testBucket   = "test-bucket"
+)
+
+// fakeQueues keeps the messages of several queues in memory. Received messages stay
+// invisible until their visibility is changed.
+type fakeQueues struct {
+	nextId    int
+	messages  map[string][]types.Message
+	invisible map[string]bool
+	sendErr   error
+}
+
+func newFakeQueues() *fakeQueues {
+	return &fakeQueues{
+		messages:  make(map[string][]types.Message),
+		invisible: make(map[string]bool),
+	}
+}
+
+func (f *fakeQueues) ReceiveMessages(ctx context.Context, queueURL string, maxMessages int, waitTimeSeconds int) ([]types.Message, error) {
+	var received []types.Message
+	for _, message := range f.messages[queueURL] {
+		if len(received) == maxMessages {
+			break
+		}
+		if f.invisible[*message.ReceiptHandle] {
+			continue
+		}
+		f.invisible[*message.ReceiptHandle] = true
+		received = append(received, message)
+	}
+	return received, nil
+}
+
+func (f *fakeQueues) DeleteMessage(ctx context.Context, queueURL string, receiptHandle string) error {
+	for i, message := range f.messages[queueURL] {
+		if *message.ReceiptHandle == receiptHandle {
+			f.messages[queueURL] = append(f.messages[queueURL][:i], f.messages[queueURL][i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("receipt handle %s not found", receiptHandle)
+}
+
+func (f *fakeQueues) ChangeMessageVisibility(ctx context.Context, queueURL string, receiptHandle string, visibilityTimeout int) error {
+	f.invisible[receiptHandle] = visibilityTimeout > 0
+	return nil
+}
+
+func (f *fakeQueues) DeleteMessageBatch(ctx context.Context, queueURL string, receiptHandles []string) ([]sqs.BatchEntryError, error) {
+	var failed []sqs.BatchEntryError
+	for _, receiptHandle := range receiptHandles {
+		if err := f.DeleteMessage(ctx, queueURL, receiptHandle); err != nil {
+			failed = append(failed, sqs.BatchEntryError{ReceiptHandle: receiptHandle, Code: "ReceiptHandleIsInvalid", Message: err.Error()})
+		}
+	}
+	return failed, nil
+}
+
+func (f *fakeQueues) ChangeMessageVisibilityBatch(ctx context.Context, queueURL string, receiptHandles []string, visibilityTimeout int) ([]sqs.BatchEntryError, error) {
+	for _, receiptHandle := range receiptHandles {
+		f.invisible[receiptHandle] = visibilityTimeout > 0
+	}
+	return nil, nil
+}
+
+func (f *fakeQueues) SendMessage(ctx context.Context, queueURL string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	if f.sendErr != nil {
+		return nil, f.sendErr
+	}
+
+	messageAttributes := make(map[string]types.MessageAttributeValue, len(attributes))
+	for name, value := range attributes {
+		messageAttributes[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+	f.nextId++
+	id := fmt.Sprintf("message-%d", f.nextId)
+	message := types.Message{
+		MessageId:         aws.String(id),
+		ReceiptHandle:     aws.String("receipt-" + id),
+		Body:              aws.String(messageBody),
+		MessageAttributes: messageAttributes,
+	}
+	f.messages[queueURL] = append(f.messages[queueURL], message)
+	return &message, nil
+}
+
+func (f *fakeQueues) SendGroupMessage(ctx context.Context, queueURL string, groupId string, deduplicationId string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	message, err := f.SendMessage(ctx, queueURL, messageBody, attributes)
+	if err != nil {
+		return nil, err
+	}
+	sent := &f.messages[queueURL][len(f.messages[queueURL])-1]
+	sent.Attributes = map[string]string{
+		string(types.MessageSystemAttributeNameMessageGroupId):         groupId,
+		string(types.MessageSystemAttributeNameMessageDeduplicationId): deduplicationId,
+	}
+	message.Attributes = sent.Attributes
+	return message, nil
+}
+
+// deadLetter adds a message forwarded by the SqsHandler to the dead-letter queue
+func (f *fakeQueues) deadLetter(body string, code string) string {
+	message, _ := f.SendMessage(context.Background(), testDLQURL, body, map[string]string{
+		sqs.AttributeFailureCode:   code,
+		sqs.AttributeFailureReason: "failed",
+		sqs.AttributeSourceQueue:   testQueueURL,
+		sqs.AttributeReceiveCount:  "5",
+		sqs.AttributeJobName:       "video-processor-movie",
+		"traceparent":              "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	return *message.MessageId
+}
+
+// fakeObjects serves the metadata of the uploaded objects by key
+type fakeObjects map[string]map[string]string
+
+func (f fakeObjects) GetObjectInfo(ctx context.Context, bucket string, key string, versionId string) (*dto.ObjectInfo, error) {
+	metadata, ok := f[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &dto.ObjectInfo{Metadata: metadata}, nil
+}
+
+func s3Event(key string) string {
+	return fmt.Sprintf(`{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":%q},"object":{"key":%q}}}]}`, testBucket, key)
+}
+
+func newTestInspector(queues *fakeQueues) *Inspector {
+	return NewInspector(queues, testDLQURL, fakeObjects{
+		"movie.mp4":   {"video-id": "42", "user-id": "7"},
+		"clip.mp4":    {"video-id": "43", "user-id": "8"},
+		"my clip.mp4": {"video-id": "44", "user-id": "9"},
+	})
+}
+
+func TestInspector_List(t *testing.T) {
+	t.Run("should decode the S3 events and their failure attributes", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		id := queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		inspector := newTestInspector(queues)
+
+		// Act
+		messages, err := inspector.List(context.Background(), Filter{}, 100)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		message := messages[0]
+		assert.Equal(t, id, message.MessageId)
+		assert.Equal(t, "JOB_START_TIMEOUT", message.FailureCode)
+		assert.Equal(t, "failed", message.FailureReason)
+		assert.Equal(t, testQueueURL, message.SourceQueue)
+		assert.Equal(t, "video-processor-movie", message.JobName)
+		assert.Equal(t, 5, message.ReceiveCount)
+		assert.Equal(t, "ObjectCreated:Put", message.EventName)
+		assert.Equal(t, testBucket, message.Bucket)
+		assert.Equal(t, "movie.mp4", message.Key)
+		assert.Equal(t, int64(42), message.VideoId)
+		assert.Equal(t, int64(7), message.UserId)
+	})
+
+	t.Run("should decode the key of the S3 events", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(s3Event("my+clip.mp4"), "JOB_START_TIMEOUT")
+		inspector := newTestInspector(queues)
+
+		// Act
+		messages, err := inspector.List(context.Background(), Filter{}, 100)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "my clip.mp4", messages[0].Key)
+		assert.Equal(t, int64(44), messages[0].VideoId)
+	})
+
+	t.Run("should decode control messages", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(`{"action":"reprocess","video_id":42,"user_id":7}`, "INVALID_CONTROL_MESSAGE")
+		inspector := newTestInspector(queues)
+
+		// Act
+		messages, err := inspector.List(context.Background(), Filter{}, 100)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "reprocess", messages[0].Action)
+		assert.Equal(t, int64(42), messages[0].VideoId)
+		assert.Equal(t, int64(7), messages[0].UserId)
+	})
+
+	t.Run("should keep malformed messages", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(`{"Records":`, "INVALID_S3_EVENT")
+		inspector := newTestInspector(queues)
+
+		// Act
+		messages, err := inspector.List(context.Background(), Filter{}, 100)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, `{"Records":`, messages[0].Body)
+		assert.Zero(t, messages[0].VideoId)
+	})
+
+	t.Run("should filter by video, user and code", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		clipId := queues.deadLetter(s3Event("clip.mp4"), "JOB_START_TIMEOUT")
+		queues.deadLetter(s3Event("clip.mp4"), "MISSING_METADATA")
+		inspector := newTestInspector(queues)
+
+		// Act
+		byVideo, err := inspector.List(context.Background(), Filter{VideoId: 43, Code: "JOB_START_TIMEOUT"}, 100)
+		require.NoError(t, err)
+		byUser, err := inspector.List(context.Background(), Filter{UserId: 7}, 100)
+		require.NoError(t, err)
+
+		// Assert
+		require.Len(t, byVideo, 1)
+		assert.Equal(t, clipId, byVideo[0].MessageId)
+		require.Len(t, byUser, 1)
+		assert.Equal(t, "movie.mp4", byUser[0].Key)
+	})
+
+	t.Run("should stop after max messages and release them", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		for range 15 {
+			queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		}
+		inspector := newTestInspector(queues)
+
+		// Act
+		messages, err := inspector.List(context.Background(), Filter{}, 12)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, messages, 12)
+		for _, invisible := range queues.invisible {
+			assert.False(t, invisible)
+		}
+	})
+}
+
+func TestInspector_Redrive(t *testing.T) {
+	t.Run("should send the selected messages back to their queue without the failure attributes", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		clipId := queues.deadLetter(s3Event("clip.mp4"), "JOB_START_TIMEOUT")
+		inspector := newTestInspector(queues)
+
+		// Act
+		redriven, err := inspector.Redrive(context.Background(), Filter{VideoId: 42}, 100, "", false)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, redriven, 1)
+		require.Len(t, queues.messages[testQueueURL], 1)
+		sent := queues.messages[testQueueURL][0]
+		assert.Equal(t, s3Event("movie.mp4"), *sent.Body)
+		assert.Contains(t, sent.MessageAttributes, "traceparent")
+		assert.NotContains(t, sent.MessageAttributes, sqs.AttributeFailureCode)
+		assert.NotContains(t, sent.MessageAttributes, sqs.AttributeSourceQueue)
+		require.Len(t, queues.messages[testDLQURL], 1)
+		assert.Equal(t, clipId, *queues.messages[testDLQURL][0].MessageId)
+		assert.False(t, queues.invisible["receipt-"+clipId])
+	})
+
+	t.Run("should send the messages to the target queue", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		inspector := newTestInspector(queues)
+		target := "https://sqs.us-east-1.amazonaws.com/000000000000/premium"
+
+		// Act
+		redriven, err := inspector.Redrive(context.Background(), Filter{}, 100, target, false)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, redriven, 1)
+		assert.Len(t, queues.messages[target], 1)
+		assert.Empty(t, queues.messages[testDLQURL])
+	})
+
+	t.Run("should send the messages to a FIFO queue in their original group", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		id := queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		queues.messages[testDLQURL][0].MessageAttributes[sqs.AttributeGroupId] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String("user-7")}
+		inspector := newTestInspector(queues)
+		target := "https://sqs.us-east-1.amazonaws.com/000000000000/uploads.fifo"
+
+		// Act
+		redriven, err := inspector.Redrive(context.Background(), Filter{}, 100, target, false)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, redriven, 1)
+		require.Len(t, queues.messages[target], 1)
+		sent := queues.messages[target][0]
+		assert.Equal(t, "user-7", sent.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)])
+		assert.True(t, strings.HasPrefix(sent.Attributes[string(types.MessageSystemAttributeNameMessageDeduplicationId)], id+"-"))
+		assert.NotContains(t, sent.MessageAttributes, sqs.AttributeGroupId)
+	})
+
+	t.Run("should not deduplicate a message redriven again to a FIFO queue", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		deadLettered := queues.messages[testDLQURL][0]
+		inspector := newTestInspector(queues)
+		target := "https://sqs.us-east-1.amazonaws.com/000000000000/uploads.fifo"
+		_, err := inspector.Redrive(context.Background(), Filter{}, 100, target, false)
+		require.NoError(t, err)
+		// The message fails again and is moved back to the dead-letter queue with the same ID
+		queues.messages[testDLQURL] = append(queues.messages[testDLQURL], deadLettered)
+		delete(queues.invisible, *deadLettered.ReceiptHandle)
+
+		// Act
+		_, err = inspector.Redrive(context.Background(), Filter{}, 100, target, false)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, queues.messages[target], 2)
+		deduplicationId := string(types.MessageSystemAttributeNameMessageDeduplicationId)
+		assert.NotEqual(t, queues.messages[target][0].Attributes[deduplicationId], queues.messages[target][1].Attributes[deduplicationId])
+	})
+
+	t.Run("should change nothing on a dry run", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		inspector := newTestInspector(queues)
+
+		// Act
+		redriven, err := inspector.Redrive(context.Background(), Filter{}, 100, "", true)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, redriven, 1)
+		assert.Empty(t, queues.messages[testQueueURL])
+		assert.Len(t, queues.messages[testDLQURL], 1)
+		for _, invisible := range queues.invisible {
+			assert.False(t, invisible)
+		}
+	})
+
+	t.Run("should keep the messages that can't be sent", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		queues.sendErr = errors.New("access denied")
+		inspector := newTestInspector(queues)
+
+		// Act
+		redriven, err := inspector.Redrive(context.Background(), Filter{}, 100, "", false)
+
+		// Assert
+		assert.ErrorContains(t, err, "access denied")
+		assert.Empty(t, redriven)
+		assert.Len(t, queues.messages[testDLQURL], 1)
+	})
+}
+
+func TestInspector_Discard(t *testing.T) {
+	t.Run("should delete the selected messages", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(`{"Records":`, "INVALID_S3_EVENT")
+		timeoutId := queues.deadLetter(s3Event("movie.mp4"), "JOB_START_TIMEOUT")
+		inspector := newTestInspector(queues)
+
+		// Act
+		discarded, err := inspector.Discard(context.Background(), Filter{Code: "INVALID_S3_EVENT"}, 100, false)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, discarded, 1)
+		require.Len(t, queues.messages[testDLQURL], 1)
+		assert.Equal(t, timeoutId, *queues.messages[testDLQURL][0].MessageId)
+	})
+
+	t.Run("should change nothing on a dry run", func(t *testing.T) {
+		// Arrange
+		queues := newFakeQueues()
+		queues.deadLetter(`{"Records":`, "INVALID_S3_EVENT")
+		inspector := newTestInspector(queues)
+
+		// Act
+		discarded, err := inspector.Discard(context.Background(), Filter{}, 100, true)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, discarded, 1)
+		assert.Len(t, queues.messages[testDLQURL], 1)
+	})
+}
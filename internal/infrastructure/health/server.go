@@ -0,0 +1,135 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+)
+
+const (
+	// checkTimeout bounds each readiness check, so a hanging dependency fails the probe
+	// instead of timing it out
+	checkTimeout = 5 * time.Second
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Check reports whether a dependency is reachable
+type Check func(ctx context.Context) error
+
+// Server serves the probes and the metrics of the starter:
+//   - /healthz answers as long as the process is running
+//   - /readyz runs the checks and fails when a dependency is unreachable
+//   - /metrics exposes the gathered metrics in the Prometheus format
+type Server struct {
+	addr    string
+	checks  map[string]Check
+	handler http.Handler
+	logger  *logger.Logger
+}
+
+// readiness is the body of /readyz, with "ok" or the error of each check
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// NewServer creates a server listening on addr, such as ":8080"
+func NewServer(addr string, gatherer prometheus.Gatherer, checks map[string]Check, logger *logger.Logger) *Server {
+	s := &Server{
+		addr:   addr,
+		checks: checks,
+		logger: logger,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /readyz", s.readyz)
+	mux.Handle("GET /metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	s.handler = mux
+
+	return s
+}
+
+// Handler returns the handler of the endpoints
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Run serves the endpoints until ctx is done, then shuts the server down
+func (s *Server) Run(ctx context.Context) error {
+	server := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errChan:
+		return fmt.Errorf("error serving health endpoints: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down health endpoints: %w", err)
+	}
+	if err := <-errChan; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving health endpoints: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	result := readiness{Status: "ok", Checks: make(map[string]string, len(s.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := "ok"
+			if err := check(ctx); err != nil {
+				status = err.Error()
+				s.logger.WarnContext(ctx, "Readiness check failed", "check", name, "error", status)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.Checks[name] = status
+			if status != "ok" {
+				result.Status = "unavailable"
+			}
+		}()
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	if result.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
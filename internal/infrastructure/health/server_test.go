@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+)
+
+func newTestServer(checks map[string]Check) *Server {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total", Help: "Test counter."}))
+	log := &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	return NewServer(":0", registry, checks, log)
+}
+
+func get(t *testing.T, server *Server, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func TestServer(t *testing.T) {
+	reachable := func(ctx context.Context) error { return nil }
+	unreachable := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	t.Run("should answer the liveness probe", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(map[string]Check{"sqs": unreachable})
+
+		// Act
+		response := get(t, server, "/healthz")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "ok", response.Body.String())
+	})
+
+	t.Run("should be ready when every check passes", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(map[string]Check{"sqs": reachable, "kubernetes": reachable})
+
+		// Act
+		response := get(t, server, "/readyz")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, response.Code)
+		var body readiness
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+		assert.Equal(t, readiness{Status: "ok", Checks: map[string]string{"sqs": "ok", "kubernetes": "ok"}}, body)
+	})
+
+	t.Run("should not be ready when a check fails", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(map[string]Check{"sqs": reachable, "kubernetes": unreachable})
+
+		// Act
+		response := get(t, server, "/readyz")
+
+		// Assert
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+		var body readiness
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+		assert.Equal(t, readiness{Status: "unavailable", Checks: map[string]string{"sqs": "ok", "kubernetes": "connection refused"}}, body)
+	})
+
+	t.Run("should expose the metrics in the Prometheus format", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(nil)
+
+		// Act
+		response := get(t, server, "/metrics")
+
+		// Assert
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), "# TYPE test_total counter")
+	})
+
+	t.Run("should stop serving when the context is done", func(t *testing.T) {
+		// Arrange
+		server := newTestServer(nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Act
+		err := server.Run(ctx)
+
+		// Assert
+		assert.NoError(t, err)
+	})
+}
@@ -14,59 +14,127 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
-var l *logger.Logger
-
-var k8sClient *kubernetes.Clientset
-var k8sAPI *api.K8sAPI
-var cfg *config.Config
-
 type Infrastructure struct {
 	Context          context.Context
-	K8sAPI           *api.K8sAPI
+	K8sAPI           api.K8sAPIInterface
 	Config           *config.Config
 	JobConfig        *config.JobConfig
 	Logger           *logger.Logger
-	SNS              *sns.SNS
-	S3               *s3.S3
+	SNS              sns.SNSInterface
+	S3               s3.S3Interface
 	AWSClientFactory *aws.ClientFactory
+
+	// kubernetesClient is given by WithKubernetesClient, the K8sAPI is built on it with the logger
+	kubernetesClient kubernetes.Interface
+}
+
+// Option replaces one of the dependencies built by New, mainly to inject fakes in tests
+type Option func(*Infrastructure)
+
+// WithConfig uses the given configuration instead of loading it from the environment
+func WithConfig(cfg *config.Config) Option {
+	return func(i *Infrastructure) {
+		i.Config = cfg
+	}
 }
 
-var infrastructure *Infrastructure
+// WithJobConfig uses the given checker configuration instead of loading it from the environment
+func WithJobConfig(jobConfig *config.JobConfig) Option {
+	return func(i *Infrastructure) {
+		i.JobConfig = jobConfig
+	}
+}
 
-// init function is called during application startup. So, at this moment is initialized
-// all structures and also the database connection
-func init() {
-	fmt.Println("🟠 Initing SQS consumer application")
-	cfg = config.LoadLambdaConfig()
-	jobConfig := config.LoadJobConfig()
-	l = logger.NewLogger(cfg)
-	ctx := context.Background()
+// WithLogger uses the given logger instead of creating one from the configuration
+func WithLogger(l *logger.Logger) Option {
+	return func(i *Infrastructure) {
+		i.Logger = l
+	}
+}
 
-	// init k8s client
-	var err error
-	k8sClient, err = k8s.ConnectToK8s(ctx, l, cfg)
-	if err != nil {
-		panic(err)
+// WithK8sAPI uses the given Kubernetes API instead of connecting to the cluster
+func WithK8sAPI(k8sAPI api.K8sAPIInterface) Option {
+	return func(i *Infrastructure) {
+		i.K8sAPI = k8sAPI
 	}
-	awsClientFactory, err := aws.NewClientFactory(ctx, cfg.AWS.Region)
-	if err != nil {
-		panic(err)
+}
+
+// WithKubernetesClient builds the Kubernetes API on the given client, such as the
+// fake clientset, instead of connecting to the cluster
+func WithKubernetesClient(client kubernetes.Interface) Option {
+	return func(i *Infrastructure) {
+		i.kubernetesClient = client
 	}
-	k8sAPI = api.NewK8sAPI(k8sClient)
+}
 
-	infrastructure = &Infrastructure{
-		Context:          ctx,
-		K8sAPI:           k8sAPI,
-		Config:           cfg,
-		JobConfig:        jobConfig,
-		Logger:           l,
-		SNS:              sns.NewSNS(cfg),
-		S3:               s3.NewS3(cfg),
-		AWSClientFactory: awsClientFactory,
+// WithAWSClientFactory uses the given AWS client factory instead of loading the AWS configuration
+func WithAWSClientFactory(factory *aws.ClientFactory) Option {
+	return func(i *Infrastructure) {
+		i.AWSClientFactory = factory
 	}
+}
 
+// WithSNS uses the given SNS client to publish the video status
+func WithSNS(client sns.SNSInterface) Option {
+	return func(i *Infrastructure) {
+		i.SNS = client
+	}
 }
 
-func GetInfrastructure() *Infrastructure {
-	return infrastructure
+// WithS3 uses the given S3 client to read the uploaded objects
+func WithS3(client s3.S3Interface) Option {
+	return func(i *Infrastructure) {
+		i.S3 = client
+	}
+}
+
+// New loads the configuration and connects to Kubernetes and AWS. Dependencies given
+// as options are used as they are.
+func New(ctx context.Context, opts ...Option) (*Infrastructure, error) {
+	infra := &Infrastructure{Context: ctx}
+	for _, opt := range opts {
+		opt(infra)
+	}
+
+	if infra.Config == nil {
+		cfg, err := config.LoadLambdaConfig()
+		if err != nil {
+			return nil, err
+		}
+		infra.Config = cfg
+	}
+	if infra.JobConfig == nil {
+		infra.JobConfig = config.LoadJobConfig()
+	}
+	if infra.Logger == nil {
+		infra.Logger = logger.NewLogger(infra.Config)
+	}
+	infra.Logger.InfoContext(ctx, "🟠 Initializing infrastructure")
+
+	if infra.K8sAPI == nil {
+		if infra.kubernetesClient == nil {
+			k8sClient, err := k8s.ConnectToK8s(ctx, infra.Logger, infra.Config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect to k8s: %w", err)
+			}
+			infra.kubernetesClient = k8sClient
+		}
+		infra.K8sAPI = api.NewK8sAPI(infra.kubernetesClient, infra.Logger)
+	}
+
+	if infra.AWSClientFactory == nil {
+		awsClientFactory, err := aws.NewClientFactory(ctx, infra.Config.AWS.Region)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS client factory: %w", err)
+		}
+		infra.AWSClientFactory = awsClientFactory
+	}
+	if infra.SNS == nil {
+		infra.SNS = sns.NewSNS(infra.Config)
+	}
+	if infra.S3 == nil {
+		infra.S3 = s3.NewS3(infra.Config)
+	}
+
+	return infra, nil
 }
@@ -0,0 +1,77 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/s3"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sns"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("should use the injected dependencies", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		cfg := &config.Config{Environment: "test"}
+		cfg.AWS.Region = "us-east-1"
+		jobConfig := &config.JobConfig{JobName: "video-processor-test"}
+		l := logger.NewLogger(cfg)
+		k8sAPI := api.NewK8sAPI(fake.NewClientset(), l)
+		factory, err := aws.NewClientFactory(ctx, cfg.AWS.Region)
+		require.NoError(t, err)
+		snsClient := sns.NewSNS(cfg)
+		s3Client := s3.NewS3(cfg)
+
+		// Act
+		infra, err := New(ctx,
+			WithConfig(cfg),
+			WithJobConfig(jobConfig),
+			WithLogger(l),
+			WithK8sAPI(k8sAPI),
+			WithAWSClientFactory(factory),
+			WithSNS(snsClient),
+			WithS3(s3Client),
+		)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Same(t, cfg, infra.Config)
+		assert.Same(t, jobConfig, infra.JobConfig)
+		assert.Same(t, l, infra.Logger)
+		assert.Same(t, k8sAPI, infra.K8sAPI)
+		assert.Same(t, factory, infra.AWSClientFactory)
+		assert.Equal(t, snsClient, infra.SNS)
+		assert.Equal(t, s3Client, infra.S3)
+		assert.Equal(t, ctx, infra.Context)
+	})
+
+	t.Run("should build the clients that were not injected", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		cfg := &config.Config{Environment: "test"}
+		cfg.AWS.Region = "us-east-1"
+
+		// Act
+		infra, err := New(ctx,
+			WithConfig(cfg),
+			WithKubernetesClient(fake.NewClientset()),
+		)
+
+		// Assert
+		require.NoError(t, err)
+		assert.IsType(t, &api.K8sAPI{}, infra.K8sAPI)
+		assert.NotNil(t, infra.JobConfig)
+		assert.NotNil(t, infra.Logger)
+		assert.NotNil(t, infra.AWSClientFactory)
+		assert.NotNil(t, infra.SNS)
+		assert.NotNil(t, infra.S3)
+	})
+}
@@ -1,33 +1,59 @@
 package logger
 
 import (
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 
 	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
 )
 
+// Log formats accepted in LOG_FORMAT
+const (
+	FormatJSON   = "json"
+	FormatText   = "text"
+	FormatPretty = "pretty"
+)
+
 type Logger struct {
 	*slog.Logger
 }
 
+// NewLogger writes to stdout with the level and format of the configuration. Outside production
+// they default to debug and pretty, in production to info and json.
 func NewLogger(cfg *config.Config) *Logger {
-	var handler slog.Handler
+	return newLogger(os.Stdout, cfg)
+}
 
+func newLogger(out io.Writer, cfg *config.Config) *Logger {
+	level, format := slog.LevelDebug, FormatPretty
 	if cfg.Environment == "production" {
-		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level:     slog.LevelInfo,
-			AddSource: true,
-		})
-	} else {
-		handler = NewPrettyHandler(os.Stdout, PrettyHandlerOptions{
-			SlogOpts: slog.HandlerOptions{
-				Level:     slog.LevelDebug,
-				AddSource: true,
-			}})
+		level, format = slog.LevelInfo, FormatJSON
+	}
+	if cfg.LogLevel != "" {
+		_ = level.UnmarshalText([]byte(cfg.LogLevel))
+	}
+	if cfg.LogFormat != "" {
+		format = strings.ToLower(cfg.LogFormat)
+	}
+
+	opts := slog.HandlerOptions{
+		Level:     level,
+		AddSource: true,
+	}
+
+	var handler slog.Handler
+	switch format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(out, &opts)
+	case FormatText:
+		handler = slog.NewTextHandler(out, &opts)
+	default:
+		handler = NewPrettyHandler(out, PrettyHandlerOptions{SlogOpts: opts})
 	}
 
 	return &Logger{
-		Logger: slog.New(handler),
+		Logger: slog.New(&contextHandler{Handler: handler}),
 	}
 }
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+)
+
+// decode returns the JSON records written by a logger
+func decode(t *testing.T, out *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	records := make([]map[string]any, 0)
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var record map[string]any
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNewLogger(t *testing.T) {
+	t.Run("should log info and above as JSON in production", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := newLogger(&out, &config.Config{Environment: "production"})
+
+		// Act
+		l.Debug("debug message")
+		l.Info("info message")
+
+		// Assert
+		records := decode(t, &out)
+		require.Len(t, records, 1)
+		assert.Equal(t, "info message", records[0]["msg"])
+	})
+
+	t.Run("should use the configured level and format", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := newLogger(&out, &config.Config{Environment: "development", LogLevel: "warn", LogFormat: "text"})
+
+		// Act
+		l.Info("info message")
+		l.Warn("warn message")
+
+		// Assert
+		assert.NotContains(t, out.String(), "info message")
+		assert.Contains(t, out.String(), `level=WARN`)
+		assert.Contains(t, out.String(), `msg="warn message"`)
+	})
+}
+
+func TestWithFields(t *testing.T) {
+	t.Run("should add the fields of the context to the records", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := newLogger(&out, &config.Config{LogFormat: FormatJSON})
+		ctx := WithFields(context.Background(), "messageId", "message-1")
+		ctx = WithFields(ctx, "videoId", int64(42))
+
+		// Act
+		l.With("component", "starter").InfoContext(ctx, "Processing message")
+		l.InfoContext(context.Background(), "Idle")
+
+		// Assert
+		records := decode(t, &out)
+		require.Len(t, records, 2)
+		assert.Equal(t, "message-1", records[0]["messageId"])
+		assert.Equal(t, float64(42), records[0]["videoId"])
+		assert.Equal(t, "starter", records[0]["component"])
+		assert.NotContains(t, records[1], "messageId")
+	})
+
+	t.Run("should not change the fields of the parent context", func(t *testing.T) {
+		// Arrange
+		parent := WithFields(context.Background(), "messageId", "message-1")
+
+		// Act
+		WithFields(parent, "videoId", int64(42))
+
+		// Assert
+		assert.Len(t, Fields(parent), 1)
+	})
+}
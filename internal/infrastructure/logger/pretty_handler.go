@@ -3,64 +3,239 @@ package logger
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 )
 
 type PrettyHandlerOptions struct {
 	SlogOpts slog.HandlerOptions
+
+	// NoColor prints without colors. Colors are only used when the output is a terminal and
+	// NO_COLOR is not set.
+	NoColor bool
 }
 
+// PrettyHandler prints a record per line for reading in a terminal, with the attributes
+// as indented JSON:
+//
+//	[15:04:05.000] INFO: Job started (api/k8_api.go:170) {"job": "video-processor-42"}
 type PrettyHandler struct {
-	slog.Handler
-	l *log.Logger
+	opts   slog.HandlerOptions
+	colors bool
+	l      *log.Logger
+
+	// fields holds the attributes given to With, nested by group
+	fields map[string]any
+	// groups is the path of the group opened by WithGroup that the record attributes go in
+	groups []string
+}
+
+func NewPrettyHandler(
+	out io.Writer,
+	opts PrettyHandlerOptions,
+) *PrettyHandler {
+	h := &PrettyHandler{
+		opts:   opts.SlogOpts,
+		colors: !opts.NoColor && os.Getenv("NO_COLOR") == "" && isTerminal(out),
+		l:      log.New(out, "", 0),
+		fields: make(map[string]any),
+	}
+
+	return h
+}
+
+func isTerminal(out io.Writer) bool {
+	file, ok := out.(*os.File)
+	return ok && (isatty.IsTerminal(file.Fd()) || isatty.IsCygwinTerminal(file.Fd()))
+}
+
+// paint colors s whatever the output of the process, the handler deciding on its own output
+func paint(attribute color.Attribute, s string) string {
+	c := color.New(attribute)
+	c.EnableColor()
+	return c.Sprint(s)
+}
+
+func (h *PrettyHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	clone := h.clone()
+	clone.merge(clone.fields, attrs)
+	return clone
+}
+
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := h.clone()
+	clone.groups = append(clone.groups[:len(clone.groups):len(clone.groups)], name)
+	return clone
 }
 
 func (h *PrettyHandler) Handle(ctx context.Context, r slog.Record) error {
 	level := r.Level.String() + ":"
+	msg := r.Message
+	if h.colors {
+		switch {
+		case r.Level >= slog.LevelError:
+			level = paint(color.FgRed, level)
+		case r.Level >= slog.LevelWarn:
+			level = paint(color.FgYellow, level)
+		case r.Level >= slog.LevelInfo:
+			level = paint(color.FgBlue, level)
+		default:
+			level = paint(color.FgMagenta, level)
+		}
+		msg = paint(color.FgCyan, msg)
+	}
 
-	switch r.Level {
-	case slog.LevelDebug:
-		level = color.MagentaString(level)
-	case slog.LevelInfo:
-		level = color.BlueString(level)
-	case slog.LevelWarn:
-		level = color.YellowString(level)
-	case slog.LevelError:
-		level = color.RedString(level)
+	line := make([]any, 0, 5)
+	if !r.Time.IsZero() {
+		line = append(line, r.Time.Format("[15:04:05.000]"))
 	}
+	line = append(line, level, msg)
 
-	fields := make(map[string]interface{}, r.NumAttrs())
-	r.Attrs(func(a slog.Attr) bool {
-		fields[a.Key] = a.Value.Any()
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		source := fmt.Sprintf("(%s/%s:%d)", filepath.Base(filepath.Dir(frame.File)), filepath.Base(frame.File), frame.Line)
+		if h.colors {
+			source = paint(color.FgHiBlack, source)
+		}
+		line = append(line, source)
+	}
 
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
 		return true
 	})
+	fields := cloneFields(h.fields)
+	h.merge(fields, attrs)
 
-	b, err := json.MarshalIndent(fields, "", "  ")
-	if err != nil {
-		return err
+	if len(fields) > 0 {
+		b, err := json.MarshalIndent(fields, "", "  ")
+		if err != nil {
+			return err
+		}
+		out := string(b)
+		if h.colors {
+			out = paint(color.FgWhite, out)
+		}
+		line = append(line, out)
 	}
 
-	timeStr := r.Time.Format("[15:05:05.000]")
-	msg := color.CyanString(r.Message)
-
-	h.l.Println(timeStr, level, msg, color.WhiteString(string(b)))
+	h.l.Println(line...)
 
 	return nil
 }
 
-func NewPrettyHandler(
-	out io.Writer,
-	opts PrettyHandlerOptions,
-) *PrettyHandler {
-	h := &PrettyHandler{
-		Handler: slog.NewJSONHandler(out, &opts.SlogOpts),
-		l:       log.New(out, "", 0),
+// clone copies the handler with its own fields, so that adding attributes doesn't change
+// the handlers it was derived from
+func (h *PrettyHandler) clone() *PrettyHandler {
+	clone := *h
+	clone.fields = cloneFields(h.fields)
+	return &clone
+}
+
+func cloneFields(fields map[string]any) map[string]any {
+	clone := maps.Clone(fields)
+	for key, value := range clone {
+		if group, ok := value.(map[string]any); ok {
+			clone[key] = cloneFields(group)
+		}
 	}
+	return clone
+}
 
-	return h
+// merge adds attributes to the current group of fields. The group is left out when no
+// attribute remains.
+func (h *PrettyHandler) merge(fields map[string]any, attrs []slog.Attr) {
+	added := make(map[string]any)
+	for _, attr := range attrs {
+		h.addAttr(added, h.groups, attr)
+	}
+	if len(added) > 0 {
+		maps.Copy(h.group(fields, h.groups), added)
+	}
+}
+
+// group returns the map of the group at path, creating it when needed
+func (h *PrettyHandler) group(fields map[string]any, path []string) map[string]any {
+	for _, name := range path {
+		group, ok := fields[name].(map[string]any)
+		if !ok {
+			group = make(map[string]any)
+			fields[name] = group
+		}
+		fields = group
+	}
+	return fields
+}
+
+// addAttr adds an attribute to fields, with the same rules as the slog handlers: empty
+// attributes are dropped and groups without a key are inlined
+func (h *PrettyHandler) addAttr(fields map[string]any, groups []string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() != slog.KindGroup && h.opts.ReplaceAttr != nil {
+		attr = h.opts.ReplaceAttr(groups, attr)
+		attr.Value = attr.Value.Resolve()
+	}
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		children := attr.Value.Group()
+		if len(children) == 0 {
+			return
+		}
+		target := fields
+		if attr.Key != "" {
+			target = h.group(fields, []string{attr.Key})
+			groups = append(groups[:len(groups):len(groups)], attr.Key)
+		}
+		for _, child := range children {
+			h.addAttr(target, groups, child)
+		}
+		return
+	}
+
+	fields[attr.Key] = fieldValue(attr.Value)
+}
+
+// fieldValue converts a value to what reads best in JSON
+func fieldValue(value slog.Value) any {
+	switch value.Kind() {
+	case slog.KindDuration:
+		return value.Duration().String()
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return err.Error()
+		}
+		if stringer, ok := value.Any().(fmt.Stringer); ok {
+			return stringer.String()
+		}
+	}
+	return value.Any()
 }
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prettyFields returns the JSON attributes printed after the message
+func prettyFields(t *testing.T, line string) map[string]any {
+	t.Helper()
+
+	start := strings.Index(line, "{")
+	require.NotEqual(t, -1, start, "no attributes in %q", line)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line[start:]), &fields))
+	return fields
+}
+
+func TestPrettyHandler(t *testing.T) {
+	t.Run("should print the time, level and message without colors when not in a terminal", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		handler := NewPrettyHandler(&out, PrettyHandlerOptions{})
+		record := slog.NewRecord(time.Date(2025, 1, 2, 10, 20, 30, 400_000_000, time.UTC), slog.LevelInfo, "Job started", 0)
+
+		// Act
+		err := handler.Handle(t.Context(), record)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "[10:20:30.400] INFO: Job started\n", out.String())
+	})
+
+	t.Run("should keep the attributes given to With", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := slog.New(NewPrettyHandler(&out, PrettyHandlerOptions{}))
+		mdcLogger := l.With("jobName", "video-processor-42", "attempt", 2)
+
+		// Act
+		mdcLogger.Info("Job status", "jobStatus", "Running")
+
+		// Assert
+		assert.Equal(t, map[string]any{
+			"jobName":   "video-processor-42",
+			"attempt":   float64(2),
+			"jobStatus": "Running",
+		}, prettyFields(t, out.String()))
+	})
+
+	t.Run("should nest the attributes in their groups", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := slog.New(NewPrettyHandler(&out, PrettyHandlerOptions{}))
+		jobLogger := l.With("component", "starter").WithGroup("job").With("name", "video-processor-42")
+
+		// Act
+		jobLogger.Info("Job created", "namespace", "video-processing", slog.Group("tier", "name", "premium"))
+
+		// Assert
+		assert.Equal(t, map[string]any{
+			"component": "starter",
+			"job": map[string]any{
+				"name":      "video-processor-42",
+				"namespace": "video-processing",
+				"tier":      map[string]any{"name": "premium"},
+			},
+		}, prettyFields(t, out.String()))
+	})
+
+	t.Run("should leave out empty groups", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := slog.New(NewPrettyHandler(&out, PrettyHandlerOptions{}))
+
+		// Act
+		l.WithGroup("job").Info("Idle", slog.Group("empty"))
+
+		// Assert
+		assert.NotContains(t, out.String(), "{")
+	})
+
+	t.Run("should not share the attributes between derived loggers", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := slog.New(NewPrettyHandler(&out, PrettyHandlerOptions{}))
+		parent := l.With("component", "starter")
+		parent.With("videoId", 42)
+
+		// Act
+		parent.Info("Processing message")
+
+		// Assert
+		assert.Equal(t, map[string]any{"component": "starter"}, prettyFields(t, out.String()))
+	})
+
+	t.Run("should print errors and durations as text", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := slog.New(NewPrettyHandler(&out, PrettyHandlerOptions{}))
+
+		// Act
+		l.Error("Failed", "error", errors.New("connection refused"), "retryAfter", 30*time.Second)
+
+		// Assert
+		assert.Equal(t, map[string]any{"error": "connection refused", "retryAfter": "30s"}, prettyFields(t, out.String()))
+	})
+
+	t.Run("should print the source location when enabled", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := slog.New(NewPrettyHandler(&out, PrettyHandlerOptions{SlogOpts: slog.HandlerOptions{AddSource: true}}))
+
+		// Act
+		l.Info("Job started")
+
+		// Assert
+		assert.Contains(t, out.String(), "(logger/pretty_handler_test.go:")
+	})
+
+	t.Run("should filter the records below the level", func(t *testing.T) {
+		// Arrange
+		var out bytes.Buffer
+		l := slog.New(NewPrettyHandler(&out, PrettyHandlerOptions{SlogOpts: slog.HandlerOptions{Level: slog.LevelWarn}}))
+
+		// Act
+		l.Info("Job started")
+		l.Warn("Job pending")
+
+		// Assert
+		assert.NotContains(t, out.String(), "Job started")
+		assert.Contains(t, out.String(), "WARN: Job pending")
+	})
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+)
+
+const namespace = "job_starter"
+
+// Outcomes of a job creation
+const (
+	OutcomeStarted       = "started"
+	OutcomeSuspended     = "suspended"
+	OutcomeAlreadyExists = "already_exists"
+	OutcomeFailed        = "failed"
+)
+
+// Metrics are the Prometheus collectors of the starter. A nil *Metrics records nothing,
+// so components can be built without metrics.
+type Metrics struct {
+	messagesReceived  *prometheus.CounterVec
+	messagesDeleted   *prometheus.CounterVec
+	messagesFailed    *prometheus.CounterVec
+	deadLettered      *prometheus.CounterVec
+	receiveErrors     *prometheus.CounterVec
+	messageLag        *prometheus.HistogramVec
+	jobsCreated       *prometheus.CounterVec
+	createJobDuration prometheus.Histogram
+}
+
+// NewRegistry creates a registry with the Go runtime and process collectors
+func NewRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// NewMetrics creates the collectors and registers them
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		messagesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_received_total",
+			Help:      "Messages received from the queues.",
+		}, []string{"queue"}),
+		messagesDeleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_deleted_total",
+			Help:      "Messages deleted from the queues after being processed.",
+		}, []string{"queue"}),
+		messagesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_failed_total",
+			Help:      "Messages whose processing failed and were left in the queues.",
+		}, []string{"queue"}),
+		deadLettered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "messages_dead_lettered_total",
+			Help:      "Messages forwarded to the dead-letter queue, by failure code.",
+		}, []string{"queue", "code"}),
+		receiveErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "queue_receive_errors_total",
+			Help:      "Failed attempts to receive messages from the queues.",
+		}, []string{"queue"}),
+		messageLag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "message_lag_seconds",
+			Help:      "Time messages waited in the queues between being sent and being received.",
+			Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900, 3600},
+		}, []string{"queue"}),
+		jobsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "jobs_created_total",
+			Help:      "Kubernetes jobs created, by outcome.",
+		}, []string{"outcome"}),
+		createJobDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "create_job_duration_seconds",
+			Help:      "Time to create a Kubernetes job and wait for it to start.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}),
+	}
+
+	registerer.MustRegister(
+		m.messagesReceived,
+		m.messagesDeleted,
+		m.messagesFailed,
+		m.deadLettered,
+		m.receiveErrors,
+		m.messageLag,
+		m.jobsCreated,
+		m.createJobDuration,
+	)
+	return m
+}
+
+// MessagesReceived counts the messages of a receive
+func (m *Metrics) MessagesReceived(queueURL string, count int) {
+	if m == nil {
+		return
+	}
+	m.messagesReceived.WithLabelValues(queueName(queueURL)).Add(float64(count))
+}
+
+// MessageDeleted counts a message deleted after being processed
+func (m *Metrics) MessageDeleted(queueURL string) {
+	if m == nil {
+		return
+	}
+	m.messagesDeleted.WithLabelValues(queueName(queueURL)).Inc()
+}
+
+// MessageFailed counts a message whose processing failed
+func (m *Metrics) MessageFailed(queueURL string) {
+	if m == nil {
+		return
+	}
+	m.messagesFailed.WithLabelValues(queueName(queueURL)).Inc()
+}
+
+// MessageDeadLettered counts a message forwarded to the dead-letter queue
+func (m *Metrics) MessageDeadLettered(queueURL string, code string) {
+	if m == nil {
+		return
+	}
+	m.deadLettered.WithLabelValues(queueName(queueURL), code).Inc()
+}
+
+// ReceiveError counts a failed receive
+func (m *Metrics) ReceiveError(queueURL string) {
+	if m == nil {
+		return
+	}
+	m.receiveErrors.WithLabelValues(queueName(queueURL)).Inc()
+}
+
+// MessageLag observes how long a message waited in the queue before being received
+func (m *Metrics) MessageLag(queueURL string, lag time.Duration) {
+	if m == nil {
+		return
+	}
+	m.messageLag.WithLabelValues(queueName(queueURL)).Observe(lag.Seconds())
+}
+
+// JobCreated counts a job creation and observes how long it took
+func (m *Metrics) JobCreated(outcome string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.jobsCreated.WithLabelValues(outcome).Inc()
+	m.createJobDuration.Observe(duration.Seconds())
+}
+
+// queueName labels a queue by the last segment of its URL, e.g. "uploads" for
+// https://sqs.us-east-1.amazonaws.com/000000000000/uploads
+func queueName(queueURL string) string {
+	u, err := url.Parse(queueURL)
+	if err != nil || u.Path == "" {
+		return queueURL
+	}
+	return path.Base(u.Path)
+}
+
+// instrumentedK8sAPI records the outcome and latency of the jobs created
+type instrumentedK8sAPI struct {
+	api.K8sAPIInterface
+	metrics *Metrics
+}
+
+// InstrumentK8sAPI wraps a Kubernetes API to record the jobs created
+func InstrumentK8sAPI(k8sAPI api.K8sAPIInterface, m *Metrics) api.K8sAPIInterface {
+	return &instrumentedK8sAPI{K8sAPIInterface: k8sAPI, metrics: m}
+}
+
+func (i *instrumentedK8sAPI) CreateJob(ctx context.Context, jobInput *api.JobInput) error {
+	start := time.Now()
+	err := i.K8sAPIInterface.CreateJob(ctx, jobInput)
+	i.metrics.JobCreated(jobOutcome(jobInput, err), time.Since(start))
+	return err
+}
+
+func jobOutcome(jobInput *api.JobInput, err error) string {
+	switch {
+	case err == nil && jobInput.Suspend:
+		return OutcomeSuspended
+	case err == nil:
+		return OutcomeStarted
+	case apierrors.IsAlreadyExists(err):
+		return OutcomeAlreadyExists
+	default:
+		return OutcomeFailed
+	}
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+	mock_api "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api/mocks"
+)
+
+func TestInstrumentK8sAPI(t *testing.T) {
+	alreadyExists := apierrors.NewAlreadyExists(schema.GroupResource{Group: "batch", Resource: "jobs"}, "video-processor-42")
+
+	tests := []struct {
+		name    string
+		suspend bool
+		err     error
+		outcome string
+	}{
+		{name: "should count a started job", outcome: OutcomeStarted},
+		{name: "should count a suspended job", suspend: true, outcome: OutcomeSuspended},
+		{name: "should count a job that already exists", err: alreadyExists, outcome: OutcomeAlreadyExists},
+		{name: "should count a failed job", err: errors.New("job failed"), outcome: OutcomeFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctrl := gomock.NewController(t)
+			k8sAPI := mock_api.NewMockK8sAPIInterface(ctrl)
+			jobInput := &api.JobInput{JobName: "video-processor-42", Suspend: tt.suspend}
+			k8sAPI.EXPECT().CreateJob(gomock.Any(), jobInput).Return(tt.err)
+
+			registry := prometheus.NewRegistry()
+			m := NewMetrics(registry)
+			instrumented := InstrumentK8sAPI(k8sAPI, m)
+
+			// Act
+			err := instrumented.CreateJob(context.Background(), jobInput)
+
+			// Assert
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, 1.0, testutil.ToFloat64(m.jobsCreated.WithLabelValues(tt.outcome)))
+			assert.Equal(t, 1, testutil.CollectAndCount(m.createJobDuration))
+		})
+	}
+
+	t.Run("should forward the other calls", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		k8sAPI := mock_api.NewMockK8sAPIInterface(ctrl)
+		k8sAPI.EXPECT().Ping(gomock.Any()).Return(nil)
+		instrumented := InstrumentK8sAPI(k8sAPI, NewMetrics(prometheus.NewRegistry()))
+
+		// Act
+		err := instrumented.Ping(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("should label the messages by queue name", func(t *testing.T) {
+		// Arrange
+		m := NewMetrics(prometheus.NewRegistry())
+		queueURL := "https://sqs.us-east-1.amazonaws.com/000000000000/uploads"
+
+		// Act
+		m.MessagesReceived(queueURL, 3)
+		m.MessageDeleted(queueURL)
+		m.MessageFailed(queueURL)
+		m.MessageDeadLettered(queueURL, "INVALID_S3_EVENT")
+		m.ReceiveError(queueURL)
+		m.MessageLag(queueURL, 2*time.Second)
+
+		// Assert
+		assert.Equal(t, 3.0, testutil.ToFloat64(m.messagesReceived.WithLabelValues("uploads")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.messagesDeleted.WithLabelValues("uploads")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.messagesFailed.WithLabelValues("uploads")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.deadLettered.WithLabelValues("uploads", "INVALID_S3_EVENT")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(m.receiveErrors.WithLabelValues("uploads")))
+		assert.Equal(t, 1, testutil.CollectAndCount(m.messageLag, "job_starter_message_lag_seconds"))
+	})
+
+	t.Run("should record nothing without metrics", func(t *testing.T) {
+		// Arrange
+		var m *Metrics
+
+		// Act & Assert
+		assert.NotPanics(t, func() {
+			m.MessagesReceived("uploads", 1)
+			m.MessageDeleted("uploads")
+			m.MessageFailed("uploads")
+			m.MessageDeadLettered("uploads", "INTERNAL")
+			m.ReceiveError("uploads")
+			m.MessageLag("uploads", time.Second)
+			m.JobCreated(OutcomeStarted, time.Second)
+		})
+	})
+}
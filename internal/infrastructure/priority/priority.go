@@ -0,0 +1,80 @@
+package priority
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+)
+
+// MetadataKey is the S3 object metadata (x-amz-meta-tier) carrying the tier of the upload
+const MetadataKey = "tier"
+
+// Tier is the resolved tier of a video and the job template overrides that come with it
+type Tier struct {
+	Name string
+	config.JobTier
+}
+
+// Resolver finds the tier of a video from the object metadata, falling back to the user tier lookup
+// and then to the default tier
+type Resolver struct {
+	defaultTier string
+	tiers       map[string]config.JobTier
+	userTiers   port.UserTierGateway
+}
+
+func NewResolver(cfg *config.Config, userTiers port.UserTierGateway) *Resolver {
+	return &Resolver{
+		defaultTier: cfg.Priority.DefaultTier,
+		tiers:       cfg.Priority.Tiers,
+		userTiers:   userTiers,
+	}
+}
+
+// Resolve always returns a usable tier. The error reports a failed user tier lookup, in which
+// case the default tier is returned.
+func (r *Resolver) Resolve(ctx context.Context, metadata map[string]string, userId int64) (Tier, error) {
+	name := strings.ToLower(strings.TrimSpace(metadata[MetadataKey]))
+
+	var err error
+	if name == "" && r.userTiers != nil {
+		var userTier string
+		userTier, err = r.userTiers.GetUserTier(ctx, userId)
+		if err != nil {
+			err = fmt.Errorf("error getting tier of user %d: %w", userId, err)
+		}
+		name = strings.ToLower(userTier)
+	}
+
+	if _, ok := r.tiers[name]; !ok {
+		name = r.defaultTier
+	}
+
+	return Tier{Name: name, JobTier: r.tiers[name]}, err
+}
+
+// Lookup returns a configured tier by name, used when a template is chosen explicitly
+func (r *Resolver) Lookup(name string) (Tier, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	jobTier, ok := r.tiers[name]
+	return Tier{Name: name, JobTier: jobTier}, ok
+}
+
+// ImageOrDefault returns the tier image, or the given default when the tier doesn't override it
+func (t Tier) ImageOrDefault(image string) string {
+	if t.Image != "" {
+		return t.Image
+	}
+	return image
+}
+
+// CommandOrDefault returns the tier command, or the given default when the tier doesn't override it
+func (t Tier) CommandOrDefault(command string) string {
+	if t.Command != "" {
+		return t.Command
+	}
+	return command
+}
@@ -0,0 +1,117 @@
+package priority
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mocks "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port/mocks"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func newConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.Priority.DefaultTier = "standard"
+	cfg.Priority.Tiers = map[string]config.JobTier{
+		"premium":  {PriorityClassName: "video-high", Image: "processor:gpu"},
+		"standard": {PriorityClassName: "video-low"},
+	}
+	return cfg
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	t.Run("should use the tier from the object metadata", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockUserTiers := mocks.NewMockUserTierGateway(ctrl)
+		resolver := NewResolver(newConfig(), mockUserTiers)
+
+		// Act
+		tier, err := resolver.Resolve(context.Background(), map[string]string{"tier": "Premium"}, 1)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "premium", tier.Name)
+		assert.Equal(t, "video-high", tier.PriorityClassName)
+	})
+
+	t.Run("should look up the user tier when metadata has no tier", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockUserTiers := mocks.NewMockUserTierGateway(ctrl)
+		resolver := NewResolver(newConfig(), mockUserTiers)
+
+		mockUserTiers.EXPECT().GetUserTier(gomock.Any(), int64(42)).Return("premium", nil)
+
+		// Act
+		tier, err := resolver.Resolve(context.Background(), map[string]string{}, 42)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "premium", tier.Name)
+	})
+
+	t.Run("should fall back to the default tier for unknown tiers", func(t *testing.T) {
+		// Arrange
+		resolver := NewResolver(newConfig(), nil)
+
+		// Act
+		tier, err := resolver.Resolve(context.Background(), map[string]string{"tier": "gold"}, 1)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "standard", tier.Name)
+		assert.Equal(t, "video-low", tier.PriorityClassName)
+	})
+
+	t.Run("should return the default tier and the error when the lookup fails", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockUserTiers := mocks.NewMockUserTierGateway(ctrl)
+		resolver := NewResolver(newConfig(), mockUserTiers)
+
+		mockUserTiers.EXPECT().GetUserTier(gomock.Any(), int64(42)).Return("", errors.New("lookup failed"))
+
+		// Act
+		tier, err := resolver.Resolve(context.Background(), nil, 42)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Equal(t, "standard", tier.Name)
+	})
+}
+
+func TestResolver_Lookup(t *testing.T) {
+	resolver := NewResolver(newConfig(), nil)
+
+	t.Run("should find a configured tier", func(t *testing.T) {
+		// Act
+		tier, ok := resolver.Lookup("PREMIUM")
+
+		// Assert
+		assert.True(t, ok)
+		assert.Equal(t, "premium", tier.Name)
+		assert.Equal(t, "processor:gpu", tier.Image)
+	})
+
+	t.Run("should not find an unknown tier", func(t *testing.T) {
+		// Act
+		_, ok := resolver.Lookup("gold")
+
+		// Assert
+		assert.False(t, ok)
+	})
+}
+
+func TestTier_Defaults(t *testing.T) {
+	t.Run("should override only the fields set in the tier", func(t *testing.T) {
+		// Arrange
+		tier := Tier{Name: "premium", JobTier: config.JobTier{Image: "processor:gpu"}}
+
+		// Act & Assert
+		assert.Equal(t, "processor:gpu", tier.ImageOrDefault("processor:latest"))
+		assert.Equal(t, "process.sh", tier.CommandOrDefault("process.sh"))
+	})
+}
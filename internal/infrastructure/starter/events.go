@@ -0,0 +1,105 @@
+package starter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Event is an S3 event notification, as delivered to SQS or Lambda
+type S3Event struct {
+	Records []S3EventRecord `json:"Records"`
+}
+
+type S3EventRecord struct {
+	EventVersion      string              `json:"eventVersion"`
+	EventSource       string              `json:"eventSource"`
+	AwsRegion         string              `json:"awsRegion"`
+	EventTime         time.Time           `json:"eventTime"`
+	EventName         string              `json:"eventName"`
+	UserIdentity      S3UserIdentity      `json:"userIdentity"`
+	RequestParameters S3RequestParameters `json:"requestParameters"`
+	ResponseElements  map[string]string   `json:"responseElements"`
+	S3                S3Record            `json:"s3"`
+}
+
+// IsObjectCreated tells if the record notifies a new object, such as "ObjectCreated:Put"
+func (r S3EventRecord) IsObjectCreated() bool {
+	return strings.HasPrefix(r.EventName, "ObjectCreated:")
+}
+
+// IsObjectRemoved tells if the record notifies a removed object, such as "ObjectRemoved:Delete"
+func (r S3EventRecord) IsObjectRemoved() bool {
+	return strings.HasPrefix(r.EventName, "ObjectRemoved:")
+}
+
+type S3UserIdentity struct {
+	PrincipalId string `json:"principalId"`
+}
+
+type S3RequestParameters struct {
+	SourceIPAddress string `json:"sourceIPAddress"`
+}
+
+type S3Record struct {
+	SchemaVersion   string   `json:"s3SchemaVersion"`
+	ConfigurationId string   `json:"configurationId"`
+	Bucket          S3Bucket `json:"bucket"`
+	Object          S3Object `json:"object"`
+}
+
+type S3Bucket struct {
+	Name          string         `json:"name"`
+	OwnerIdentity S3UserIdentity `json:"ownerIdentity"`
+	Arn           string         `json:"arn"`
+}
+
+type S3Object struct {
+	// Key is URL encoded in the notifications, such as "my+video.mp4" for "my video.mp4"
+	Key       string `json:"key"`
+	Size      int64  `json:"size"`
+	ETag      string `json:"eTag"`
+	VersionId string `json:"versionId"`
+	// Sequencer orders the events of a key, as a hexadecimal value of varying length
+	Sequencer string `json:"sequencer"`
+}
+
+// compareSequencers compares the sequencers of two events of the same key, returning a
+// negative number when a happened before b, zero when they are the same event and a positive
+// number when a happened after b. The shorter sequencer is right padded with zeros, as S3 asks.
+func compareSequencers(a, b string) int {
+	a, b = strings.ToUpper(a), strings.ToUpper(b)
+	if len(a) < len(b) {
+		a += strings.Repeat("0", len(b)-len(a))
+	} else {
+		b += strings.Repeat("0", len(a)-len(b))
+	}
+	return strings.Compare(a, b)
+}
+
+// DecodedKey returns the key of the object as stored in S3
+func (o S3Object) DecodedKey() (string, error) {
+	key, err := url.QueryUnescape(o.Key)
+	if err != nil {
+		return "", fmt.Errorf("invalid object key %q: %w", o.Key, err)
+	}
+	return key, nil
+}
+
+// ControlMessage is a command sent to the upload queue instead of an S3 event
+type ControlMessage struct {
+	Action  string `json:"action"`
+	VideoId int64  `json:"video_id"`
+	UserId  int64  `json:"user_id"`
+	// Bucket, Key and Template are used by the reprocess action. Template selects one of
+	// the configured job tiers instead of the tier of the user.
+	Bucket   string `json:"bucket,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Template string `json:"template,omitempty"`
+}
+
+const (
+	ActionCancel    = "cancel"
+	ActionReprocess = "reprocess"
+)
@@ -0,0 +1,232 @@
+package starter
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/admission"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/priority"
+)
+
+// JobScheduler creates the jobs of a video on Kubernetes. It admits the video against the
+// limits of the namespace and the user, picks the tier of the jobs and splits the video into
+// chunks as configured.
+type JobScheduler struct {
+	cfg          *config.Config
+	logger       *logger.Logger
+	k8sAPI       api.K8sAPIInterface
+	admission    *admission.Admission
+	tierResolver *priority.Resolver
+}
+
+func NewJobScheduler(cfg *config.Config, logger *logger.Logger, k8sAPI api.K8sAPIInterface, admission *admission.Admission, tierResolver *priority.Resolver) *JobScheduler {
+	return &JobScheduler{
+		cfg:          cfg,
+		logger:       logger,
+		k8sAPI:       k8sAPI,
+		admission:    admission,
+		tierResolver: tierResolver,
+	}
+}
+
+// ScheduleVideoJobs creates the checker job, then the processor job of a video
+func (j *JobScheduler) ScheduleVideoJobs(ctx context.Context, video dto.ScheduleVideoJobsInput) error {
+	ctx = logger.WithFields(ctx, "videoId", video.VideoId, "userId", video.UserId)
+	tier := j.resolveTier(ctx, video)
+	completions := jobCompletions(j.cfg, video.Metadata, video.Size)
+
+	// Leave the message in the queue when the namespace or the user is over the limit
+	if err := j.admission.Admit(ctx, video.UserId); err != nil {
+		return fmt.Errorf("error admitting video %d: %w", video.VideoId, err)
+	}
+
+	// Create job checker
+	j.logger.InfoContext(ctx, "Creating job checker", "jobName", video.CheckerJobName)
+	err := j.k8sAPI.CreateJob(ctx, &api.JobInput{
+		Namespace:          j.cfg.K8S.Namespace,
+		JobName:            video.CheckerJobName,
+		Image:              j.cfg.K8S.Job.ImageChecker,
+		ServiceAccountName: j.cfg.K8S.ServiceAccountName,
+		Labels:             jobLabels(api.ComponentChecker, video, tier),
+		Annotations:        jobAnnotations(video),
+		Envs: checkerEnvs(map[string]string{
+			"JOB_NAME":                           video.JobName,
+			"JOB_NAMESPACE":                      j.cfg.K8S.Namespace,
+			"JOB_VIDEO_ID":                       strconv.FormatInt(video.VideoId, 10),
+			"JOB_USER_ID":                        strconv.FormatInt(video.UserId, 10),
+			"JOB_COMPLETIONS":                    strconv.FormatInt(int64(completions), 10),
+			"JOB_ATTEMPT":                        strconv.Itoa(video.Attempt),
+			"AWS_ACCESS_KEY_ID":                  j.cfg.AWS.AccessKey,
+			"AWS_SECRET_ACCESS_KEY":              j.cfg.AWS.SecretAccessKey,
+			"AWS_SESSION_TOKEN":                  j.cfg.AWS.SessionToken,
+			"AWS_REGION":                         j.cfg.AWS.Region,
+			"AWS_SNS_TOPIC_ARN":                  j.cfg.AWS.SNS.TopicArn,
+			"AWS_SQS_QUEUE_URL":                  j.cfg.AWS.SQS.QueueURL,
+			"AWS_SQS_PRIORITY_QUEUES":            priorityQueues(j.cfg.Priority.Queues),
+			"K8S_NAMESPACE":                      j.cfg.K8S.Namespace,
+			"K8S_JOB_NAME":                       video.JobName,
+			"K8S_JOB_IMAGE":                      j.cfg.K8S.Job.Image,
+			"K8S_JOB_COMMAND":                    j.cfg.K8S.Job.Command,
+			"K8S_JOB_PREFIX":                     j.cfg.K8S.Job.Prefix,
+			"K8S_JOB_BACK_OFF_LIMIT":             strconv.FormatInt(int64(j.cfg.K8S.Job.BackOffLimit), 10),
+			"K8S_JOB_IMAGE_CHECKER":              j.cfg.K8S.Job.ImageChecker,
+			"K8S_JOB_TTL_SECONDS_AFTER_FINISHED": j.cfg.K8S.Job.TtlSecondsAfterFinished.String(),
+			"ENVIRONMENT":                        j.cfg.Environment,
+			"LOG_LEVEL":                          j.cfg.LogLevel,
+			"LOG_FORMAT":                         j.cfg.LogFormat,
+			"OTEL_EXPORTER_OTLP_ENDPOINT":        j.cfg.OTLPEndpoint,
+		}),
+		TtlSecondsAfterFinished: j.cfg.K8S.Job.TtlSecondsAfterFinished,
+		StartTimeout:            j.cfg.K8S.Job.StartTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating job checker: %w", domain.WithJob(video.CheckerJobName, err))
+	}
+
+	// Create main job
+	j.logger.InfoContext(ctx, "Creating job", "jobName", video.JobName, "completions", completions, "tier", tier.Name, "attempt", video.Attempt)
+	err = j.k8sAPI.CreateJob(ctx, &api.JobInput{
+		Namespace:         j.cfg.K8S.Namespace,
+		JobName:           video.JobName,
+		Image:             tier.ImageOrDefault(j.cfg.K8S.Job.Image),
+		Cmd:               tier.CommandOrDefault(j.cfg.K8S.Job.Command),
+		PriorityClassName: tier.PriorityClassName,
+		Suspend:           j.cfg.K8S.Job.Suspend,
+		QueueLabel:        j.cfg.K8S.Job.QueueLabel,
+		QueueName:         j.cfg.K8S.Job.QueueName,
+		Labels:            jobLabels(api.ComponentProcessor, video, tier),
+		Annotations:       jobAnnotations(video),
+		Envs: processorEnvs(j.cfg.K8S.Job.Envs, map[string]string{
+			"VIDEO_KEY":             video.Key,
+			"VIDEO_BUCKET":          video.Bucket,
+			"VIDEO_VERSION_ID":      video.VersionId,
+			"PROCESSED_BUCKET":      video.Bucket,
+			"VIDEO_ID":              strconv.FormatInt(video.VideoId, 10),
+			"VIDEO_USER_ID":         strconv.FormatInt(video.UserId, 10),
+			"SNS_TOPIC_ARN":         j.cfg.AWS.SNS.TopicArn,
+			"AWS_ACCESS_KEY_ID":     j.cfg.AWS.AccessKey,
+			"AWS_SECRET_ACCESS_KEY": j.cfg.AWS.SecretAccessKey,
+			"AWS_SESSION_TOKEN":     j.cfg.AWS.SessionToken,
+			"AWS_REGION":            j.cfg.AWS.Region,
+			"VIDEO_CHUNKS":          strconv.FormatInt(int64(completions), 10),
+		}),
+		TtlSecondsAfterFinished: j.cfg.K8S.Job.TtlSecondsAfterFinished,
+		StartTimeout:            j.cfg.K8S.Job.StartTimeout,
+		Completions:             completions,
+		Parallelism:             j.cfg.K8S.Job.Indexed.Parallelism,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating job: %w", domain.WithJob(video.JobName, err))
+	}
+
+	return nil
+}
+
+// resolveTier returns the tier selected by the template of the video, or else the tier of its user
+func (j *JobScheduler) resolveTier(ctx context.Context, video dto.ScheduleVideoJobsInput) priority.Tier {
+	if tier, ok := j.tierResolver.Lookup(video.Template); ok {
+		return tier
+	}
+	if video.Template != "" {
+		j.logger.WarnContext(ctx, "Unknown template, using the tier of the user", "template", video.Template)
+	}
+
+	tier, err := j.tierResolver.Resolve(ctx, video.Metadata, video.UserId)
+	if err != nil {
+		j.logger.WarnContext(ctx, "Using default tier", "error", err.Error(), "tier", tier.Name)
+	}
+	return tier
+}
+
+func jobLabels(component string, video dto.ScheduleVideoJobsInput, tier priority.Tier) map[string]string {
+	return map[string]string{
+		api.LabelManagedBy:   api.ManagedByJobStarter,
+		api.LabelComponent:   component,
+		api.LabelVideoID:     strconv.FormatInt(video.VideoId, 10),
+		api.LabelUserID:      strconv.FormatInt(video.UserId, 10),
+		api.LabelTier:        tier.Name,
+		api.LabelAttempt:     strconv.Itoa(video.Attempt),
+		api.LabelRevision:    strconv.Itoa(video.Revision),
+		api.LabelVideoObject: api.ObjectLabel(video.Bucket, video.Key),
+	}
+}
+
+// checkerEnvs adds the OTEL_* variables of the starter to the envs of the checker, such as the
+// headers and timeout of the exporter, so its spans reach the same collector. The checker keeps
+// its own service name.
+func checkerEnvs(envs map[string]string) map[string]string {
+	for _, variable := range os.Environ() {
+		key, value, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(key, "OTEL_") || key == "OTEL_SERVICE_NAME" {
+			continue
+		}
+		if _, ok := envs[key]; !ok {
+			envs[key] = value
+		}
+	}
+	return envs
+}
+
+// processorEnvs adds the configured envs to the envs of the pipeline, which take precedence
+func processorEnvs(configured, pipeline map[string]string) map[string]string {
+	envs := maps.Clone(configured)
+	if envs == nil {
+		envs = make(map[string]string, len(pipeline))
+	}
+	maps.Copy(envs, pipeline)
+	return envs
+}
+
+func jobAnnotations(video dto.ScheduleVideoJobsInput) map[string]string {
+	annotations := map[string]string{
+		api.AnnotationVideoBucket: video.Bucket,
+		api.AnnotationVideoKey:    video.Key,
+	}
+	if video.Sequencer != "" {
+		annotations[api.AnnotationVideoSequencer] = video.Sequencer
+	}
+	if video.ETag != "" {
+		annotations[api.AnnotationVideoETag] = video.ETag
+	}
+	return annotations
+}
+
+// priorityQueues formats the queues as read from AWS_SQS_PRIORITY_QUEUES, so the checker loads
+// a valid configuration when the starter polls only priority queues
+func priorityQueues(queues []config.WeightedQueue) string {
+	entries := make([]string, 0, len(queues))
+	for _, queue := range queues {
+		entries = append(entries, fmt.Sprintf("%s=%d", queue.URL, queue.Weight))
+	}
+	return strings.Join(entries, ",")
+}
+
+// jobCompletions returns how many chunks the video should be split into. The "chunks" metadata
+// hint takes precedence over the object size, and the result is capped by the configured maximum.
+func jobCompletions(cfg *config.Config, metadata map[string]string, size int64) int32 {
+	indexed := cfg.K8S.Job.Indexed
+	if !indexed.Enabled {
+		return 1
+	}
+
+	completions := int64(1)
+	if hint, err := strconv.ParseInt(metadata["chunks"], 10, 32); err == nil && hint > 0 {
+		completions = hint
+	} else if indexed.ChunkSizeBytes > 0 && size > 0 {
+		completions = (size + indexed.ChunkSizeBytes - 1) / indexed.ChunkSizeBytes
+	}
+
+	if indexed.MaxCompletions > 0 && completions > int64(indexed.MaxCompletions) {
+		completions = int64(indexed.MaxCompletions)
+	}
+	return int32(completions)
+}
@@ -0,0 +1,377 @@
+package starter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/port"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/usecase"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/admission"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/priority"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Starter turns upload queue messages into processor and checker jobs
+type Starter struct {
+	cfg           *config.Config
+	logger        *logger.Logger
+	k8sAPI        api.K8sAPIInterface
+	objects       port.ObjectGateway
+	videoUsecase  port.VideoUsecase
+	startVideoJob port.StartVideoJobUsecase
+
+	// current holds the settings applied to new messages, replaced as a whole by Reload
+	current *atomic.Pointer[settings]
+}
+
+// settings are the parts of the Starter that follow the configuration
+type settings struct {
+	cfg           *config.Config
+	startVideoJob port.StartVideoJobUsecase
+}
+
+func NewStarter(
+	cfg *config.Config,
+	logger *logger.Logger,
+	k8sAPI api.K8sAPIInterface,
+	objects port.ObjectGateway,
+	videoUsecase port.VideoUsecase,
+	userTiers port.UserTierGateway,
+) *Starter {
+	s := &Starter{
+		logger:       logger,
+		k8sAPI:       k8sAPI,
+		objects:      objects,
+		videoUsecase: videoUsecase,
+		current:      &atomic.Pointer[settings]{},
+	}
+	s.current.Store(s.newSettings(cfg, userTiers))
+	return s
+}
+
+func (s *Starter) newSettings(cfg *config.Config, userTiers port.UserTierGateway) *settings {
+	jobs := NewJobScheduler(cfg, s.logger, s.k8sAPI, admission.NewAdmission(s.k8sAPI, cfg), priority.NewResolver(cfg, userTiers))
+	return &settings{
+		cfg:           cfg,
+		startVideoJob: usecase.NewStartVideoJobUsecase(s.objects, jobs, s.videoUsecase, cfg.K8S.Job.Prefix),
+	}
+}
+
+// Reload applies a new configuration to the messages received from now on. Messages being
+// processed keep the configuration they started with, and the settings only applied on startup
+// keep their value until a restart. It returns the settings that changed, those included.
+func (s *Starter) Reload(cfg *config.Config, userTiers port.UserTierGateway) []string {
+	previous := s.current.Load()
+	s.current.Store(s.newSettings(config.KeepStartupSettings(previous.cfg, cfg), userTiers))
+
+	changes := config.Diff(previous.cfg, cfg)
+	if len(changes) == 0 {
+		s.logger.Info("Configuration reloaded without changes")
+	} else {
+		s.logger.Info("Configuration reloaded", "changes", changes)
+	}
+	return changes
+}
+
+// HandleMessage processes a control message or an S3 event received from the upload queue.
+// It follows the SqsHandler processor contract: messages that can never be processed fail
+// with a domain.Error of a permanent code, other failures are retried.
+func (s *Starter) HandleMessage(ctx context.Context, message types.Message) error {
+	return s.withCurrentSettings().handleMessage(ctx, message)
+}
+
+// withCurrentSettings returns a copy of the Starter bound to the current settings, so that a
+// reload in the middle of a message doesn't mix two configurations
+func (s *Starter) withCurrentSettings() *Starter {
+	current := s.current.Load()
+	bound := *s
+	bound.cfg = current.cfg
+	bound.startVideoJob = current.startVideoJob
+	return &bound
+}
+
+func (s *Starter) handleMessage(ctx context.Context, message types.Message) error {
+	s.logger.InfoContext(ctx, "Processing message", "message", message)
+
+	var controlMessage ControlMessage
+	if err := json.Unmarshal([]byte(*message.Body), &controlMessage); err == nil && controlMessage.Action != "" {
+		if err := s.processControlMessage(ctx, controlMessage); err != nil {
+			s.logger.ErrorContext(ctx, "Failed to process control message", "error", err.Error())
+			return err
+		}
+		return nil
+	}
+
+	var s3Event S3Event
+	if err := json.Unmarshal([]byte(*message.Body), &s3Event); err != nil {
+		return domain.NewError(domain.CodeInvalidS3Event, "failed to unmarshal S3 event", err)
+	}
+
+	for _, record := range s3Event.Records {
+		switch {
+		case record.IsObjectRemoved():
+			if err := s.cancelRemovedObject(ctx, record); err != nil {
+				s.logger.ErrorContext(ctx, "Failed to cancel removed object", "error", err.Error())
+				return err
+			}
+		case record.IsObjectCreated():
+			if err := s.processS3Record(ctx, record); err != nil {
+				s.logger.ErrorContext(ctx, "Failed to process message", "error", err.Error())
+				return err
+			}
+		default:
+			s.logger.InfoContext(ctx, "Ignoring S3 event", "eventName", record.EventName, "key", record.S3.Object.Key)
+		}
+	}
+
+	return nil
+}
+
+func (s *Starter) processS3Record(ctx context.Context, record S3EventRecord) error {
+	key, err := objectKey(record)
+	if err != nil {
+		return err
+	}
+	object := record.S3.Object
+	s.logger.InfoContext(ctx, "Processing S3 record",
+		"key", key,
+		"bucket", record.S3.Bucket.Name,
+		"versionId", object.VersionId,
+		"sequencer", object.Sequencer,
+		"eTag", object.ETag,
+		"eventName", record.EventName,
+		"eventTime", record.EventTime,
+	)
+
+	revision, start, err := s.orderObjectEvent(ctx, record.S3.Bucket.Name, key, object)
+	if err != nil || !start {
+		return err
+	}
+
+	output, err := s.startVideoJob.StartVideoJob(ctx, dto.StartVideoJobInput{
+		Bucket:    record.S3.Bucket.Name,
+		Key:       key,
+		VersionId: object.VersionId,
+		Sequencer: object.Sequencer,
+		ETag:      object.ETag,
+		Attempt:   1,
+		Revision:  revision,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "Video jobs created", "videoId", output.VideoId, "userId", output.UserId, "jobName", output.JobName)
+	return nil
+}
+
+// orderObjectEvent compares an upload event with the event the current jobs of the object were
+// created for. It tells whether to start jobs for the event, and the revision to start them as:
+//   - the same event delivered again, or an event older than the jobs, is skipped
+//   - an overwrite with the same content (eTag) while the jobs are running is skipped
+//   - a newer event supersedes the jobs, which are deleted, and starts the next revision
+//   - the jobs of an event whose processor job wasn't created, such as a checker left by a
+//     failed creation, are superseded the same way by the event delivered again
+//
+// The next revision is a new upload rather than a reprocessing: its checker announces it as
+// UPLOADED, so the superseded jobs are deleted without publishing CANCELED.
+// Events without sequencer, and jobs created without one, can't be ordered and start revision 1.
+func (s *Starter) orderObjectEvent(ctx context.Context, bucket, key string, object S3Object) (revision int, start bool, err error) {
+	if object.Sequencer == "" {
+		return 1, true, nil
+	}
+
+	jobs, err := s.k8sAPI.FindVideoJobsByObject(ctx, s.cfg.K8S.Namespace, bucket, key)
+	if err != nil {
+		return 0, false, fmt.Errorf("error finding jobs of object: %w", err)
+	}
+	latest, ok := latestObjectJob(jobs)
+	if !ok {
+		return 1, true, nil
+	}
+
+	// The latest event was only handled once its processor job was created
+	handled := slices.ContainsFunc(jobs, func(job api.VideoJob) bool {
+		return job.Component == api.ComponentProcessor && job.Sequencer == latest.Sequencer
+	})
+	inFlight := slices.ContainsFunc(jobs, isProcessing)
+	switch order := compareSequencers(object.Sequencer, latest.Sequencer); {
+	case order < 0:
+		s.logger.InfoContext(ctx, "Ignoring stale S3 event", "job", latest.Name, "jobSequencer", latest.Sequencer)
+		return 0, false, nil
+	case !handled:
+		s.logger.WarnContext(ctx, "Replacing the jobs of an event without processor job", "job", latest.Name, "jobSequencer", latest.Sequencer)
+	case order == 0:
+		s.logger.InfoContext(ctx, "Ignoring duplicate S3 event", "job", latest.Name)
+		return 0, false, nil
+	case inFlight && object.ETag != "" && object.ETag == latest.ETag:
+		s.logger.InfoContext(ctx, "Ignoring S3 event with the content being processed", "job", latest.Name)
+		return 0, false, nil
+	}
+
+	// Delete the jobs of the previous version, whose names the next revision doesn't reuse
+	s.logger.InfoContext(ctx, "Superseding the jobs of the previous version", "job", latest.Name, "jobSequencer", latest.Sequencer)
+	videoIds := make(map[int64]bool)
+	for _, job := range jobs {
+		videoIds[job.VideoId] = true
+		revision = max(revision, job.Revision)
+	}
+	for videoId := range videoIds {
+		if _, err := s.k8sAPI.CancelVideoJobs(ctx, s.cfg.K8S.Namespace, videoId); err != nil {
+			return 0, false, fmt.Errorf("error deleting jobs of video %d: %w", videoId, err)
+		}
+	}
+	return revision + 1, true, nil
+}
+
+// latestObjectJob returns the job created for the latest event of an object, among the jobs
+// that know their event
+func latestObjectJob(jobs []api.VideoJob) (api.VideoJob, bool) {
+	var latest api.VideoJob
+	found := false
+	for _, job := range jobs {
+		if job.Sequencer == "" {
+			continue
+		}
+		if !found || compareSequencers(job.Sequencer, latest.Sequencer) > 0 {
+			latest, found = job, true
+		}
+	}
+	return latest, found
+}
+
+func (s *Starter) processControlMessage(ctx context.Context, message ControlMessage) error {
+	switch message.Action {
+	case ActionCancel:
+		return s.cancelVideo(ctx, message.VideoId, message.UserId)
+	case ActionReprocess:
+		return s.reprocessVideo(ctx, message)
+	default:
+		return domain.NewError(domain.CodeInvalidControlMessage, fmt.Sprintf("unknown control action: %s", message.Action), nil)
+	}
+}
+
+// cancelRemovedObject cancels the videos being processed from an S3 object that was removed
+func (s *Starter) cancelRemovedObject(ctx context.Context, record S3EventRecord) error {
+	key, err := objectKey(record)
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Object removed", "key", key, "bucket", record.S3.Bucket.Name)
+
+	jobs, err := s.k8sAPI.FindVideoJobsByObject(ctx, s.cfg.K8S.Namespace, record.S3.Bucket.Name, key)
+	if err != nil {
+		return fmt.Errorf("error finding jobs of removed object: %w", err)
+	}
+
+	videoIds := make(map[int64]int64)
+	for _, job := range jobs {
+		videoIds[job.VideoId] = job.UserId
+	}
+	for videoId, userId := range videoIds {
+		if err := s.cancelVideo(ctx, videoId, userId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancelVideo deletes the jobs of a video and publishes the canceled status when the video
+// was still being processed
+func (s *Starter) cancelVideo(ctx context.Context, videoId, userId int64) error {
+	ctx = logger.WithFields(ctx, "videoId", videoId)
+	s.logger.InfoContext(ctx, "Canceling video")
+
+	jobs, err := s.k8sAPI.CancelVideoJobs(ctx, s.cfg.K8S.Namespace, videoId)
+	if err != nil {
+		return fmt.Errorf("error canceling jobs of video %d: %w", videoId, err)
+	}
+
+	inFlight := false
+	for _, job := range jobs {
+		if userId == 0 {
+			userId = job.UserId
+		}
+		if isProcessing(job) {
+			inFlight = true
+		}
+	}
+	if !inFlight {
+		s.logger.InfoContext(ctx, "Video is not being processed, nothing to cancel", "deletedJobs", len(jobs))
+		return nil
+	}
+
+	s.logger.InfoContext(ctx, "Video canceled", "deletedJobs", len(jobs))
+	return s.videoUsecase.UpdateVideoStatus(ctx, dto.UpdateVideoStatusInput{
+		VideoId: videoId,
+		UserId:  userId,
+		Status:  dto.VideoStatusCanceled,
+	})
+}
+
+// reprocessVideo replaces the finished jobs of a video with new ones, named after the next attempt
+func (s *Starter) reprocessVideo(ctx context.Context, message ControlMessage) error {
+	// The job scheduler adds the video and the user to the fields of ctx
+	s.logger.InfoContext(ctx, "Reprocessing video", "videoId", message.VideoId, "bucket", message.Bucket, "key", message.Key)
+
+	if message.VideoId == 0 || message.Bucket == "" || message.Key == "" {
+		return domain.NewError(domain.CodeInvalidControlMessage, "reprocess requires video_id, bucket and key", nil)
+	}
+
+	jobs, err := s.k8sAPI.ListVideoJobs(ctx, s.cfg.K8S.Namespace, message.VideoId)
+	if err != nil {
+		return fmt.Errorf("error listing jobs of video %d: %w", message.VideoId, err)
+	}
+
+	attempt := 1
+	latest, _ := latestObjectJob(jobs)
+	for _, job := range jobs {
+		if isProcessing(job) {
+			s.logger.WarnContext(ctx, "Video is still being processed, ignoring reprocess", "videoId", message.VideoId, "job", job.Name)
+			return nil
+		}
+		attempt = max(attempt, job.Attempt)
+	}
+	attempt++
+
+	for _, job := range jobs {
+		if err := s.k8sAPI.DeleteJob(ctx, s.cfg.K8S.Namespace, job.Name); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.startVideoJob.StartVideoJob(ctx, dto.StartVideoJobInput{
+		Bucket:    message.Bucket,
+		Key:       message.Key,
+		VideoId:   message.VideoId,
+		UserId:    message.UserId,
+		Sequencer: latest.Sequencer,
+		ETag:      latest.ETag,
+		Attempt:   attempt,
+		Revision:  max(latest.Revision, 1),
+		Template:  message.Template,
+	})
+	return err
+}
+
+// objectKey returns the decoded key of the object of a record
+func objectKey(record S3EventRecord) (string, error) {
+	key, err := record.S3.Object.DecodedKey()
+	if err != nil {
+		return "", domain.NewError(domain.CodeInvalidS3Event, "failed to decode object key", err)
+	}
+	return key, nil
+}
+
+// isProcessing tells if the job is a processor job that has not finished yet
+func isProcessing(job api.VideoJob) bool {
+	return job.Component == api.ComponentProcessor && job.Status != api.JobStatusComplete && job.Status != api.JobStatusFailed
+}
@@ -0,0 +1,203 @@
+package starter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+	mocks "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api/mocks"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+)
+
+const (
+	testBucket = "uploads"
+	testKey    = "videos/movie.mp4"
+)
+
+func newTestStarter(k8sAPI api.K8sAPIInterface) *Starter {
+	cfg := &config.Config{}
+	cfg.K8S.Namespace = "video"
+	return &Starter{
+		cfg:    cfg,
+		logger: &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))},
+		k8sAPI: k8sAPI,
+	}
+}
+
+// objectJob returns a job of video 42 created for the upload event of sequencer and eTag
+func objectJob(component, status, sequencer, eTag string, revision int) api.VideoJob {
+	return api.VideoJob{
+		Name:      "video-processor-movie-" + component,
+		Component: component,
+		Status:    status,
+		VideoId:   42,
+		UserId:    7,
+		Bucket:    testBucket,
+		Key:       testKey,
+		Sequencer: sequencer,
+		ETag:      eTag,
+		Attempt:   1,
+		Revision:  revision,
+	}
+}
+
+func TestStarter_orderObjectEvent(t *testing.T) {
+	const (
+		sequencer   = "0055AED6DCD90281E5"
+		older       = "0055AED6DCD90281"
+		newer       = "0055AED6DCD9028300"
+		eTag        = "d41d8cd98f00b204e9800998ecf8427e"
+		changedETag = "9b2cf535f27731c974343645a3985328"
+		checker     = api.ComponentChecker
+		processor   = api.ComponentProcessor
+		running     = api.JobStatusRunning
+		complete    = api.JobStatusComplete
+	)
+
+	tests := []struct {
+		name         string
+		object       S3Object
+		jobs         []api.VideoJob
+		wantRevision int
+		wantStart    bool
+		wantDeleted  bool
+	}{
+		{
+			name:         "should start the first revision of an object without jobs",
+			object:       S3Object{Sequencer: sequencer, ETag: eTag},
+			wantRevision: 1,
+			wantStart:    true,
+		},
+		{
+			name:   "should skip the event delivered again",
+			object: S3Object{Sequencer: sequencer, ETag: eTag},
+			jobs:   []api.VideoJob{objectJob(processor, running, sequencer, eTag, 1), objectJob(checker, running, sequencer, eTag, 1)},
+		},
+		{
+			name:   "should skip an event delivered again once the jobs finished",
+			object: S3Object{Sequencer: sequencer, ETag: eTag},
+			jobs:   []api.VideoJob{objectJob(processor, complete, sequencer, eTag, 1)},
+		},
+		{
+			name:   "should skip an event older than the jobs",
+			object: S3Object{Sequencer: older, ETag: changedETag},
+			jobs:   []api.VideoJob{objectJob(processor, running, sequencer, eTag, 1)},
+		},
+		{
+			name:   "should skip an overwrite with the content in flight",
+			object: S3Object{Sequencer: newer, ETag: eTag},
+			jobs:   []api.VideoJob{objectJob(processor, running, sequencer, eTag, 1)},
+		},
+		{
+			name:         "should supersede the jobs in flight with a newer content",
+			object:       S3Object{Sequencer: newer, ETag: changedETag},
+			jobs:         []api.VideoJob{objectJob(processor, running, sequencer, eTag, 1), objectJob(checker, running, sequencer, eTag, 1)},
+			wantRevision: 2,
+			wantStart:    true,
+			wantDeleted:  true,
+		},
+		{
+			name:         "should supersede the finished jobs with an overwrite of the same content",
+			object:       S3Object{Sequencer: newer, ETag: eTag},
+			jobs:         []api.VideoJob{objectJob(processor, complete, sequencer, eTag, 1)},
+			wantRevision: 2,
+			wantStart:    true,
+			wantDeleted:  true,
+		},
+		{
+			name:         "should start the revision after the latest one",
+			object:       S3Object{Sequencer: newer, ETag: changedETag},
+			jobs:         []api.VideoJob{objectJob(processor, complete, sequencer, eTag, 3)},
+			wantRevision: 4,
+			wantStart:    true,
+			wantDeleted:  true,
+		},
+		{
+			name:         "should replace the jobs of the event whose processor job wasn't created",
+			object:       S3Object{Sequencer: sequencer, ETag: eTag},
+			jobs:         []api.VideoJob{objectJob(checker, running, sequencer, eTag, 1)},
+			wantRevision: 2,
+			wantStart:    true,
+			wantDeleted:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			ctrl := gomock.NewController(t)
+			mockK8sAPI := mocks.NewMockK8sAPIInterface(ctrl)
+			s := newTestStarter(mockK8sAPI)
+
+			mockK8sAPI.EXPECT().FindVideoJobsByObject(gomock.Any(), "video", testBucket, testKey).Return(tt.jobs, nil)
+			if tt.wantDeleted {
+				mockK8sAPI.EXPECT().CancelVideoJobs(gomock.Any(), "video", int64(42)).Return(tt.jobs, nil)
+			}
+
+			// Act
+			revision, start, err := s.orderObjectEvent(context.Background(), testBucket, testKey, tt.object)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantRevision, revision)
+		})
+	}
+
+	t.Run("should start the first revision of an event without sequencer", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		s := newTestStarter(mocks.NewMockK8sAPIInterface(ctrl))
+
+		// Act
+		revision, start, err := s.orderObjectEvent(context.Background(), testBucket, testKey, S3Object{ETag: eTag})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, start)
+		assert.Equal(t, 1, revision)
+	})
+
+	t.Run("should return error when the jobs of the object can't be found", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockK8sAPI := mocks.NewMockK8sAPIInterface(ctrl)
+		s := newTestStarter(mockK8sAPI)
+		expectedError := errors.New("connection refused")
+
+		mockK8sAPI.EXPECT().FindVideoJobsByObject(gomock.Any(), "video", testBucket, testKey).Return(nil, expectedError)
+
+		// Act
+		_, start, err := s.orderObjectEvent(context.Background(), testBucket, testKey, S3Object{Sequencer: sequencer})
+
+		// Assert
+		assert.ErrorIs(t, err, expectedError)
+		assert.False(t, start)
+	})
+
+	t.Run("should return error when the superseded jobs can't be deleted", func(t *testing.T) {
+		// Arrange
+		ctrl := gomock.NewController(t)
+		mockK8sAPI := mocks.NewMockK8sAPIInterface(ctrl)
+		s := newTestStarter(mockK8sAPI)
+		expectedError := errors.New("connection refused")
+
+		mockK8sAPI.EXPECT().FindVideoJobsByObject(gomock.Any(), "video", testBucket, testKey).
+			Return([]api.VideoJob{objectJob(processor, running, sequencer, eTag, 1)}, nil)
+		mockK8sAPI.EXPECT().CancelVideoJobs(gomock.Any(), "video", int64(42)).Return(nil, expectedError)
+
+		// Act
+		_, start, err := s.orderObjectEvent(context.Background(), testBucket, testKey, S3Object{Sequencer: newer, ETag: changedETag})
+
+		// Assert
+		assert.ErrorIs(t, err, expectedError)
+		assert.False(t, start)
+	})
+}
@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda"
+
+// Environment variables carrying the trace context into the jobs, named after the
+// W3C Trace Context headers
+const (
+	EnvTraceParent = "TRACEPARENT"
+	EnvTraceState  = "TRACESTATE"
+)
+
+// propagator reads and writes the W3C Trace Context and Baggage
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Setup installs the tracer provider of the service. Spans are exported with OTLP over HTTP
+// to endpoint, such as http://localhost:4318, or dropped when endpoint is empty.
+// The returned function flushes the spans left and must be called before exiting.
+func Setup(ctx context.Context, serviceName string, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("error creating tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Flush exports the spans ended so far without shutting the tracer provider down, such as at
+// the end of a Lambda invocation. It does nothing when tracing isn't set up.
+func Flush(ctx context.Context) error {
+	if provider, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); ok {
+		return provider.ForceFlush(ctx)
+	}
+	return nil
+}
+
+// Start starts a span with the tracer of the module
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// RecordError marks the span as failed with err
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Inject returns the trace context of ctx as W3C Trace Context fields, such as traceparent,
+// or an empty map when ctx has no span
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier
+}
+
+// InjectEnv returns the trace context of ctx as the TRACEPARENT and TRACESTATE variables
+func InjectEnv(ctx context.Context) map[string]string {
+	envs := make(map[string]string)
+	for key, value := range Inject(ctx) {
+		envs[strings.ToUpper(key)] = value
+	}
+	return envs
+}
+
+// ExtractEnv continues the trace given to the job by the starter, if any
+func ExtractEnv(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{}
+	for _, key := range propagator.Fields() {
+		if value := os.Getenv(strings.ToUpper(key)); value != "" {
+			carrier[key] = value
+		}
+	}
+	return propagator.Extract(ctx, carrier)
+}
+
+// ExtractSQS continues the trace carried by the attributes of a queue message, if any
+func ExtractSQS(ctx context.Context, attributes map[string]sqstypes.MessageAttributeValue) context.Context {
+	carrier := propagation.MapCarrier{}
+	for _, key := range propagator.Fields() {
+		if attribute, ok := attributes[key]; ok && attribute.StringValue != nil {
+			carrier[key] = *attribute.StringValue
+		}
+	}
+	return propagator.Extract(ctx, carrier)
+}
+
+// InjectSNS adds the trace context of ctx to the attributes of a topic message
+func InjectSNS(ctx context.Context, attributes map[string]snstypes.MessageAttributeValue) {
+	for key, value := range Inject(ctx) {
+		attributes[key] = snstypes.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+}
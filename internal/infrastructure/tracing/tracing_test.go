@@ -0,0 +1,162 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// newSpanContext starts a sampled span, as a traced message would carry
+func newSpanContext(t *testing.T) (context.Context, trace.SpanContext) {
+	t.Helper()
+
+	provider := sdktrace.NewTracerProvider()
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	ctx, span := provider.Tracer("test").Start(context.Background(), "test")
+	t.Cleanup(func() { span.End() })
+	return ctx, span.SpanContext()
+}
+
+func TestInjectEnv(t *testing.T) {
+	t.Run("should pass the trace context as TRACEPARENT", func(t *testing.T) {
+		// Arrange
+		ctx, spanContext := newSpanContext(t)
+
+		// Act
+		envs := InjectEnv(ctx)
+
+		// Assert
+		require.Contains(t, envs, EnvTraceParent)
+		assert.Contains(t, envs[EnvTraceParent], spanContext.TraceID().String())
+	})
+
+	t.Run("should pass nothing without a span", func(t *testing.T) {
+		// Act
+		envs := InjectEnv(context.Background())
+
+		// Assert
+		assert.Empty(t, envs)
+	})
+}
+
+func TestExtractEnv(t *testing.T) {
+	t.Run("should continue the trace of TRACEPARENT", func(t *testing.T) {
+		// Arrange
+		t.Setenv(EnvTraceParent, testTraceParent)
+
+		// Act
+		ctx := ExtractEnv(context.Background())
+
+		// Assert
+		spanContext := trace.SpanContextFromContext(ctx)
+		assert.True(t, spanContext.IsRemote())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+	})
+
+	t.Run("should start no trace without TRACEPARENT", func(t *testing.T) {
+		// Arrange
+		t.Setenv(EnvTraceParent, "")
+
+		// Act
+		ctx := ExtractEnv(context.Background())
+
+		// Assert
+		assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+	})
+}
+
+func TestExtractSQS(t *testing.T) {
+	t.Run("should continue the trace of the message attributes", func(t *testing.T) {
+		// Arrange
+		attributes := map[string]sqstypes.MessageAttributeValue{
+			"traceparent": {DataType: aws.String("String"), StringValue: aws.String(testTraceParent)},
+		}
+
+		// Act
+		ctx := ExtractSQS(context.Background(), attributes)
+
+		// Assert
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.SpanContextFromContext(ctx).TraceID().String())
+	})
+
+	t.Run("should start no trace without attributes", func(t *testing.T) {
+		// Act
+		ctx := ExtractSQS(context.Background(), nil)
+
+		// Assert
+		assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+	})
+}
+
+func TestInjectSNS(t *testing.T) {
+	t.Run("should add the trace context to the message attributes", func(t *testing.T) {
+		// Arrange
+		ctx, spanContext := newSpanContext(t)
+		attributes := make(map[string]snstypes.MessageAttributeValue)
+
+		// Act
+		InjectSNS(ctx, attributes)
+
+		// Assert
+		require.Contains(t, attributes, "traceparent")
+		assert.Equal(t, "String", aws.ToString(attributes["traceparent"].DataType))
+		assert.Contains(t, aws.ToString(attributes["traceparent"].StringValue), spanContext.TraceID().String())
+	})
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("should not export without an endpoint", func(t *testing.T) {
+		// Act
+		shutdown, err := Setup(context.Background(), "job-starter", "")
+
+		// Assert
+		require.NoError(t, err)
+		assert.NoError(t, shutdown(context.Background()))
+	})
+}
+
+func TestFlush(t *testing.T) {
+	t.Run("should export the ended spans and keep the provider running", func(t *testing.T) {
+		// Arrange
+		exporter := tracetest.NewInMemoryExporter()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(provider)
+		t.Cleanup(func() {
+			otel.SetTracerProvider(previous)
+			_ = provider.Shutdown(context.Background())
+		})
+		_, span := Start(context.Background(), "sqs.process")
+		span.End()
+
+		// Act
+		err := Flush(context.Background())
+
+		// Assert
+		require.NoError(t, err)
+		assert.Len(t, exporter.GetSpans(), 1)
+		_, next := Start(context.Background(), "sqs.process")
+		next.End()
+		require.NoError(t, Flush(context.Background()))
+		assert.Len(t, exporter.GetSpans(), 2)
+	})
+
+	t.Run("should do nothing without tracing", func(t *testing.T) {
+		// Act
+		err := Flush(context.Background())
+
+		// Assert
+		assert.NoError(t, err)
+	})
+}
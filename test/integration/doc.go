@@ -0,0 +1,3 @@
+// Package integration runs the starter and the checker end to end against in-process
+// stand-ins for Kubernetes, SQS, SNS and S3, so no cluster or AWS account is needed.
+package integration
@@ -0,0 +1,308 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/dto"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/s3"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sqs"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/logger"
+)
+
+const (
+	testNamespace = "video-processing"
+	testBucket    = "test-bucket"
+	testKey       = "test-key"
+	testVersionId = "096fKKXTRTtl3on89fVO.nfljtsv6qko"
+	testVideoId   = 42
+	testUserId    = 7
+	testQueueURL  = "https://sqs.us-east-1.amazonaws.com/000000000000/uploads"
+	testDLQURL    = "https://sqs.us-east-1.amazonaws.com/000000000000/uploads-dlq"
+)
+
+// fakeSQS keeps the messages of a queue in memory
+type fakeSQS struct {
+	mu         sync.Mutex
+	nextId     int
+	messages   []types.Message
+	deleted    []string
+	visibility map[string]int
+	// forwarded holds the messages sent to other queues, such as the dead-letter queue
+	forwarded []types.Message
+}
+
+func newFakeSQS() *fakeSQS {
+	return &fakeSQS{visibility: make(map[string]int)}
+}
+
+// send enqueues a message body and returns its id
+func (f *fakeSQS) send(body string) string {
+	return f.sendWithAttributes(body, nil)
+}
+
+// sendWithAttributes enqueues a message body with string attributes, such as a trace context
+func (f *fakeSQS) sendWithAttributes(body string, attributes map[string]string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messageAttributes := make(map[string]types.MessageAttributeValue, len(attributes))
+	for name, value := range attributes {
+		messageAttributes[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+
+	f.nextId++
+	id := fmt.Sprintf("message-%d", f.nextId)
+	f.messages = append(f.messages, types.Message{
+		MessageId:         aws.String(id),
+		ReceiptHandle:     aws.String("receipt-" + id),
+		Body:              aws.String(body),
+		MessageAttributes: messageAttributes,
+	})
+	return id
+}
+
+func (f *fakeSQS) ReceiveMessages(ctx context.Context, queueURL string, maxMessages int, waitTimeSeconds int) ([]types.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	received := make([]types.Message, 0, maxMessages)
+	for _, message := range f.messages {
+		if len(received) == maxMessages {
+			break
+		}
+		if _, delayed := f.visibility[*message.ReceiptHandle]; delayed {
+			continue
+		}
+		received = append(received, message)
+	}
+	return received, nil
+}
+
+func (f *fakeSQS) DeleteMessage(ctx context.Context, queueURL string, receiptHandle string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, message := range f.messages {
+		if *message.ReceiptHandle == receiptHandle {
+			f.messages = append(f.messages[:i], f.messages[i+1:]...)
+			f.deleted = append(f.deleted, *message.MessageId)
+			return nil
+		}
+	}
+	return fmt.Errorf("receipt handle %s not found", receiptHandle)
+}
+
+func (f *fakeSQS) ChangeMessageVisibility(ctx context.Context, queueURL string, receiptHandle string, visibilityTimeout int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.visibility[receiptHandle] = visibilityTimeout
+	return nil
+}
+
+func (f *fakeSQS) DeleteMessageBatch(ctx context.Context, queueURL string, receiptHandles []string) ([]sqs.BatchEntryError, error) {
+	var failed []sqs.BatchEntryError
+	for _, receiptHandle := range receiptHandles {
+		if err := f.DeleteMessage(ctx, queueURL, receiptHandle); err != nil {
+			failed = append(failed, sqs.BatchEntryError{ReceiptHandle: receiptHandle, Code: "ReceiptHandleIsInvalid", Message: err.Error()})
+		}
+	}
+	return failed, nil
+}
+
+func (f *fakeSQS) ChangeMessageVisibilityBatch(ctx context.Context, queueURL string, receiptHandles []string, visibilityTimeout int) ([]sqs.BatchEntryError, error) {
+	for _, receiptHandle := range receiptHandles {
+		if err := f.ChangeMessageVisibility(ctx, queueURL, receiptHandle, visibilityTimeout); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, queueURL string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	if queueURL == testQueueURL {
+		id := f.sendWithAttributes(messageBody, attributes)
+		return &types.Message{MessageId: aws.String(id)}, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messageAttributes := make(map[string]types.MessageAttributeValue, len(attributes))
+	for name, value := range attributes {
+		messageAttributes[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+	f.nextId++
+	message := types.Message{
+		MessageId:         aws.String(fmt.Sprintf("message-%d", f.nextId)),
+		Body:              aws.String(messageBody),
+		MessageAttributes: messageAttributes,
+	}
+	f.forwarded = append(f.forwarded, message)
+	return &message, nil
+}
+
+func (f *fakeSQS) SendGroupMessage(ctx context.Context, queueURL string, groupId string, deduplicationId string, messageBody string, attributes map[string]string) (*types.Message, error) {
+	return f.SendMessage(ctx, queueURL, messageBody, attributes)
+}
+
+// fakeSNS records the status events published to the topic
+type fakeSNS struct {
+	mu       sync.Mutex
+	payloads []dto.VideoStatusPayload
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, message string) error {
+	var payload dto.VideoStatusPayload
+	if err := json.Unmarshal([]byte(message), &payload); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.payloads = append(f.payloads, payload)
+	return nil
+}
+
+// statuses returns the published statuses in order
+func (f *fakeSNS) statuses() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	statuses := make([]string, 0, len(f.payloads))
+	for _, payload := range f.payloads {
+		statuses = append(statuses, payload.Status)
+	}
+	return statuses
+}
+
+// fakeS3 answers HeadObject requests for the uploaded videos
+type fakeS3 struct {
+	objects map[string]s3.ObjectInfo
+	// versions holds the version requested by each HeadObject request
+	versions []string
+}
+
+func (f *fakeS3) GetObjectInfo(ctx context.Context, bucket string, key string, versionId string) (*s3.ObjectInfo, error) {
+	f.versions = append(f.versions, versionId)
+	info, ok := f.objects[bucket+"/"+key]
+	if !ok {
+		return nil, fmt.Errorf("object %s/%s not found", bucket, key)
+	}
+	return &info, nil
+}
+
+func (f *fakeS3) GetObjectMetadata(ctx context.Context, bucket string, key string) (map[string]string, error) {
+	info, err := f.GetObjectInfo(ctx, bucket, key, "")
+	if err != nil {
+		return nil, err
+	}
+	return info.Metadata, nil
+}
+
+// newFakeClientset returns a clientset whose job watches report the job as started,
+// as the cluster would once the pod is scheduled
+func newFakeClientset() *fake.Clientset {
+	clientset := fake.NewClientset()
+	clientset.PrependWatchReactor("jobs", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watcher := watch.NewFakeWithChanSize(1, false)
+		watcher.Add(&batchv1.Job{Status: batchv1.JobStatus{Active: 1}})
+		return true, watcher, nil
+	})
+	return clientset
+}
+
+// scriptJobStatuses makes each read of the job return the next status. The last status is kept.
+func scriptJobStatuses(clientset *fake.Clientset, jobName string, statuses ...batchv1.JobStatus) {
+	var mu sync.Mutex
+	clientset.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		if get.GetName() != jobName {
+			return false, nil, nil
+		}
+
+		obj, err := clientset.Tracker().Get(get.GetResource(), get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		job := obj.(*batchv1.Job).DeepCopy()
+		job.Status = statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		return true, job, nil
+	})
+}
+
+func newTestConfig() *config.Config {
+	cfg := &config.Config{Environment: "test"}
+	cfg.K8S.Namespace = testNamespace
+	cfg.K8S.ServiceAccountName = "job-starter"
+	cfg.K8S.Job.Prefix = "video-processor"
+	cfg.K8S.Job.Image = "video-processor:latest"
+	cfg.K8S.Job.ImageChecker = "job-checker:latest"
+	cfg.K8S.Job.Command = "/app/run"
+	cfg.K8S.Job.TtlSecondsAfterFinished = time.Hour
+	cfg.AWS.Region = "us-east-1"
+	cfg.AWS.SNS.TopicArn = "arn:aws:sns:us-east-1:000000000000:video-status"
+	cfg.AWS.SQS.QueueURL = testQueueURL
+	cfg.AWS.SQS.MaxMessagesBatch = 10
+	return cfg
+}
+
+func newTestLogger() *logger.Logger {
+	return &logger.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+// loadFixture reads a payload from test/data
+func loadFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("..", "data", name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+// jobEnvs returns the environment of the first container of a job
+func jobEnvs(job *batchv1.Job) map[string]string {
+	envs := make(map[string]string)
+	for _, env := range job.Spec.Template.Spec.Containers[0].Env {
+		envs[env.Name] = env.Value
+	}
+	return envs
+}
+
+// checkerJobConfig loads the configuration from the environment of the checker job, with the
+// loaders the checker runs at startup
+func checkerJobConfig(t *testing.T, checkerJob *batchv1.Job) *config.JobConfig {
+	t.Helper()
+
+	for key, value := range jobEnvs(checkerJob) {
+		t.Setenv(key, value)
+	}
+	_, err := config.LoadLambdaConfigFrom("")
+	require.NoError(t, err)
+	return config.LoadJobConfig()
+}
@@ -0,0 +1,680 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/adapter/gateway"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/domain"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/core/usecase"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/api"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/s3"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/aws/sqs"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/checker"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/config"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/starter"
+	"github.com/FIAP-SOAT-G20/hackathon-job-starter-lambda/internal/infrastructure/tracing"
+)
+
+const (
+	processorJobName = "video-processor-test-key"
+	checkerJobName   = "video-processor-test-key-checker"
+)
+
+// environment wires the real starter and checker to the in-process stand-ins
+type environment struct {
+	cfg       *config.Config
+	clientset *fake.Clientset
+	k8sAPI    *api.K8sAPI
+	queue     *fakeSQS
+	topic     *fakeSNS
+	objects   *fakeS3
+	handler   *sqs.SqsHandler
+	starter   *starter.Starter
+	usecase   *usecase.VideoUsecase
+}
+
+func newEnvironment() *environment {
+	cfg := newTestConfig()
+	log := newTestLogger()
+	clientset := newFakeClientset()
+	k8sAPI := api.NewK8sAPI(clientset, log)
+	queue := newFakeSQS()
+	topic := &fakeSNS{}
+	objects := &fakeS3{objects: map[string]s3.ObjectInfo{
+		testBucket + "/" + testKey: {
+			Metadata: map[string]string{"video-id": "42", "user-id": "7"},
+			Size:     1024,
+		},
+	}}
+	videoUsecase := usecase.NewVideoUsecase(gateway.NewVideoGateway(topic))
+
+	return &environment{
+		cfg:       cfg,
+		clientset: clientset,
+		k8sAPI:    k8sAPI,
+		queue:     queue,
+		topic:     topic,
+		objects:   objects,
+		handler:   sqs.NewSqsHandler(queue, testQueueURL, cfg.AWS.SQS.MaxMessagesBatch, 0, log, nil).WithDeadLetterQueue(testDLQURL, 0),
+		starter:   starter.NewStarter(cfg, log, k8sAPI, gateway.NewObjectGateway(objects), videoUsecase, gateway.NewUserTierGateway(nil)),
+		usecase:   videoUsecase,
+	}
+}
+
+// deliver sends a fixture to the queue and lets the starter consume it
+func (e *environment) deliver(t *testing.T, fixture string) string {
+	t.Helper()
+
+	ctx := context.Background()
+	messageId := e.queue.send(loadFixture(t, fixture))
+	err := e.handler.ReceiveMessages(ctx, e.starter.HandleMessage)
+	require.NoError(t, err)
+	return messageId
+}
+
+// runChecker runs the checker configured from the checker job, as it would run in its pod
+func (e *environment) runChecker(t *testing.T, checkerJobName string) error {
+	t.Helper()
+
+	checkerJob := e.getJob(t, checkerJobName)
+	c := checker.NewChecker(checkerJobConfig(t, checkerJob), newTestLogger().Logger, e.k8sAPI, e.usecase, time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return c.Run(ctx)
+}
+
+func (e *environment) getJob(t *testing.T, name string) *batchv1.Job {
+	t.Helper()
+
+	job, err := e.clientset.BatchV1().Jobs(testNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return job
+}
+
+func (e *environment) jobNames(t *testing.T) []string {
+	t.Helper()
+
+	jobs, err := e.clientset.BatchV1().Jobs(testNamespace).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	names := make([]string, 0, len(jobs.Items))
+	for _, job := range jobs.Items {
+		names = append(names, job.Name)
+	}
+	return names
+}
+
+func TestUploadedVideo(t *testing.T) {
+	t.Run("should create the processor and checker jobs from the S3 event", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+
+		// Act
+		messageId := env.deliver(t, "s3_event_payload.json")
+
+		// Assert
+		assert.Equal(t, []string{messageId}, env.queue.deleted)
+		assert.ElementsMatch(t, []string{processorJobName, checkerJobName}, env.jobNames(t))
+
+		processor := env.getJob(t, processorJobName)
+		assert.Equal(t, "video-processor:latest", processor.Spec.Template.Spec.Containers[0].Image)
+		assert.Equal(t, []string{"/bin/sh", "-c", "/app/run"}, processor.Spec.Template.Spec.Containers[0].Command)
+		assert.Equal(t, api.ComponentProcessor, processor.Labels[api.LabelComponent])
+		assert.Equal(t, "42", processor.Labels[api.LabelVideoID])
+		assert.Equal(t, "7", processor.Labels[api.LabelUserID])
+		assert.Equal(t, "1", processor.Labels[api.LabelAttempt])
+		assert.Equal(t, testBucket, processor.Annotations[api.AnnotationVideoBucket])
+		assert.Equal(t, testKey, processor.Annotations[api.AnnotationVideoKey])
+		processorEnvs := jobEnvs(processor)
+		assert.Equal(t, testKey, processorEnvs["VIDEO_KEY"])
+		assert.Equal(t, testBucket, processorEnvs["VIDEO_BUCKET"])
+		assert.Equal(t, "42", processorEnvs["VIDEO_ID"])
+		assert.Equal(t, "7", processorEnvs["VIDEO_USER_ID"])
+		assert.Equal(t, testVersionId, processorEnvs["VIDEO_VERSION_ID"])
+		assert.Equal(t, []string{testVersionId}, env.objects.versions)
+
+		checkerJob := env.getJob(t, checkerJobName)
+		assert.Equal(t, "job-checker:latest", checkerJob.Spec.Template.Spec.Containers[0].Image)
+		assert.Empty(t, checkerJob.Spec.Template.Spec.Containers[0].Command)
+		assert.Equal(t, api.ComponentChecker, checkerJob.Labels[api.LabelComponent])
+		assert.Equal(t, processorJobName, jobEnvs(checkerJob)["JOB_NAME"])
+		assert.Empty(t, env.topic.statuses())
+	})
+
+	t.Run("should set the configured envs in the processor without overriding the pipeline", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.cfg.K8S.Job.Envs = map[string]string{"OUTPUT_FORMAT": "zip", "VIDEO_KEY": "configured-key"}
+
+		// Act
+		env.deliver(t, "s3_event_payload.json")
+
+		// Assert
+		processorEnvs := jobEnvs(env.getJob(t, processorJobName))
+		assert.Equal(t, "zip", processorEnvs["OUTPUT_FORMAT"])
+		assert.Equal(t, testKey, processorEnvs["VIDEO_KEY"])
+		assert.NotContains(t, jobEnvs(env.getJob(t, checkerJobName)), "OUTPUT_FORMAT")
+	})
+
+	t.Run("should pass the tracing and logging settings to the checker", func(t *testing.T) {
+		// Arrange
+		t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "authorization=Bearer token")
+		t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=test")
+		t.Setenv("OTEL_SERVICE_NAME", "job-starter")
+		env := newEnvironment()
+		env.cfg.OTLPEndpoint = "http://otel-collector:4318"
+		env.cfg.LogLevel = "debug"
+		env.cfg.LogFormat = "json"
+
+		// Act
+		env.deliver(t, "s3_event_payload.json")
+
+		// Assert
+		checkerJob := env.getJob(t, checkerJobName)
+		checkerEnvs := jobEnvs(checkerJob)
+		assert.Equal(t, "authorization=Bearer token", checkerEnvs["OTEL_EXPORTER_OTLP_HEADERS"])
+		assert.Equal(t, "deployment.environment=test", checkerEnvs["OTEL_RESOURCE_ATTRIBUTES"])
+		assert.NotContains(t, checkerEnvs, "OTEL_SERVICE_NAME")
+		checkerJobConfig(t, checkerJob)
+		checkerConfig, err := config.LoadLambdaConfigFrom("")
+		require.NoError(t, err)
+		assert.Equal(t, "http://otel-collector:4318", checkerConfig.OTLPEndpoint)
+		assert.Equal(t, "debug", checkerConfig.LogLevel)
+		assert.Equal(t, "json", checkerConfig.LogFormat)
+		assert.Equal(t, "test", checkerConfig.Environment)
+	})
+
+	t.Run("should start the jobs of an object whose key has spaces", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.objects.objects[testBucket+"/uploads/My Movie.mp4"] = s3.ObjectInfo{
+			Metadata: map[string]string{"video-id": "42", "user-id": "7"},
+		}
+
+		// Act
+		env.queue.send(`{"Records":[{"eventName":"ObjectCreated:CompleteMultipartUpload","s3":{"bucket":{"name":"test-bucket"},"object":{"key":"uploads/My+Movie.mp4"}}}]}`)
+		err := env.handler.ReceiveMessages(context.Background(), env.starter.HandleMessage)
+
+		// Assert
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"video-processor-my-movie", "video-processor-my-movie-checker"}, env.jobNames(t))
+		processor := env.getJob(t, "video-processor-my-movie")
+		assert.Equal(t, "uploads/My Movie.mp4", jobEnvs(processor)["VIDEO_KEY"])
+		assert.Equal(t, "uploads/My Movie.mp4", processor.Annotations[api.AnnotationVideoKey])
+	})
+
+	t.Run("should ignore the events of other than created objects", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+
+		// Act
+		messageId := env.queue.send(`{"Records":[{"eventName":"ObjectTagging:Put","s3":{"bucket":{"name":"test-bucket"},"object":{"key":"test-key"}}}]}`)
+		err := env.handler.ReceiveMessages(context.Background(), env.starter.HandleMessage)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{messageId}, env.queue.deleted)
+		assert.Empty(t, env.jobNames(t))
+		assert.Empty(t, env.objects.versions)
+	})
+
+	t.Run("should forward an event with an invalid key to the dead-letter queue", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+
+		// Act
+		messageId := env.queue.send(`{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"test-bucket"},"object":{"key":"movie%zz.mp4"}}}]}`)
+		err := env.handler.ReceiveMessages(context.Background(), env.starter.HandleMessage)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, []string{messageId}, env.queue.deleted)
+		require.Len(t, env.queue.forwarded, 1)
+		assert.Equal(t, "INVALID_S3_EVENT", *env.queue.forwarded[0].MessageAttributes[sqs.AttributeFailureCode].StringValue)
+	})
+
+	t.Run("should pass the trace of the message to the jobs", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+		// Act
+		env.queue.sendWithAttributes(loadFixture(t, "s3_event_payload.json"), map[string]string{"traceparent": traceParent})
+		err := env.handler.ReceiveMessages(context.Background(), env.starter.HandleMessage)
+
+		// Assert
+		require.NoError(t, err)
+		for _, jobName := range []string{processorJobName, checkerJobName} {
+			job := env.getJob(t, jobName)
+			assert.Contains(t, jobEnvs(job)[tracing.EnvTraceParent], "4bf92f3577b34da6a3ce929d0e0e4736")
+			assert.Contains(t, job.Annotations["traceparent"], "4bf92f3577b34da6a3ce929d0e0e4736")
+		}
+	})
+
+	t.Run("should publish uploaded and processing until the job completes", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+		scriptJobStatuses(env.clientset, processorJobName,
+			batchv1.JobStatus{},
+			batchv1.JobStatus{Active: 1},
+			batchv1.JobStatus{Active: 1},
+			batchv1.JobStatus{Succeeded: 1},
+		)
+
+		// Act
+		err := env.runChecker(t, checkerJobName)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"UPLOADED", "PROCESSING"}, env.topic.statuses())
+		for _, payload := range env.topic.payloads {
+			assert.Equal(t, int64(testVideoId), payload.VideoId)
+			assert.Equal(t, int64(testUserId), payload.UserId)
+		}
+	})
+
+	t.Run("should publish failed when the job fails", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+		scriptJobStatuses(env.clientset, processorJobName,
+			batchv1.JobStatus{Active: 1},
+			batchv1.JobStatus{Failed: 1},
+		)
+
+		// Act
+		err := env.runChecker(t, checkerJobName)
+
+		// Assert
+		assert.ErrorIs(t, err, domain.ErrJobFailed)
+		assert.Equal(t, []string{"UPLOADED", "PROCESSING", "FAILED"}, env.topic.statuses())
+	})
+
+	t.Run("should keep the message in the queue when the object has no metadata", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.queue.send(`{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"test-bucket"},"object":{"key":"unknown-key"}}}]}`)
+
+		// Act
+		err := env.handler.ReceiveMessages(context.Background(), env.starter.HandleMessage)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Empty(t, env.queue.deleted)
+		assert.Empty(t, env.jobNames(t))
+		assert.Empty(t, env.topic.statuses())
+	})
+
+	t.Run("should forward a malformed message to the dead-letter queue and delete it", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		messageId := env.queue.send(`{"Records":`)
+
+		// Act
+		err := env.handler.ReceiveMessages(context.Background(), env.starter.HandleMessage)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{messageId}, env.queue.deleted)
+		require.Len(t, env.queue.forwarded, 1)
+		assert.Equal(t, `{"Records":`, *env.queue.forwarded[0].Body)
+		reason := env.queue.forwarded[0].MessageAttributes[sqs.AttributeFailureReason]
+		assert.Contains(t, *reason.StringValue, "failed to unmarshal S3 event")
+		code := env.queue.forwarded[0].MessageAttributes[sqs.AttributeFailureCode]
+		assert.Equal(t, string(domain.CodeInvalidS3Event), *code.StringValue)
+		assert.Empty(t, env.jobNames(t))
+	})
+
+	t.Run("should configure the checker when only priority queues are polled", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.cfg.AWS.SQS.QueueURL = ""
+		env.cfg.Priority.Queues = []config.WeightedQueue{{URL: testQueueURL, Weight: 3}}
+		env.deliver(t, "s3_event_payload.json")
+
+		// Act
+		jobConfig := checkerJobConfig(t, env.getJob(t, checkerJobName))
+
+		// Assert
+		assert.Equal(t, processorJobName, jobConfig.JobName)
+		assert.Equal(t, int64(testVideoId), jobConfig.VideoId)
+		assert.Equal(t, testQueueURL+"=3", jobEnvs(env.getJob(t, checkerJobName))["AWS_SQS_PRIORITY_QUEUES"])
+	})
+}
+
+func TestDeadLetter(t *testing.T) {
+	t.Run("should forward an unordered upload with the job already created", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+
+		// Act
+		messageId := env.queue.send(`{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"test-bucket"},"object":{"key":"test-key"}}}]}`)
+		require.NoError(t, env.handler.ReceiveMessages(context.Background(), env.starter.HandleMessage))
+
+		// Assert
+		assert.Contains(t, env.queue.deleted, messageId)
+		require.Len(t, env.queue.forwarded, 1)
+		attributes := env.queue.forwarded[0].MessageAttributes
+		assert.Equal(t, string(domain.CodeJobAlreadyExists), *attributes[sqs.AttributeFailureCode].StringValue)
+		assert.Equal(t, checkerJobName, *attributes[sqs.AttributeJobName].StringValue)
+		assert.Equal(t, testQueueURL, *attributes[sqs.AttributeSourceQueue].StringValue)
+	})
+}
+
+func TestCanceledVideo(t *testing.T) {
+	t.Run("should delete the jobs and publish canceled from a control message", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+
+		// Act
+		env.deliver(t, "cancel_control_payload.json")
+
+		// Assert
+		assert.Len(t, env.queue.deleted, 2)
+		assert.Empty(t, env.jobNames(t))
+		assert.Equal(t, []string{"CANCELED"}, env.topic.statuses())
+	})
+
+	t.Run("should delete the jobs and publish canceled when the object is removed", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+
+		// Act
+		env.deliver(t, "s3_object_removed_payload.json")
+
+		// Assert
+		assert.Len(t, env.queue.deleted, 2)
+		assert.Empty(t, env.jobNames(t))
+		assert.Equal(t, []string{"CANCELED"}, env.topic.statuses())
+	})
+}
+
+func TestReprocessedVideo(t *testing.T) {
+	t.Run("should replace the failed jobs with a new attempt", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+		processor := env.getJob(t, processorJobName)
+		processor.Status = batchv1.JobStatus{Failed: 1}
+		_, err := env.clientset.BatchV1().Jobs(testNamespace).UpdateStatus(context.Background(), processor, metav1.UpdateOptions{})
+		require.NoError(t, err)
+
+		// Act
+		env.deliver(t, "reprocess_control_payload.json")
+
+		// Assert
+		assert.ElementsMatch(t, []string{processorJobName + "-2", processorJobName + "-2-checker"}, env.jobNames(t))
+		assert.Equal(t, "2", env.getJob(t, processorJobName+"-2").Labels[api.LabelAttempt])
+		assert.Equal(t, []string{"REPROCESSING"}, env.topic.statuses())
+	})
+
+	t.Run("should not announce the upload again when checking a new attempt", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+		processor := env.getJob(t, processorJobName)
+		processor.Status = batchv1.JobStatus{Failed: 1}
+		_, err := env.clientset.BatchV1().Jobs(testNamespace).UpdateStatus(context.Background(), processor, metav1.UpdateOptions{})
+		require.NoError(t, err)
+		env.deliver(t, "reprocess_control_payload.json")
+		scriptJobStatuses(env.clientset, processorJobName+"-2",
+			batchv1.JobStatus{Active: 1},
+			batchv1.JobStatus{Succeeded: 1},
+		)
+
+		// Act
+		err = env.runChecker(t, processorJobName+"-2-checker")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"REPROCESSING", "PROCESSING"}, env.topic.statuses())
+	})
+
+	t.Run("should ignore the request while the video is being processed", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+
+		// Act
+		env.deliver(t, "reprocess_control_payload.json")
+
+		// Assert
+		assert.ElementsMatch(t, []string{processorJobName, checkerJobName}, env.jobNames(t))
+		assert.Empty(t, env.topic.statuses())
+	})
+}
+
+func TestReloadedConfiguration(t *testing.T) {
+	t.Run("should create the jobs of new messages with the reloaded template", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		reloaded := newTestConfig()
+		reloaded.K8S.Job.Image = "video-processor:2.0.0"
+		reloaded.K8S.Job.BackOffLimit = 5
+
+		// Act
+		changes := env.starter.Reload(reloaded, gateway.NewUserTierGateway(nil))
+		env.deliver(t, "s3_event_payload.json")
+
+		// Assert
+		assert.Equal(t, []string{
+			"k8s.job.backOffLimit: 0 -> 5",
+			`k8s.job.image: "video-processor:latest" -> "video-processor:2.0.0"`,
+		}, changes)
+		processor := env.getJob(t, processorJobName)
+		assert.Equal(t, "video-processor:2.0.0", processor.Spec.Template.Spec.Containers[0].Image)
+		checkerJob := env.getJob(t, checkerJobName)
+		assert.Equal(t, "5", jobEnvs(checkerJob)["K8S_JOB_BACK_OFF_LIMIT"])
+	})
+
+	t.Run("should keep the AWS settings and the queues polled until a restart", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		reloaded := newTestConfig()
+		reloaded.AWS.Region = "sa-east-1"
+		reloaded.AWS.SNS.TopicArn = "arn:aws:sns:sa-east-1:123456789012:video-status"
+		reloaded.AWS.SQS.QueueURL = "https://sqs.sa-east-1.amazonaws.com/123456789012/uploads"
+		reloaded.Priority.Queues = []config.WeightedQueue{{URL: testQueueURL, Weight: 3}}
+		reloaded.K8S.Job.Image = "video-processor:2.0.0"
+
+		// Act
+		changes := env.starter.Reload(reloaded, gateway.NewUserTierGateway(nil))
+		env.deliver(t, "s3_event_payload.json")
+
+		// Assert
+		require.Len(t, changes, 5)
+		for _, change := range changes {
+			assert.Equal(t, !strings.HasPrefix(change, "k8s.job.image:"), config.RequiresRestart(change), change)
+		}
+		processor := env.getJob(t, processorJobName)
+		assert.Equal(t, "video-processor:2.0.0", processor.Spec.Template.Spec.Containers[0].Image)
+		assert.Equal(t, env.cfg.AWS.Region, jobEnvs(processor)["AWS_REGION"])
+		assert.Equal(t, env.cfg.AWS.SNS.TopicArn, jobEnvs(processor)["SNS_TOPIC_ARN"])
+		checkerEnvs := jobEnvs(env.getJob(t, checkerJobName))
+		assert.Equal(t, env.cfg.AWS.Region, checkerEnvs["AWS_REGION"])
+		assert.Equal(t, env.cfg.AWS.SNS.TopicArn, checkerEnvs["AWS_SNS_TOPIC_ARN"])
+		assert.Equal(t, testQueueURL, checkerEnvs["AWS_SQS_QUEUE_URL"])
+		assert.Empty(t, checkerEnvs["AWS_SQS_PRIORITY_QUEUES"])
+	})
+}
+
+// uploadEvent returns the S3 event fixture for another sequencer and eTag of the object
+func uploadEvent(t *testing.T, sequencer string, eTag string) string {
+	t.Helper()
+
+	event := loadFixture(t, "s3_event_payload.json")
+	event = strings.Replace(event, "0055AED6DCD90281E5", sequencer, 1)
+	return strings.Replace(event, "d41d8cd98f00b204e9800998ecf8427e", eTag, 1)
+}
+
+// deliverBody sends a message body to the queue and lets the starter consume it
+func (e *environment) deliverBody(t *testing.T, body string) string {
+	t.Helper()
+
+	messageId := e.queue.send(body)
+	require.NoError(t, e.handler.ReceiveMessages(context.Background(), e.starter.HandleMessage))
+	return messageId
+}
+
+func TestOverwrittenVideo(t *testing.T) {
+	t.Run("should ignore an upload event delivered again", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+
+		// Act
+		messageId := env.deliver(t, "s3_event_payload.json")
+
+		// Assert
+		assert.Contains(t, env.queue.deleted, messageId)
+		assert.Empty(t, env.queue.forwarded)
+		assert.ElementsMatch(t, []string{processorJobName, checkerJobName}, env.jobNames(t))
+		assert.Empty(t, env.topic.statuses())
+	})
+
+	t.Run("should ignore an upload event older than the jobs", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+
+		// Act
+		messageId := env.deliverBody(t, uploadEvent(t, "0055AED6DCD90281", "9b2cf535f27731c974343645a3985328"))
+
+		// Assert
+		assert.Contains(t, env.queue.deleted, messageId)
+		assert.ElementsMatch(t, []string{processorJobName, checkerJobName}, env.jobNames(t))
+		assert.Equal(t, "0055AED6DCD90281E5", env.getJob(t, processorJobName).Annotations[api.AnnotationVideoSequencer])
+		assert.Empty(t, env.topic.statuses())
+	})
+
+	t.Run("should ignore an overwrite with the content being processed", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+
+		// Act
+		messageId := env.deliverBody(t, uploadEvent(t, "0055AED6DCD90281F0", "d41d8cd98f00b204e9800998ecf8427e"))
+
+		// Assert
+		assert.Contains(t, env.queue.deleted, messageId)
+		assert.ElementsMatch(t, []string{processorJobName, checkerJobName}, env.jobNames(t))
+		assert.Empty(t, env.topic.statuses())
+	})
+
+	t.Run("should supersede the running jobs with the newer version", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+
+		// Act
+		messageId := env.deliverBody(t, uploadEvent(t, "0055AED6DCD9028300", "9b2cf535f27731c974343645a3985328"))
+
+		// Assert
+		assert.Contains(t, env.queue.deleted, messageId)
+		assert.ElementsMatch(t, []string{processorJobName + "-r2", processorJobName + "-r2-checker"}, env.jobNames(t))
+		processor := env.getJob(t, processorJobName+"-r2")
+		assert.Equal(t, "0055AED6DCD9028300", processor.Annotations[api.AnnotationVideoSequencer])
+		assert.Equal(t, "9b2cf535f27731c974343645a3985328", processor.Annotations[api.AnnotationVideoETag])
+		assert.Equal(t, "1", processor.Labels[api.LabelAttempt])
+		assert.Equal(t, "2", processor.Labels[api.LabelRevision])
+		assert.Empty(t, env.topic.statuses())
+	})
+
+	t.Run("should announce the newer version as a new upload", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+		env.deliverBody(t, uploadEvent(t, "0055AED6DCD9028300", "9b2cf535f27731c974343645a3985328"))
+		scriptJobStatuses(env.clientset, processorJobName+"-r2",
+			batchv1.JobStatus{Active: 1},
+			batchv1.JobStatus{Succeeded: 1},
+		)
+
+		// Act
+		err := env.runChecker(t, processorJobName+"-r2-checker")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"UPLOADED", "PROCESSING"}, env.topic.statuses())
+	})
+
+	t.Run("should reprocess the newer version under its revision", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+		env.deliverBody(t, uploadEvent(t, "0055AED6DCD9028300", "9b2cf535f27731c974343645a3985328"))
+		processor := env.getJob(t, processorJobName+"-r2")
+		processor.Status = batchv1.JobStatus{Failed: 1}
+		_, err := env.clientset.BatchV1().Jobs(testNamespace).UpdateStatus(context.Background(), processor, metav1.UpdateOptions{})
+		require.NoError(t, err)
+
+		// Act
+		env.deliver(t, "reprocess_control_payload.json")
+
+		// Assert
+		assert.ElementsMatch(t, []string{processorJobName + "-r2-2", processorJobName + "-r2-2-checker"}, env.jobNames(t))
+		assert.Equal(t, "0055AED6DCD9028300", env.getJob(t, processorJobName+"-r2-2").Annotations[api.AnnotationVideoSequencer])
+		assert.Equal(t, []string{"REPROCESSING"}, env.topic.statuses())
+	})
+
+	t.Run("should start the jobs again when the processor job of the event wasn't created", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		failed := false
+		env.clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+			if job.Name == processorJobName && !failed {
+				failed = true
+				return true, nil, errors.New("etcdserver: request timed out")
+			}
+			return false, nil, nil
+		})
+		messageId := env.deliver(t, "s3_event_payload.json")
+		require.NotContains(t, env.queue.deleted, messageId)
+
+		// Act
+		err := env.handler.ReceiveMessages(context.Background(), env.starter.HandleMessage)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Contains(t, env.queue.deleted, messageId)
+		assert.Empty(t, env.queue.forwarded)
+		assert.ElementsMatch(t, []string{processorJobName + "-r2", processorJobName + "-r2-checker"}, env.jobNames(t))
+		assert.Equal(t, "0055AED6DCD90281E5", env.getJob(t, processorJobName+"-r2").Annotations[api.AnnotationVideoSequencer])
+		assert.Empty(t, env.topic.statuses())
+	})
+
+	t.Run("should keep the event of the jobs when reprocessed", func(t *testing.T) {
+		// Arrange
+		env := newEnvironment()
+		env.deliver(t, "s3_event_payload.json")
+		processor := env.getJob(t, processorJobName)
+		processor.Status = batchv1.JobStatus{Failed: 1}
+		_, err := env.clientset.BatchV1().Jobs(testNamespace).UpdateStatus(context.Background(), processor, metav1.UpdateOptions{})
+		require.NoError(t, err)
+		env.deliver(t, "reprocess_control_payload.json")
+
+		// Act
+		messageId := env.deliver(t, "s3_event_payload.json")
+
+		// Assert
+		assert.Contains(t, env.queue.deleted, messageId)
+		assert.ElementsMatch(t, []string{processorJobName + "-2", processorJobName + "-2-checker"}, env.jobNames(t))
+		assert.Equal(t, "0055AED6DCD90281E5", env.getJob(t, processorJobName+"-2").Annotations[api.AnnotationVideoSequencer])
+	})
+}